@@ -87,5 +87,60 @@ describe("generatePredicate", () => {
 
     expect(pred.runDetails.builder.id).toBe(jobWorkflowRef);
     expect(pred.buildDefinition.resolvedDependencies).toHaveLength(2);
+    expect(pred.runDetails.metadata.startedOn).toBeUndefined();
+    expect(pred.runDetails.metadata.finishedOn).toBeUndefined();
+  });
+
+  it("get predicate with build metadata", () => {
+    const currentRun = {
+      id: 123456,
+      run_attempt: 1,
+      repository: {
+        name: "example-source",
+        full_name: "slsa-framework/example-source",
+        id: 123,
+        owner: {
+          id: 456,
+        },
+      },
+      actor: {
+        id: 456,
+      },
+      triggering_actor: {
+        id: 456,
+      },
+    };
+
+    const bd = {
+      buildType: "https://slsa.dev/container-based-build/v0.1?draft",
+      externalParameters: {},
+    };
+
+    const buildMetadata = predicate.parseBuildMetadata(
+      '{"invocationID":"123456-1","startedOn":"2023-01-02T03:04:05Z","finishedOn":"2023-01-02T03:05:05Z"}',
+    ) as types.Metadata;
+
+    const pred = predicate.generatePredicate(
+      bd,
+      { uri: "git+https://github.com/slsa-framework/example-builder" },
+      "octo-org/octo-automation/.github/workflows/oidc.yml@refs/heads/main",
+      currentRun,
+      buildMetadata,
+    ) as types.SLSAv1Predicate;
+
+    expect(pred.runDetails.metadata.startedOn).toEqual(
+      new Date("2023-01-02T03:04:05Z"),
+    );
+    expect(pred.runDetails.metadata.finishedOn).toEqual(
+      new Date("2023-01-02T03:05:05Z"),
+    );
+  });
+});
+
+describe("parseBuildMetadata", () => {
+  it("rejects invalid build times", () => {
+    expect(() =>
+      predicate.parseBuildMetadata('{"startedOn":"yesterday"}'),
+    ).toThrow();
   });
 });
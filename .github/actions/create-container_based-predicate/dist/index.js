@@ -220,6 +220,7 @@ function run() {
             const binaryDigest = core.getInput("binary-sha256");
             const binaryURI = core.getInput("binary-uri");
             const jobWorkflowRef = core.getInput("builder-id");
+            const buildMetadataInput = core.getInput("build-metadata");
             const token = core.getInput("token");
             if (!token) {
                 throw new Error("token not provided");
@@ -230,6 +231,11 @@ function run() {
             // Read SLSA build definition
             const buffer = tscommon.safeReadFileSync(bdPath);
             const bd = JSON.parse(buffer.toString());
+            // Read the metadata of the build recorded by the builder, if any.
+            let buildMetadata = {};
+            if (buildMetadataInput) {
+                buildMetadata = (0, predicate_1.parseBuildMetadata)(buildMetadataInput);
+            }
             // Get builder binary artifact reference.
             const builderBinaryRef = {
                 uri: binaryURI,
@@ -240,7 +246,7 @@ function run() {
             // Generate the predicate.
             const ownerRepo = utils.getEnv("GITHUB_REPOSITORY");
             const currentWorkflowRun = yield gh.getWorkflowRun(ownerRepo, Number(process.env.GITHUB_RUN_ID), token);
-            const predicate = (0, predicate_1.generatePredicate)(bd, builderBinaryRef, jobWorkflowRef, currentWorkflowRun);
+            const predicate = (0, predicate_1.generatePredicate)(bd, builderBinaryRef, jobWorkflowRef, currentWorkflowRun, buildMetadata);
             // Write output predicate
             tscommon.safeWriteFileSync(outputFile, JSON.stringify(predicate));
             core.debug(`Wrote predicate to ${outputFile}`);
@@ -279,9 +285,30 @@ run();
 // See the License for the specific language governing permissions and
 // limitations under the License.
 Object.defineProperty(exports, "__esModule", ({ value: true }));
-exports.generatePredicate = void 0;
+exports.generatePredicate = exports.parseBuildMetadata = void 0;
 const github_1 = __nccwpck_require__(5928);
-function generatePredicate(bd, binaryRef, jobWorkflowRef, currentRun) {
+/**
+ * parseBuildMetadata parses the JSON-encoded metadata of the build recorded by
+ * the builder. Only the build times are kept, as the invocation ID is derived
+ * from the workflow run.
+ */
+function parseBuildMetadata(s) {
+    const m = JSON.parse(s);
+    const metadata = {};
+    for (const key of ["startedOn", "finishedOn"]) {
+        if (m[key] === undefined || m[key] === null) {
+            continue;
+        }
+        const d = new Date(m[key]);
+        if (isNaN(d.getTime())) {
+            throw new Error(`invalid ${key} in build metadata: ${m[key]}`);
+        }
+        metadata[key] = d;
+    }
+    return metadata;
+}
+exports.parseBuildMetadata = parseBuildMetadata;
+function generatePredicate(bd, binaryRef, jobWorkflowRef, currentRun, buildMetadata = {}) {
     // Add the builder binary to the resolved dependencies.
     if (!bd.resolvedDependencies) {
         bd.resolvedDependencies = [binaryRef];
@@ -297,6 +324,9 @@ function generatePredicate(bd, binaryRef, jobWorkflowRef, currentRun) {
             },
             metadata: {
                 invocationId: (0, github_1.getInvocationID)(currentRun),
+                // The build times are measured by the builder.
+                startedOn: buildMetadata.startedOn,
+                finishedOn: buildMetadata.finishedOn,
             },
         },
     };
@@ -13,8 +13,12 @@
 // limitations under the License.
 
 import * as core from "@actions/core";
-import type { BuildDefinition, ResourceDescriptor } from "./predicate";
-import { generatePredicate } from "./predicate";
+import type {
+  BuildDefinition,
+  Metadata,
+  ResourceDescriptor,
+} from "./predicate";
+import { generatePredicate, parseBuildMetadata } from "./predicate";
 import * as gh from "./github";
 import * as utils from "./utils";
 import * as tscommon from "tscommon";
@@ -52,6 +56,7 @@ async function run(): Promise<void> {
     const binaryDigest = core.getInput("binary-sha256");
     const binaryURI = core.getInput("binary-uri");
     const jobWorkflowRef = core.getInput("builder-id");
+    const buildMetadataInput = core.getInput("build-metadata");
     const token = core.getInput("token");
     if (!token) {
       throw new Error("token not provided");
@@ -65,6 +70,12 @@ async function run(): Promise<void> {
     const buffer = tscommon.safeReadFileSync(bdPath);
     const bd: BuildDefinition = JSON.parse(buffer.toString());
 
+    // Read the metadata of the build recorded by the builder, if any.
+    let buildMetadata: Metadata = {};
+    if (buildMetadataInput) {
+      buildMetadata = parseBuildMetadata(buildMetadataInput);
+    }
+
     // Get builder binary artifact reference.
     const builderBinaryRef: ResourceDescriptor = {
       uri: binaryURI,
@@ -85,6 +96,7 @@ async function run(): Promise<void> {
       builderBinaryRef,
       jobWorkflowRef,
       currentWorkflowRun,
+      buildMetadata,
     );
 
     // Write output predicate
@@ -89,11 +89,33 @@ export interface SLSAv1Predicate {
   runDetails: RunDetails;
 }
 
+/**
+ * parseBuildMetadata parses the JSON-encoded metadata of the build recorded by
+ * the builder. Only the build times are kept, as the invocation ID is derived
+ * from the workflow run.
+ */
+export function parseBuildMetadata(s: string): Metadata {
+  const m = JSON.parse(s);
+  const metadata: Metadata = {};
+  for (const key of ["startedOn", "finishedOn"] as const) {
+    if (m[key] === undefined || m[key] === null) {
+      continue;
+    }
+    const d = new Date(m[key]);
+    if (isNaN(d.getTime())) {
+      throw new Error(`invalid ${key} in build metadata: ${m[key]}`);
+    }
+    metadata[key] = d;
+  }
+  return metadata;
+}
+
 export function generatePredicate(
   bd: BuildDefinition,
   binaryRef: ResourceDescriptor,
   jobWorkflowRef: string,
   currentRun: ApiWorkflowRun,
+  buildMetadata: Metadata = {},
 ): SLSAv1Predicate {
   // Add the builder binary to the resolved dependencies.
   if (!bd.resolvedDependencies) {
@@ -110,6 +132,9 @@ export function generatePredicate(
       },
       metadata: {
         invocationId: getInvocationID(currentRun),
+        // The build times are measured by the builder.
+        startedOn: buildMetadata.startedOn,
+        finishedOn: buildMetadata.finishedOn,
       },
     },
   };
@@ -0,0 +1,82 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+var (
+	// ErrInvalidRepository indicates a repository that isn't of the form
+	// "owner/name".
+	ErrInvalidRepository = errors.New("invalid repository")
+
+	// ErrNoClient indicates that no GitHub API client is available.
+	ErrNoClient = errors.New("no GitHub API client")
+)
+
+// attestationRequest is the request body of the attestations endpoint.
+type attestationRequest struct {
+	Bundle json.RawMessage `json:"bundle"`
+}
+
+// attestationResponse is the response body of the attestations endpoint.
+type attestationResponse struct {
+	ID int64 `json:"id"`
+}
+
+// UploadAttestation uploads the Sigstore bundle of an attestation to the
+// attestations of the repository, given as "owner/name", and returns the ID
+// of the attestation. The client's token must have the attestations write
+// permission.
+func UploadAttestation(ctx context.Context, c *github.Client, repository string, bundle []byte) (int64, error) {
+	if c == nil {
+		return 0, ErrNoClient
+	}
+	owner, name, err := splitRepository(repository)
+	if err != nil {
+		return 0, err
+	}
+
+	// NOTE: The attestations API is not supported by this version of go-github.
+	u := fmt.Sprintf("repos/%s/%s/attestations", owner, name)
+	req, err := c.NewRequest(http.MethodPost, u, &attestationRequest{Bundle: bundle})
+	if err != nil {
+		return 0, err
+	}
+
+	var resp attestationResponse
+	if _, err := c.Do(ctx, req, &resp); err != nil {
+		return 0, fmt.Errorf("uploading attestation: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// splitRepository returns the owner and name of a repository given as
+// "owner/name".
+func splitRepository(repository string) (string, string, error) {
+	owner, name, ok := strings.Cut(repository, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidRepository, repository)
+	}
+	return owner, name, nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-github/v57/github"
+)
+
+func TestUploadAttestation(t *testing.T) {
+	bundle := []byte(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`)
+
+	testCases := []struct {
+		name       string
+		repository string
+		bundle     []byte
+		id         int64
+		err        error
+		apiErr     bool
+	}{
+		{
+			name:       "upload",
+			repository: "owner/repo",
+			bundle:     bundle,
+			id:         1,
+		},
+		{
+			name:       "no owner",
+			repository: "repo",
+			bundle:     bundle,
+			err:        ErrInvalidRepository,
+		},
+		{
+			name:       "nested",
+			repository: "owner/repo/path",
+			bundle:     bundle,
+			err:        ErrInvalidRepository,
+		},
+		{
+			name:       "rejected bundle",
+			repository: "owner/repo",
+			bundle:     []byte(`null`),
+			apiErr:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewTestAttestationsServer(t)
+
+			id, err := UploadAttestation(context.Background(), s.GithubClient(), tc.repository, tc.bundle)
+			var errResp *github.ErrorResponse
+			if want, got := tc.apiErr, errors.As(err, &errResp); want != got {
+				t.Fatalf("unexpected API error, want: %v, got: %v", want, err)
+			}
+			if !tc.apiErr && !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if want, got := tc.id, id; want != got {
+				t.Errorf("unexpected id, want: %d, got: %d", want, got)
+			}
+			if diff := cmp.Diff([]json.RawMessage{tc.bundle}, s.Bundles(tc.repository)); diff != "" {
+				t.Errorf("unexpected bundles (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUploadAttestation_noClient(t *testing.T) {
+	if _, err := UploadAttestation(context.Background(), nil, "owner/repo", []byte(`{}`)); !errors.Is(err, ErrNoClient) {
+		t.Errorf("unexpected error, want: %v, got: %v", ErrNoClient, err)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// TestAttestationsServer is a fake of the GitHub attestations API. Other
+// endpoints can be faked with HandleFunc.
+type TestAttestationsServer struct {
+	*httptest.Server
+
+	mux     *http.ServeMux
+	mu      sync.Mutex
+	bundles map[string][]json.RawMessage
+}
+
+// NewTestAttestationsServer returns a started fake of the GitHub attestations
+// API. The server is closed when the test finishes.
+func NewTestAttestationsServer(t *testing.T) *TestAttestationsServer {
+	s := &TestAttestationsServer{
+		mux:     http.NewServeMux(),
+		bundles: map[string][]json.RawMessage{},
+	}
+	s.mux.HandleFunc("/", s.handle)
+	s.Server = httptest.NewServer(s.mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *TestAttestationsServer) handle(w http.ResponseWriter, r *http.Request) {
+	repository, ok := strings.CutPrefix(r.URL.Path, "/repos/")
+	if ok {
+		repository, ok = strings.CutSuffix(repository, "/attestations")
+	}
+	if !ok || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var req attestationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Bundle) == 0 || req.Bundle[0] != '{' {
+		http.Error(w, `{"message": "invalid bundle"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.mu.Lock()
+	s.bundles[repository] = append(s.bundles[repository], req.Bundle)
+	var id int64
+	for _, b := range s.bundles {
+		id += int64(len(b))
+	}
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(attestationResponse{ID: id})
+}
+
+// HandleFunc registers the handler of another endpoint of the API.
+func (s *TestAttestationsServer) HandleFunc(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+}
+
+// Bundles returns the bundles uploaded to the repository, given as
+// "owner/name".
+func (s *TestAttestationsServer) Bundles(repository string) []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.bundles[repository]...)
+}
+
+// GithubClient returns a GitHub API client for the fake API.
+func (s *TestAttestationsServer) GithubClient() *github.Client {
+	c := github.NewClient(s.Client())
+	c.BaseURL, _ = url.Parse(s.URL + "/")
+	return c
+}
@@ -22,12 +22,14 @@ import (
 )
 
 // NewGithubClient returns a new GitHub API client authenticated using the
-// token from the GitHub context.
+// token from the GitHub context. Requests are retried on transient errors and
+// rate limits.
 func NewGithubClient(ctx context.Context) (*github.Client, error) {
 	t, err := GetToken()
 	if err != nil {
 		return nil, err
 	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, NewHTTPClient())
 	return github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: t},
 	))), nil
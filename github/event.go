@@ -0,0 +1,392 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Names of the events that trigger workflows.
+//
+// See: https://docs.github.com/en/actions/using-workflows/events-that-trigger-workflows
+const (
+	EventPush             = "push"
+	EventRelease          = "release"
+	EventWorkflowDispatch = "workflow_dispatch"
+	EventWorkflowCall     = "workflow_call"
+	EventPullRequest      = "pull_request"
+	EventSchedule         = "schedule"
+	EventCreate           = "create"
+)
+
+const (
+	refsHeadsPrefix = "refs/heads/"
+	refsTagsPrefix  = "refs/tags/"
+
+	refTypeTag    = "tag"
+	refTypeBranch = "branch"
+)
+
+// ErrInvalidEvent indicates that the event payload is not valid for the event.
+var ErrInvalidEvent = errors.New("invalid event")
+
+// EventPayload is the raw payload of the event that triggered a workflow run,
+// as found in the `github` context. Use ParseEvent to get a typed event.
+type EventPayload map[string]any
+
+// Event is a typed event payload.
+type Event interface {
+	// EventName returns the name of the event.
+	EventName() string
+
+	// Validate checks that the payload has the fields required by the event.
+	Validate() error
+}
+
+// Repository is the repository in an event payload.
+type Repository struct {
+	ID            int64  `json:"id"`
+	FullName      string `json:"full_name"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+// Commit is a commit in a push event payload.
+type Commit struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+// PushEvent is the payload of the push event. Tag pushes are push events
+// with a ref under refs/tags/.
+type PushEvent struct {
+	Ref        string     `json:"ref"`
+	Before     string     `json:"before"`
+	After      string     `json:"after"`
+	Created    bool       `json:"created"`
+	Deleted    bool       `json:"deleted"`
+	Forced     bool       `json:"forced"`
+	BaseRef    string     `json:"base_ref"`
+	HeadCommit *Commit    `json:"head_commit"`
+	Repository Repository `json:"repository"`
+}
+
+// EventName implements Event.EventName.
+func (*PushEvent) EventName() string { return EventPush }
+
+// Validate implements Event.Validate.
+func (e *PushEvent) Validate() error {
+	if !strings.HasPrefix(e.Ref, "refs/") {
+		return fmt.Errorf("%w: push: unexpected ref %q", ErrInvalidEvent, e.Ref)
+	}
+	if e.Deleted {
+		return fmt.Errorf("%w: push: ref %q was deleted", ErrInvalidEvent, e.Ref)
+	}
+	return nil
+}
+
+// Tag returns the tag that was pushed, if any.
+func (e *PushEvent) Tag() (string, bool) {
+	return cutRef(e.Ref, refsTagsPrefix)
+}
+
+// Release is the release in a release event payload.
+type Release struct {
+	ID              int64  `json:"id"`
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish"`
+	Name            string `json:"name"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+	HTMLURL         string `json:"html_url"`
+}
+
+// ReleaseEvent is the payload of the release event.
+type ReleaseEvent struct {
+	Action     string     `json:"action"`
+	Release    Release    `json:"release"`
+	Repository Repository `json:"repository"`
+}
+
+// EventName implements Event.EventName.
+func (*ReleaseEvent) EventName() string { return EventRelease }
+
+// Validate implements Event.Validate.
+func (e *ReleaseEvent) Validate() error {
+	if e.Action == "" {
+		return fmt.Errorf("%w: release: missing action", ErrInvalidEvent)
+	}
+	if e.Release.TagName == "" {
+		return fmt.Errorf("%w: release: missing tag_name", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// WorkflowDispatchEvent is the payload of the workflow_dispatch event.
+type WorkflowDispatchEvent struct {
+	Ref        string         `json:"ref"`
+	Workflow   string         `json:"workflow"`
+	Inputs     map[string]any `json:"inputs"`
+	Repository Repository     `json:"repository"`
+}
+
+// EventName implements Event.EventName.
+func (*WorkflowDispatchEvent) EventName() string { return EventWorkflowDispatch }
+
+// Validate implements Event.Validate. Inputs must be strings, booleans,
+// numbers or null.
+func (e *WorkflowDispatchEvent) Validate() error {
+	if !strings.HasPrefix(e.Ref, "refs/") {
+		return fmt.Errorf("%w: workflow_dispatch: unexpected ref %q", ErrInvalidEvent, e.Ref)
+	}
+	return validateInputs(e.Inputs)
+}
+
+// WorkflowCallEvent is the payload of a workflow_call event.
+type WorkflowCallEvent struct {
+	Inputs map[string]any `json:"inputs"`
+}
+
+// EventName implements Event.EventName.
+func (*WorkflowCallEvent) EventName() string { return EventWorkflowCall }
+
+// Validate implements Event.Validate. Inputs must be strings, booleans,
+// numbers or null.
+func (e *WorkflowCallEvent) Validate() error {
+	return validateInputs(e.Inputs)
+}
+
+// PullRequestBranch is the head or base of a pull request.
+type PullRequestBranch struct {
+	Ref  string      `json:"ref"`
+	SHA  string      `json:"sha"`
+	Repo *Repository `json:"repo"`
+}
+
+// PullRequest is the pull request in a pull_request event payload.
+type PullRequest struct {
+	Number int               `json:"number"`
+	State  string            `json:"state"`
+	Merged bool              `json:"merged"`
+	Head   PullRequestBranch `json:"head"`
+	Base   PullRequestBranch `json:"base"`
+}
+
+// PullRequestEvent is the payload of the pull_request event.
+type PullRequestEvent struct {
+	Action      string      `json:"action"`
+	Number      int         `json:"number"`
+	PullRequest PullRequest `json:"pull_request"`
+	Repository  Repository  `json:"repository"`
+}
+
+// EventName implements Event.EventName.
+func (*PullRequestEvent) EventName() string { return EventPullRequest }
+
+// Validate implements Event.Validate.
+func (e *PullRequestEvent) Validate() error {
+	if e.Number <= 0 || e.PullRequest.Number != e.Number {
+		return fmt.Errorf("%w: pull_request: unexpected number %d", ErrInvalidEvent, e.Number)
+	}
+	if e.PullRequest.Head.SHA == "" || e.PullRequest.Base.Ref == "" {
+		return fmt.Errorf("%w: pull_request: missing head sha or base ref", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// IsFork returns whether the pull request comes from a fork.
+func (e *PullRequestEvent) IsFork() bool {
+	head, base := e.PullRequest.Head.Repo, e.PullRequest.Base.Repo
+	if head == nil || base == nil {
+		return false
+	}
+	return head.ID != base.ID
+}
+
+// ScheduleEvent is the payload of the schedule event.
+type ScheduleEvent struct {
+	Schedule string `json:"schedule"`
+}
+
+// EventName implements Event.EventName.
+func (*ScheduleEvent) EventName() string { return EventSchedule }
+
+// Validate implements Event.Validate.
+func (e *ScheduleEvent) Validate() error {
+	if e.Schedule == "" {
+		return fmt.Errorf("%w: schedule: missing schedule", ErrInvalidEvent)
+	}
+	return nil
+}
+
+// CreateEvent is the payload of the create event, which is sent when a branch
+// or tag is created.
+type CreateEvent struct {
+	Ref        string     `json:"ref"`
+	RefType    string     `json:"ref_type"`
+	Repository Repository `json:"repository"`
+}
+
+// EventName implements Event.EventName.
+func (*CreateEvent) EventName() string { return EventCreate }
+
+// Validate implements Event.Validate.
+func (e *CreateEvent) Validate() error {
+	if e.Ref == "" {
+		return fmt.Errorf("%w: create: missing ref", ErrInvalidEvent)
+	}
+	if e.RefType != refTypeTag && e.RefType != refTypeBranch {
+		return fmt.Errorf("%w: create: unexpected ref_type %q", ErrInvalidEvent, e.RefType)
+	}
+	return nil
+}
+
+// Tag returns the tag that was created, if any.
+func (e *CreateEvent) Tag() (string, bool) {
+	if e.RefType != refTypeTag {
+		return "", false
+	}
+	return e.Ref, true
+}
+
+// UnknownEvent is the payload of an event without a typed model.
+type UnknownEvent struct {
+	Name    string
+	Payload EventPayload
+}
+
+// EventName implements Event.EventName.
+func (e *UnknownEvent) EventName() string { return e.Name }
+
+// Validate implements Event.Validate. Unknown events are not validated.
+func (*UnknownEvent) Validate() error { return nil }
+
+// ParseEvent parses and validates the payload of the event with the given
+// name. Events without a typed model are returned as an *UnknownEvent.
+func ParseEvent(name string, payload EventPayload) (Event, error) {
+	var e Event
+	switch name {
+	case EventPush:
+		e = &PushEvent{}
+	case EventRelease:
+		e = &ReleaseEvent{}
+	case EventWorkflowDispatch:
+		e = &WorkflowDispatchEvent{}
+	case EventWorkflowCall:
+		e = &WorkflowCallEvent{}
+	case EventPullRequest:
+		e = &PullRequestEvent{}
+	case EventSchedule:
+		e = &ScheduleEvent{}
+	case EventCreate:
+		e = &CreateEvent{}
+	default:
+		return &UnknownEvent{Name: name, Payload: payload}, nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: json.Marshal: %w", ErrInvalidEvent, name, err)
+	}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, fmt.Errorf("%w: %s: json.Unmarshal: %w", ErrInvalidEvent, name, err)
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Inputs returns the inputs in the event payload, if any. Only
+// workflow_dispatch and workflow_call events have inputs. Inputs must be
+// strings, booleans, numbers or null.
+func (p EventPayload) Inputs() (map[string]any, error) {
+	v, ok := p["inputs"]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	inputs, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected inputs type %T", ErrInvalidEvent, v)
+	}
+	if err := validateInputs(inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// validateInputs checks that the inputs are strings, booleans, numbers or
+// null. Optional inputs without a default value are null.
+func validateInputs(inputs map[string]any) error {
+	for k, v := range inputs {
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Invalid, reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+		default:
+			return fmt.Errorf("%w: input %q has unsupported type %T", ErrInvalidEvent, k, v)
+		}
+	}
+	return nil
+}
+
+// ParseEvent parses and validates the event that triggered the workflow run.
+func (c *WorkflowContext) ParseEvent() (Event, error) {
+	return ParseEvent(c.EventName, c.Event)
+}
+
+// Tag returns the tag the workflow run was triggered for, if any. The tag is
+// taken from the ref, or from the release for release events.
+func (c *WorkflowContext) Tag() (string, bool) {
+	if tag, ok := cutRef(c.Ref, refsTagsPrefix); ok {
+		return tag, true
+	}
+	if c.EventName == EventRelease {
+		if e, err := c.ParseEvent(); err == nil {
+			return e.(*ReleaseEvent).Release.TagName, true
+		}
+	}
+	return "", false
+}
+
+// Branch returns the branch the workflow run was triggered for, if any.
+func (c *WorkflowContext) Branch() (string, bool) {
+	return cutRef(c.Ref, refsHeadsPrefix)
+}
+
+// cutRef returns the name of the ref with the given prefix.
+func cutRef(ref, prefix string) (string, bool) {
+	name, ok := strings.CutPrefix(ref, prefix)
+	if !ok {
+		return "", false
+	}
+	return name, true
+}
+
+// Release returns the release that triggered the workflow run, if any.
+func (c *WorkflowContext) Release() (*Release, error) {
+	if c.EventName != EventRelease {
+		return nil, nil
+	}
+	e, err := c.ParseEvent()
+	if err != nil {
+		return nil, err
+	}
+	return &e.(*ReleaseEvent).Release, nil
+}
@@ -0,0 +1,327 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// payload decodes a JSON event payload the same way as GetWorkflowContext.
+func payload(t *testing.T, s string) EventPayload {
+	t.Helper()
+
+	var p EventPayload
+	if err := json.Unmarshal([]byte(s), &p); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	return p
+}
+
+func TestParseEvent(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		event    string
+		payload  string
+		expected Event
+		err      error
+	}{
+		{
+			name:    "push",
+			event:   EventPush,
+			payload: `{"ref":"refs/heads/main","before":"abc","after":"def","head_commit":{"id":"def","message":"msg"},"repository":{"id":1,"full_name":"owner/repo"}}`,
+			expected: &PushEvent{
+				Ref:        "refs/heads/main",
+				Before:     "abc",
+				After:      "def",
+				HeadCommit: &Commit{ID: "def", Message: "msg"},
+				Repository: Repository{ID: 1, FullName: "owner/repo"},
+			},
+		},
+		{
+			name:    "push deleted",
+			event:   EventPush,
+			payload: `{"ref":"refs/tags/v1.0.0","deleted":true}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "push invalid ref",
+			event:   EventPush,
+			payload: `{"ref":"main"}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "release",
+			event:   EventRelease,
+			payload: `{"action":"published","release":{"id":2,"tag_name":"v1.0.0","target_commitish":"main","prerelease":true}}`,
+			expected: &ReleaseEvent{
+				Action: "published",
+				Release: Release{
+					ID:              2,
+					TagName:         "v1.0.0",
+					TargetCommitish: "main",
+					Prerelease:      true,
+				},
+			},
+		},
+		{
+			name:    "release missing tag",
+			event:   EventRelease,
+			payload: `{"action":"published","release":{"id":2}}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "workflow_dispatch",
+			event:   EventWorkflowDispatch,
+			payload: `{"ref":"refs/heads/main","workflow":".github/workflows/release.yml","inputs":{"version":"v1","dry":true,"count":2}}`,
+			expected: &WorkflowDispatchEvent{
+				Ref:      "refs/heads/main",
+				Workflow: ".github/workflows/release.yml",
+				Inputs: map[string]any{
+					"version": "v1",
+					"dry":     true,
+					"count":   float64(2),
+				},
+			},
+		},
+		{
+			name:    "workflow_dispatch nested input",
+			event:   EventWorkflowDispatch,
+			payload: `{"ref":"refs/heads/main","inputs":{"version":{"major":1}}}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "workflow_call",
+			event:   EventWorkflowCall,
+			payload: `{"inputs":{"version":"v1"}}`,
+			expected: &WorkflowCallEvent{
+				Inputs: map[string]any{"version": "v1"},
+			},
+		},
+		{
+			name:    "workflow_call list input",
+			event:   EventWorkflowCall,
+			payload: `{"inputs":{"versions":["v1"]}}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "pull_request",
+			event:   EventPullRequest,
+			payload: `{"action":"opened","number":3,"pull_request":{"number":3,"state":"open","head":{"ref":"feature","sha":"abc"},"base":{"ref":"main","sha":"def"}}}`,
+			expected: &PullRequestEvent{
+				Action: "opened",
+				Number: 3,
+				PullRequest: PullRequest{
+					Number: 3,
+					State:  "open",
+					Head:   PullRequestBranch{Ref: "feature", SHA: "abc"},
+					Base:   PullRequestBranch{Ref: "main", SHA: "def"},
+				},
+			},
+		},
+		{
+			name:    "pull_request mismatched number",
+			event:   EventPullRequest,
+			payload: `{"action":"opened","number":3,"pull_request":{"number":4,"head":{"sha":"abc"},"base":{"ref":"main"}}}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:     "schedule",
+			event:    EventSchedule,
+			payload:  `{"schedule":"0 0 * * *"}`,
+			expected: &ScheduleEvent{Schedule: "0 0 * * *"},
+		},
+		{
+			name:    "schedule missing",
+			event:   EventSchedule,
+			payload: `{}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:     "create tag",
+			event:    EventCreate,
+			payload:  `{"ref":"v1.0.0","ref_type":"tag"}`,
+			expected: &CreateEvent{Ref: "v1.0.0", RefType: "tag"},
+		},
+		{
+			name:    "create invalid ref type",
+			event:   EventCreate,
+			payload: `{"ref":"v1.0.0","ref_type":"repository"}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "wrong field type",
+			event:   EventPush,
+			payload: `{"ref":1}`,
+			err:     ErrInvalidEvent,
+		},
+		{
+			name:    "unknown",
+			event:   "issue_comment",
+			payload: `{"action":"created"}`,
+			expected: &UnknownEvent{
+				Name:    "issue_comment",
+				Payload: map[string]any{"action": "created"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			e, err := ParseEvent(tc.event, payload(t, tc.payload))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, e); diff != "" {
+				t.Errorf("unexpected event (-want +got):\n%s", diff)
+			}
+			if e != nil && e.EventName() != tc.event {
+				t.Errorf("unexpected event name, want: %q, got: %q", tc.event, e.EventName())
+			}
+		})
+	}
+}
+
+func TestEventPayload_Inputs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		payload  EventPayload
+		expected map[string]any
+		err      error
+	}{
+		{
+			name: "no inputs",
+		},
+		{
+			name: "scalar inputs",
+			payload: map[string]any{
+				"inputs": map[string]any{"a": "b", "c": 2, "d": false},
+			},
+			expected: map[string]any{"a": "b", "c": 2, "d": false},
+		},
+		{
+			name: "not an object",
+			payload: map[string]any{
+				"inputs": "a=b",
+			},
+			err: ErrInvalidEvent,
+		},
+		{
+			name: "null input",
+			payload: map[string]any{
+				"inputs": map[string]any{"a": nil, "b": "c"},
+			},
+			expected: map[string]any{"a": nil, "b": "c"},
+		},
+		{
+			name: "object input",
+			payload: map[string]any{
+				"inputs": map[string]any{"a": map[string]any{"b": "c"}},
+			},
+			err: ErrInvalidEvent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inputs, err := tc.payload.Inputs()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, inputs); diff != "" {
+				t.Errorf("unexpected inputs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWorkflowContext_refHelpers(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		context WorkflowContext
+		tag     string
+		branch  string
+		release *Release
+	}{
+		{
+			name: "branch push",
+			context: WorkflowContext{
+				EventName: EventPush,
+				Ref:       "refs/heads/main",
+			},
+			branch: "main",
+		},
+		{
+			name: "tag push",
+			context: WorkflowContext{
+				EventName: EventPush,
+				Ref:       "refs/tags/v1.0.0",
+			},
+			tag: "v1.0.0",
+		},
+		{
+			name: "release",
+			context: WorkflowContext{
+				EventName: EventRelease,
+				Ref:       "refs/tags/v1.0.0",
+				Event:     payload(t, `{"action":"published","release":{"id":2,"tag_name":"v1.0.0"}}`),
+			},
+			tag:     "v1.0.0",
+			release: &Release{ID: 2, TagName: "v1.0.0"},
+		},
+		{
+			name: "release without tag ref",
+			context: WorkflowContext{
+				EventName: EventRelease,
+				Event:     payload(t, `{"action":"published","release":{"id":2,"tag_name":"v2.0.0"}}`),
+			},
+			tag:     "v2.0.0",
+			release: &Release{ID: 2, TagName: "v2.0.0"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tag, _ := tc.context.Tag(); tag != tc.tag {
+				t.Errorf("unexpected tag, want: %q, got: %q", tc.tag, tag)
+			}
+			if branch, _ := tc.context.Branch(); branch != tc.branch {
+				t.Errorf("unexpected branch, want: %q, got: %q", tc.branch, branch)
+			}
+			release, err := tc.context.Release()
+			if err != nil {
+				t.Fatalf("Release: %v", err)
+			}
+			if diff := cmp.Diff(tc.release, release); diff != "" {
+				t.Errorf("unexpected release (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
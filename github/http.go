@@ -0,0 +1,235 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultHTTPTimeout is the timeout of a request, including retries.
+	defaultHTTPTimeout = 2 * time.Minute
+
+	// defaultMaxRetries is the number of times a request is retried.
+	defaultMaxRetries = 4
+
+	// defaultMinBackoff and defaultMaxBackoff bound the backoff between
+	// retries when the server doesn't say how long to wait.
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 15 * time.Second
+
+	// defaultMaxRetryWait is the longest wait requested by the server via
+	// Retry-After or rate-limit headers that is honored. Responses asking to
+	// wait longer are returned to the caller.
+	defaultMaxRetryWait = time.Minute
+)
+
+// retryableStatus are the response statuses of transient errors.
+var retryableStatus = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// idempotentMethods are the methods of requests that can be retried.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// NewHTTPClient returns an HTTP client for GitHub APIs. Requests time out and
+// are retried with jittered exponential backoff on transient errors and rate
+// limits. Only idempotent requests are retried, as with http.Transport:
+// requests with a method that is not idempotent opt in to retries with an
+// Idempotency-Key or X-Idempotency-Key header.
+func NewHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:   defaultHTTPTimeout,
+		Transport: newRetryTransport(http.DefaultTransport),
+	}
+}
+
+// retryTransport is an http.RoundTripper that retries requests on transient
+// errors.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	maxWait    time.Duration
+
+	// now returns the current time. It is used to compute the wait from
+	// rate-limit reset times.
+	now func() time.Time
+
+	// jitter returns a random duration in [0, d).
+	jitter func(d time.Duration) time.Duration
+
+	// sleep waits for d or until ctx is done.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryTransport(base http.RoundTripper) *retryTransport {
+	return &retryTransport{
+		base:       base,
+		maxRetries: defaultMaxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		maxWait:    defaultMaxRetryWait,
+		now:        time.Now,
+		jitter: func(d time.Duration) time.Duration {
+			if d <= 0 {
+				return 0
+			}
+			//nolint:gosec // The jitter doesn't need to be cryptographically secure.
+			return time.Duration(rand.Int63n(int64(d)))
+		},
+		sleep: sleepContext,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.RoundTrip.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		r := req
+		if attempt > 0 {
+			var err error
+			if r, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := t.base.RoundTrip(r)
+		if attempt >= t.maxRetries || !canRetry(req) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait, retry := t.retryWait(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		if resp != nil {
+			// Drain the body so that the connection can be reused.
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		if err := t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryWait returns how long to wait before retrying a request that returned
+// resp and err, and whether it should be retried.
+func (t *retryTransport) retryWait(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		// Network errors are retried.
+		return t.backoff(attempt), true
+	}
+
+	if wait, ok := t.serverWait(resp); ok {
+		if wait > t.maxWait {
+			return 0, false
+		}
+		return wait, true
+	}
+
+	if !retryableStatus[resp.StatusCode] {
+		return 0, false
+	}
+	return t.backoff(attempt), true
+}
+
+// serverWait returns the wait requested by the server via the Retry-After
+// header, or the rate-limit reset time if the rate limit is exhausted.
+func (t *retryTransport) serverWait(resp *http.Response) (time.Duration, bool) {
+	// Primary and secondary rate limits are reported with 403 or 429.
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests &&
+		resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+			return time.Duration(s) * time.Second, true
+		}
+		if d, err := http.ParseTime(v); err == nil {
+			return max(d.Sub(t.now()), 0), true
+		}
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if s, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(s, 0).Sub(t.now()), 0), true
+		}
+	}
+	return 0, false
+}
+
+// backoff returns the jittered exponential backoff for the attempt.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.minBackoff << attempt
+	if d > t.maxBackoff || d <= 0 {
+		d = t.maxBackoff
+	}
+	// Wait at least half of the backoff.
+	return d/2 + t.jitter(d/2)
+}
+
+// canRetry returns whether the request is idempotent and can be sent again.
+func canRetry(req *http.Request) bool {
+	if !idempotentMethods[req.Method] && req.Header.Get("Idempotency-Key") == "" &&
+		req.Header.Get("X-Idempotency-Key") == "" {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequest returns a copy of the request with a fresh body.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,357 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testRetryTransport returns a retryTransport that records its waits instead
+// of sleeping and doesn't add jitter.
+func testRetryTransport(now time.Time, waits *[]time.Duration) *retryTransport {
+	t := newRetryTransport(http.DefaultTransport)
+	t.now = func() time.Time { return now }
+	t.jitter = func(time.Duration) time.Duration { return 0 }
+	t.sleep = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return t
+}
+
+// response is a canned response of a fake server.
+type response struct {
+	status int
+	header map[string]string
+}
+
+func TestRetryTransport(t *testing.T) {
+	now := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		method    string
+		body      string
+		header    map[string]string
+		responses []response
+		status    int
+		requests  int
+		waits     []time.Duration
+	}{
+		{
+			name:      "success",
+			responses: []response{{status: http.StatusOK}},
+			status:    http.StatusOK,
+			requests:  1,
+		},
+		{
+			name: "transient errors",
+			responses: []response{
+				{status: http.StatusBadGateway},
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 3,
+			waits:    []time.Duration{250 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name: "retries exhausted",
+			responses: []response{
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+				{status: http.StatusInternalServerError},
+			},
+			status:   http.StatusInternalServerError,
+			requests: 5,
+			waits: []time.Duration{
+				250 * time.Millisecond, 500 * time.Millisecond,
+				time.Second, 2 * time.Second,
+			},
+		},
+		{
+			name:      "not retryable",
+			responses: []response{{status: http.StatusNotFound}},
+			status:    http.StatusNotFound,
+			requests:  1,
+		},
+		{
+			name: "retry after seconds",
+			responses: []response{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "7"}},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{7 * time.Second},
+		},
+		{
+			name: "retry after date",
+			responses: []response{
+				{
+					status: http.StatusServiceUnavailable,
+					header: map[string]string{"Retry-After": now.Add(3 * time.Second).Format(http.TimeFormat)},
+				},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{3 * time.Second},
+		},
+		{
+			name: "secondary rate limit",
+			responses: []response{
+				{status: http.StatusForbidden, header: map[string]string{"Retry-After": "30"}},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{30 * time.Second},
+		},
+		{
+			name: "primary rate limit",
+			responses: []response{
+				{
+					status: http.StatusForbidden,
+					header: map[string]string{
+						"X-RateLimit-Remaining": "0",
+						"X-RateLimit-Reset":     strconv.FormatInt(now.Add(10*time.Second).Unix(), 10),
+					},
+				},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{10 * time.Second},
+		},
+		{
+			name: "rate limit reset too late",
+			responses: []response{
+				{
+					status: http.StatusForbidden,
+					header: map[string]string{
+						"X-RateLimit-Remaining": "0",
+						"X-RateLimit-Reset":     strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+					},
+				},
+			},
+			status:   http.StatusForbidden,
+			requests: 1,
+		},
+		{
+			name:      "forbidden",
+			responses: []response{{status: http.StatusForbidden}},
+			status:    http.StatusForbidden,
+			requests:  1,
+		},
+		{
+			name:   "put with body",
+			method: http.MethodPut,
+			body:   "payload",
+			responses: []response{
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{250 * time.Millisecond},
+		},
+		{
+			name:   "post not retried",
+			method: http.MethodPost,
+			body:   "payload",
+			responses: []response{
+				{status: http.StatusServiceUnavailable},
+			},
+			status:   http.StatusServiceUnavailable,
+			requests: 1,
+		},
+		{
+			name:   "post with idempotency key",
+			method: http.MethodPost,
+			body:   "payload",
+			header: map[string]string{"Idempotency-Key": "hoge"},
+			responses: []response{
+				{status: http.StatusServiceUnavailable},
+				{status: http.StatusOK},
+			},
+			status:   http.StatusOK,
+			requests: 2,
+			waits:    []time.Duration{250 * time.Millisecond},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests int
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, err := io.ReadAll(r.Body)
+				if err != nil || string(b) != tc.body {
+					t.Errorf("unexpected body %q: %v", b, err)
+				}
+				resp := tc.responses[requests]
+				requests++
+				for k, v := range resp.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(resp.status)
+			}))
+			defer s.Close()
+
+			var waits []time.Duration
+			c := &http.Client{Transport: testRetryTransport(now, &waits)}
+
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req, err := http.NewRequest(method, s.URL, body)
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			if want, got := tc.status, resp.StatusCode; want != got {
+				t.Errorf("unexpected status, want: %d, got: %d", want, got)
+			}
+			if want, got := tc.requests, requests; want != got {
+				t.Errorf("unexpected number of requests, want: %d, got: %d", want, got)
+			}
+			if diff := cmp.Diff(tc.waits, waits); diff != "" {
+				t.Errorf("unexpected waits (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRetryTransport_networkError(t *testing.T) {
+	var waits []time.Duration
+	rt := testRetryTransport(time.Now(), &waits)
+	var attempts int
+	rt.base = roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := http.StatusOK, resp.StatusCode; want != got {
+		t.Errorf("unexpected status, want: %d, got: %d", want, got)
+	}
+	if want, got := 3, attempts; want != got {
+		t.Errorf("unexpected number of attempts, want: %d, got: %d", want, got)
+	}
+}
+
+func TestRetryTransport_canceled(t *testing.T) {
+	rt := newRetryTransport(roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	}))
+	rt.minBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error, want: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestToken_retry(t *testing.T) {
+	now := time.Date(2022, 4, 14, 12, 24, 0, 0, time.UTC)
+
+	token := &OIDCToken{
+		Audience:          []string{"hoge"},
+		Expiry:            now.Add(1 * time.Hour),
+		JobWorkflowRef:    "pico",
+		RepositoryID:      "1234",
+		RepositoryOwnerID: "4321",
+		ActorID:           "4567",
+	}
+	s, c := NewTestOIDCServer(t, now, token)
+	defer s.Close()
+
+	// Fail the first token requests with transient errors.
+	var requests int
+	handler := s.Config.Handler
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			requests++
+			if requests < 3 {
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, fmt.Sprintf("attempt %d", requests), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	var waits []time.Duration
+	c.httpClient = &http.Client{Transport: testRetryTransport(now, &waits)}
+
+	got, err := c.Token(context.Background(), []string{"hoge"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !tokenEqual(s.URL, token, got) {
+		t.Errorf("unexpected token: %v", cmp.Diff(token, got))
+	}
+	if diff := cmp.Diff([]time.Duration{time.Second, time.Second}, waits); diff != "" {
+		t.Errorf("unexpected waits (-want +got):\n%s", diff)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
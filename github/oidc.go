@@ -17,6 +17,7 @@ package github
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,18 +26,28 @@ import (
 	"net/url"
 	"os"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/go-oidc/v3/oidc"
+	"gopkg.in/square/go-jose.v2"
 )
 
-var defaultActionsProviderURL = "https://token.actions.githubusercontent.com"
+// ActionsIssuer is the issuer of GitHub Actions OIDC tokens.
+const ActionsIssuer = "https://token.actions.githubusercontent.com"
+
+var defaultActionsProviderURL = ActionsIssuer
 
 const (
 	requestTokenEnvKey = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
 	requestURLEnvKey   = "ACTIONS_ID_TOKEN_REQUEST_URL"
 )
 
+// tokenExpiryMargin is the minimum remaining lifetime of a cached token for
+// it to be reused.
+const tokenExpiryMargin = 2 * time.Minute
+
 // OIDCToken represents the contents of a GitHub OIDC JWT token.
 type OIDCToken struct {
 	// Issuer is the token issuer.
@@ -54,6 +65,34 @@ type OIDCToken struct {
 	// ActorID is the unique ID of the actor who triggered the build.
 	ActorID string `json:"actor_id"`
 
+	// Repository is the owner and name of the repository.
+	Repository string `json:"repository"`
+
+	// Ref is the git ref that triggered the workflow run.
+	Ref string `json:"ref"`
+
+	// SHA is the commit SHA that triggered the workflow run.
+	SHA string `json:"sha"`
+
+	// WorkflowRef is a reference to the workflow of the workflow run.
+	WorkflowRef string `json:"workflow_ref"`
+
+	// JobWorkflowSHA is the commit SHA of the current job workflow.
+	JobWorkflowSHA string `json:"job_workflow_sha"`
+
+	// RunID is the ID of the workflow run.
+	RunID string `json:"run_id"`
+
+	// RunAttempt is the attempt number of the workflow run.
+	RunAttempt string `json:"run_attempt"`
+
+	// EventName is the name of the event that triggered the workflow run.
+	EventName string `json:"event_name"`
+
+	// RunnerEnvironment is the environment of the runner executing the job,
+	// either "github-hosted" or "self-hosted".
+	RunnerEnvironment string `json:"runner_environment"`
+
 	// Expiry is the expiration date of the token.
 	Expiry time.Time
 
@@ -76,6 +115,13 @@ var (
 
 	// errVerify indicates an error in the token verification process.
 	errVerify = errors.New("verify")
+
+	// ErrInvalidKeySet indicates that a JSON Web Key Set is invalid.
+	ErrInvalidKeySet = errors.New("invalid key set")
+
+	// ErrContextMismatch indicates that the claims of the token disagree with
+	// the workflow context.
+	ErrContextMismatch = errors.New("token claims do not match workflow context")
 )
 
 // OIDCClient is a client for the GitHub OIDC provider.
@@ -89,6 +135,30 @@ type OIDCClient struct {
 
 	// bearerToken is used to request an ID token.
 	bearerToken string
+
+	// httpClient is used to request tokens and the provider's keys.
+	httpClient *http.Client
+
+	// now returns the current time. It is used to check the expiry of cached
+	// tokens.
+	now func() time.Time
+
+	// mu protects verifier and tokens.
+	mu sync.Mutex
+
+	// verifier is the verifier shared by all token requests.
+	verifier *oidc.IDTokenVerifier
+
+	// tokens are the cached tokens keyed by audience.
+	tokens map[string]*tokenEntry
+}
+
+// tokenEntry is a cached token. Its mutex is held while the token is
+// requested so that concurrent requests for the same audience are only sent
+// once.
+type tokenEntry struct {
+	mu    sync.Mutex
+	token *OIDCToken
 }
 
 // NewOIDCClient returns new GitHub OIDC provider client.
@@ -106,8 +176,11 @@ func NewOIDCClient() (*OIDCClient, error) {
 	c := OIDCClient{
 		requestURL:  parsedURL,
 		bearerToken: os.Getenv(requestTokenEnvKey),
+		httpClient:  NewHTTPClient(),
 	}
 	c.verifierFunc = func(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+		// NOTE: The client is also used by the key set to fetch the keys.
+		ctx = oidc.ClientContext(ctx, c.httpClient)
 		provider, err := oidc.NewProvider(ctx, defaultActionsProviderURL)
 		if err != nil {
 			return nil, err
@@ -122,6 +195,65 @@ func NewOIDCClient() (*OIDCClient, error) {
 	return &c, nil
 }
 
+// NewStaticOIDCClient returns a client that verifies tokens offline using the
+// issuer and signing keys given instead of discovering them from the provider.
+// It can't request new tokens and is meant for verifying existing tokens with
+// VerifyToken.
+func NewStaticOIDCClient(issuer string, keys *jose.JSONWebKeySet) (*OIDCClient, error) {
+	if _, err := url.ParseRequestURI(issuer); err != nil {
+		return nil, fmt.Errorf("%w: invalid issuer %q: %w", errURLError, issuer, err)
+	}
+
+	var publicKeys []crypto.PublicKey
+	if keys != nil {
+		for _, k := range keys.Keys {
+			if !k.Valid() {
+				return nil, fmt.Errorf("%w: key %q is not valid", ErrInvalidKeySet, k.KeyID)
+			}
+			publicKeys = append(publicKeys, k.Public().Key)
+		}
+	}
+	if len(publicKeys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrInvalidKeySet)
+	}
+
+	c := OIDCClient{}
+	keySet := &oidc.StaticKeySet{PublicKeys: publicKeys}
+	c.verifierFunc = func(context.Context) (*oidc.IDTokenVerifier, error) {
+		return oidc.NewVerifier(issuer, keySet, &oidc.Config{
+			// NOTE: The audience is checked by the client.
+			SkipClientIDCheck: true,
+			Now:               c.currentTime,
+		}), nil
+	}
+	return &c, nil
+}
+
+// ParseJWKS parses a JSON Web Key Set such as the one served by GitHub's
+// provider at https://token.actions.githubusercontent.com/.well-known/jwks.
+func ParseJWKS(b []byte) (*jose.JSONWebKeySet, error) {
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKeySet, err)
+	}
+	return &keys, nil
+}
+
+// WithClock sets the function used to get the current time when checking the
+// expiry of tokens.
+func (c *OIDCClient) WithClock(now func() time.Time) *OIDCClient {
+	c.now = now
+	return c
+}
+
+// currentTime returns the current time of the client's clock.
+func (c *OIDCClient) currentTime() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
 func (c *OIDCClient) newRequestURL(audience []string) string {
 	requestURL := *c.requestURL
 	q := requestURL.Query()
@@ -133,6 +265,10 @@ func (c *OIDCClient) newRequestURL(audience []string) string {
 }
 
 func (c *OIDCClient) requestToken(ctx context.Context, audience []string) ([]byte, error) {
+	if c.requestURL == nil {
+		return nil, fmt.Errorf("%w: client can't request tokens", errURLError)
+	}
+
 	// Request the token.
 	req, err := http.NewRequest("GET", c.newRequestURL(audience), nil)
 	if err != nil {
@@ -140,7 +276,11 @@ func (c *OIDCClient) requestToken(ctx context.Context, audience []string) ([]byt
 	}
 	req.Header.Add("Authorization", "bearer "+c.bearerToken)
 	req = req.WithContext(ctx)
-	resp, err := http.DefaultClient.Do(req)
+	client := c.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w", errRequestError, err)
 	}
@@ -169,10 +309,25 @@ func (c *OIDCClient) decodePayload(b []byte) (string, error) {
 	return payload.Value, nil
 }
 
-// verifyToken verifies the token contents and signature.
-func (c *OIDCClient) verifyToken(ctx context.Context, audience []string, payload string) (*oidc.IDToken, error) {
-	// Verify the token.
-	verifier, err := c.verifierFunc(ctx)
+// getVerifier returns the shared token verifier, creating it on first use.
+// The verifier caches the provider's signing keys.
+func (c *OIDCClient) getVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.verifier == nil {
+		verifier, err := c.verifierFunc(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.verifier = verifier
+	}
+	return c.verifier, nil
+}
+
+// verifySignature verifies the token signature, issuer and expiry.
+func (c *OIDCClient) verifySignature(ctx context.Context, payload string) (*oidc.IDToken, error) {
+	verifier, err := c.getVerifier(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%w: creating verifier: %w", errVerify, err)
 	}
@@ -181,6 +336,15 @@ func (c *OIDCClient) verifyToken(ctx context.Context, audience []string, payload
 	if err != nil {
 		return nil, fmt.Errorf("%w: could not verify token: %w", errVerify, err)
 	}
+	return t, nil
+}
+
+// verifyToken verifies the token contents and signature.
+func (c *OIDCClient) verifyToken(ctx context.Context, audience []string, payload string) (*oidc.IDToken, error) {
+	t, err := c.verifySignature(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
 
 	// Verify the audience received is the one we requested.
 	if !compareStringSlice(audience, t.Audience) {
@@ -220,9 +384,51 @@ func (c *OIDCClient) verifyClaims(token *OIDCToken) error {
 	return nil
 }
 
-// Token requests an OIDC token from GitHub's provider, verifies it, and
-// returns the token.
+// Token returns a verified OIDC token for the audience. Tokens are requested
+// from GitHub's provider once per audience and cached until they are about to
+// expire. Token is safe for concurrent use.
 func (c *OIDCClient) Token(ctx context.Context, audience []string) (*OIDCToken, error) {
+	entry := c.tokenEntry(audience)
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.token == nil || !c.currentTime().Add(tokenExpiryMargin).Before(entry.token.Expiry) {
+		token, err := c.newToken(ctx, audience)
+		if err != nil {
+			return nil, err
+		}
+		entry.token = token
+	}
+
+	// Return a copy so that callers can't modify the cached token.
+	token := *entry.token
+	token.Audience = append([]string{}, entry.token.Audience...)
+	return &token, nil
+}
+
+// tokenEntry returns the cache entry for the audience.
+func (c *OIDCClient) tokenEntry(audience []string) *tokenEntry {
+	key := append([]string{}, audience...)
+	sort.Strings(key)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.tokens == nil {
+		c.tokens = make(map[string]*tokenEntry)
+	}
+	k := strings.Join(key, "\x00")
+	entry, ok := c.tokens[k]
+	if !ok {
+		entry = &tokenEntry{}
+		c.tokens[k] = entry
+	}
+	return entry
+}
+
+// newToken requests an OIDC token from GitHub's provider, verifies it, and
+// returns the token.
+func (c *OIDCClient) newToken(ctx context.Context, audience []string) (*OIDCToken, error) {
 	tokenBytes, err := c.requestToken(ctx, audience)
 	if err != nil {
 		return nil, err
@@ -250,6 +456,72 @@ func (c *OIDCClient) Token(ctx context.Context, audience []string) (*OIDCToken,
 	return token, nil
 }
 
+// VerifyToken verifies a raw token, such as one saved from an earlier workflow
+// run, and returns its contents. The audience is only checked if it is not
+// empty.
+func (c *OIDCClient) VerifyToken(ctx context.Context, raw string, audience []string) (*OIDCToken, error) {
+	var t *oidc.IDToken
+	var err error
+	if len(audience) > 0 {
+		t, err = c.verifyToken(ctx, audience, raw)
+	} else {
+		t, err = c.verifySignature(ctx, raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.decodeToken(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.verifyClaims(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// CheckWorkflowContext checks that the claims of the token agree with the
+// workflow context, which is not signed. Claims that are not set in the token
+// are not checked.
+func (t *OIDCToken) CheckWorkflowContext(c *WorkflowContext) error {
+	type claim struct {
+		name           string
+		token, context string
+	}
+	claims := []claim{
+		{"repository", t.Repository, c.Repository},
+		{"ref", t.Ref, c.Ref},
+		{"sha", t.SHA, c.SHA},
+		{"workflow_ref", t.WorkflowRef, c.WorkflowRef},
+		{"run_id", t.RunID, c.RunID},
+		{"run_attempt", t.RunAttempt, c.RunAttempt},
+		{"event_name", t.EventName, c.EventName},
+	}
+	if t.JobWorkflowRef != "" && t.JobWorkflowRef == t.WorkflowRef {
+		// The job runs in the workflow of the run.
+		claims = append(claims, claim{"job_workflow_ref", t.JobWorkflowRef, c.WorkflowRef})
+	} else {
+		// The job runs in a reusable workflow, whose ref is not in the
+		// context. Only its commit SHA is.
+		claims = append(claims, claim{"job_workflow_sha", t.JobWorkflowSHA, c.JobWorkflowSHA})
+	}
+
+	var mismatches []string
+	for _, claim := range claims {
+		if claim.token != "" && claim.token != claim.context {
+			mismatches = append(mismatches,
+				fmt.Sprintf("%s: token %q, context %q", claim.name, claim.token, claim.context))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%w: %s", ErrContextMismatch, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
 func compareStringSlice(s1, s2 []string) bool {
 	// Verify the audience received is the one we requested.
 	if len(s1) != len(s2) {
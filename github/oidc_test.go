@@ -17,16 +17,21 @@ package github
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"gopkg.in/square/go-jose.v2"
 )
 
 // tokenEqual returns whether the tokens are functionally equal for the purposes of the test.
@@ -58,7 +63,8 @@ func tokenEqual(issuer string, wantToken, gotToken *OIDCToken) bool {
 		return false
 	}
 
-	return true
+	// Compare the remaining claims.
+	return cmp.Equal(wantToken, gotToken, cmpopts.IgnoreFields(OIDCToken{}, "Issuer", "Audience", "Expiry"))
 }
 
 func TestNewOIDCClient(t *testing.T) {
@@ -130,6 +136,27 @@ func TestToken(t *testing.T) {
 				ActorID:           "4567",
 			},
 		},
+		{
+			name:     "workflow run claims",
+			audience: []string{"hoge"},
+			token: &OIDCToken{
+				Audience:          []string{"hoge"},
+				Expiry:            now.Add(1 * time.Hour),
+				JobWorkflowRef:    "pico",
+				RepositoryID:      "1234",
+				RepositoryOwnerID: "4321",
+				ActorID:           "4567",
+				Repository:        "owner/repo",
+				Ref:               "refs/heads/main",
+				SHA:               "abcde",
+				WorkflowRef:       "owner/repo/.github/workflows/release.yml@refs/heads/main",
+				JobWorkflowSHA:    "fghij",
+				RunID:             "12345",
+				RunAttempt:        "1",
+				EventName:         "push",
+				RunnerEnvironment: "github-hosted",
+			},
+		},
 		{
 			name:     "no repository id claim",
 			audience: []string{"hoge"},
@@ -294,6 +321,413 @@ func TestToken(t *testing.T) {
 	}
 }
 
+func TestToken_cache(t *testing.T) {
+	now := time.Date(2022, 4, 14, 12, 24, 0, 0, time.UTC)
+
+	token := &OIDCToken{
+		Audience:          []string{"hoge", "fuga"},
+		Expiry:            now.Add(10 * time.Minute),
+		JobWorkflowRef:    "pico",
+		RepositoryID:      "1234",
+		RepositoryOwnerID: "4321",
+		ActorID:           "4567",
+	}
+	s, c := NewTestOIDCServer(t, now, token)
+	defer s.Close()
+
+	var requests atomic.Int32
+	handler := s.Config.Handler
+	s.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			requests.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	var verifiers atomic.Int32
+	verifierFunc := c.verifierFunc
+	c.verifierFunc = func(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+		verifiers.Add(1)
+		return verifierFunc(ctx)
+	}
+
+	clock := now
+	c.now = func() time.Time { return clock }
+
+	// Concurrent requests for the same audience, in any order, only request
+	// the token once.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			audience := []string{"hoge", "fuga"}
+			if i%2 == 0 {
+				audience = []string{"fuga", "hoge"}
+			}
+			got, err := c.Token(context.Background(), audience)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if !tokenEqual(s.URL, token, got) {
+				t.Errorf("unexpected token: %v", cmp.Diff(token, got))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if want, got := int32(1), requests.Load(); want != got {
+		t.Errorf("unexpected number of token requests, want: %d, got: %d", want, got)
+	}
+
+	// The token is requested again once it is about to expire.
+	clock = now.Add(10*time.Minute - tokenExpiryMargin)
+	if _, err := c.Token(context.Background(), []string{"hoge", "fuga"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := int32(2), requests.Load(); want != got {
+		t.Errorf("unexpected number of token requests, want: %d, got: %d", want, got)
+	}
+
+	// The verifier is shared by all requests.
+	if want, got := int32(1), verifiers.Load(); want != got {
+		t.Errorf("unexpected number of verifiers, want: %d, got: %d", want, got)
+	}
+}
+
+func TestToken_cacheErrors(t *testing.T) {
+	now := time.Date(2022, 4, 14, 12, 24, 0, 0, time.UTC)
+
+	s, c := newRawTestOIDCServer(t, now, http.StatusServiceUnavailable, "")
+	defer s.Close()
+
+	// Errors are not cached.
+	for i := 0; i < 2; i++ {
+		if _, err := c.Token(context.Background(), []string{"hoge"}); !errors.Is(err, errRequestError) {
+			t.Fatalf("unexpected error: %v", cmp.Diff(err, errRequestError, cmpopts.EquateErrors()))
+		}
+	}
+	if len(c.tokens) != 1 || c.tokens["hoge"].token != nil {
+		t.Errorf("unexpected cached token: %v", c.tokens)
+	}
+}
+
+func TestOIDCToken_CheckWorkflowContext(t *testing.T) {
+	t.Parallel()
+
+	token := &OIDCToken{
+		Repository:  "owner/repo",
+		Ref:         "refs/heads/main",
+		SHA:         "abcde",
+		WorkflowRef: "owner/repo/.github/workflows/release.yml@refs/heads/main",
+		RunID:       "12345",
+		RunAttempt:  "1",
+		EventName:   "push",
+	}
+	// reusable is a token for a job running in a reusable workflow.
+	reusable := *token
+	reusable.JobWorkflowRef = "owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0"
+	reusable.JobWorkflowSHA = "fedcb"
+	// direct is a token for a job running in the workflow of the run.
+	direct := *token
+	direct.JobWorkflowRef = token.WorkflowRef
+	direct.JobWorkflowSHA = "abcde"
+	wctx := WorkflowContext{
+		Repository:  "owner/repo",
+		Ref:         "refs/heads/main",
+		SHA:         "abcde",
+		WorkflowRef: "owner/repo/.github/workflows/release.yml@refs/heads/main",
+		RunID:       "12345",
+		RunAttempt:  "1",
+		EventName:   "push",
+	}
+
+	testCases := []struct {
+		name    string
+		token   *OIDCToken
+		context func(*WorkflowContext)
+		err     error
+	}{
+		{
+			name:  "match",
+			token: token,
+		},
+		{
+			name:  "no claims",
+			token: &OIDCToken{},
+			context: func(c *WorkflowContext) {
+				c.SHA = "fghij"
+			},
+		},
+		{
+			name:  "sha mismatch",
+			token: token,
+			context: func(c *WorkflowContext) {
+				c.SHA = "fghij"
+			},
+			err: ErrContextMismatch,
+		},
+		{
+			name:  "ref mismatch",
+			token: token,
+			context: func(c *WorkflowContext) {
+				c.Ref = "refs/tags/v1.0.0"
+			},
+			err: ErrContextMismatch,
+		},
+		{
+			name:  "missing run attempt",
+			token: token,
+			context: func(c *WorkflowContext) {
+				c.RunAttempt = ""
+			},
+			err: ErrContextMismatch,
+		},
+		{
+			name:  "repository mismatch",
+			token: token,
+			context: func(c *WorkflowContext) {
+				c.Repository = "attacker/repo"
+			},
+			err: ErrContextMismatch,
+		},
+		{
+			name:  "direct job workflow",
+			token: &direct,
+		},
+		{
+			name: "direct job workflow ref mismatch",
+			token: &OIDCToken{
+				JobWorkflowRef: "attacker/repo/.github/workflows/release.yml@refs/heads/main",
+				WorkflowRef:    "attacker/repo/.github/workflows/release.yml@refs/heads/main",
+			},
+			err: ErrContextMismatch,
+		},
+		{
+			name:  "reusable job workflow",
+			token: &reusable,
+			context: func(c *WorkflowContext) {
+				c.JobWorkflowSHA = "fedcb"
+			},
+		},
+		{
+			name:  "reusable job workflow sha mismatch",
+			token: &reusable,
+			context: func(c *WorkflowContext) {
+				c.JobWorkflowSHA = "01234"
+			},
+			err: ErrContextMismatch,
+		},
+		{
+			name:  "reusable job workflow sha missing",
+			token: &reusable,
+			err:   ErrContextMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := wctx
+			if tc.context != nil {
+				tc.context(&c)
+			}
+			if err := tc.token.CheckWorkflowContext(&c); !errors.Is(err, tc.err) {
+				t.Errorf("unexpected error: %v", cmp.Diff(err, tc.err, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func TestOIDCClient_VerifyToken(t *testing.T) {
+	now := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+	issuer := "https://token.actions.githubusercontent.com"
+
+	token := &OIDCToken{
+		Audience:          []string{"hoge"},
+		Expiry:            now.Add(1 * time.Hour),
+		JobWorkflowRef:    "pico",
+		RepositoryID:      "1234",
+		RepositoryOwnerID: "4321",
+		ActorID:           "4567",
+		Repository:        "owner/repo",
+	}
+	raw, keys := NewTestSignedToken(t, issuer, token)
+	_, otherKeys := NewTestSignedToken(t, issuer, token)
+	noClaimsRaw, noClaimsKeys := NewTestSignedToken(t, issuer, &OIDCToken{
+		Audience: []string{"hoge"},
+		Expiry:   now.Add(1 * time.Hour),
+	})
+
+	testCases := []struct {
+		name     string
+		issuer   string
+		keys     *jose.JSONWebKeySet
+		raw      string
+		audience []string
+		now      time.Time
+		expected *OIDCToken
+		err      error
+	}{
+		{
+			name:     "valid",
+			issuer:   issuer,
+			keys:     keys,
+			raw:      raw,
+			audience: []string{"hoge"},
+			now:      now,
+			expected: token,
+		},
+		{
+			name:     "any audience",
+			issuer:   issuer,
+			keys:     keys,
+			raw:      raw,
+			now:      now,
+			expected: token,
+		},
+		{
+			name:     "wrong audience",
+			issuer:   issuer,
+			keys:     keys,
+			raw:      raw,
+			audience: []string{"fuga"},
+			now:      now,
+			err:      errVerify,
+		},
+		{
+			name:   "wrong key",
+			issuer: issuer,
+			keys:   otherKeys,
+			raw:    raw,
+			now:    now,
+			err:    errVerify,
+		},
+		{
+			name:   "wrong issuer",
+			issuer: "https://example.com",
+			keys:   keys,
+			raw:    raw,
+			now:    now,
+			err:    errVerify,
+		},
+		{
+			name:   "expired",
+			issuer: issuer,
+			keys:   keys,
+			raw:    raw,
+			now:    now.Add(2 * time.Hour),
+			err:    errVerify,
+		},
+		{
+			name:   "not a token",
+			issuer: issuer,
+			keys:   keys,
+			raw:    "hoge",
+			now:    now,
+			err:    errVerify,
+		},
+		{
+			name:   "missing claims",
+			issuer: issuer,
+			keys:   noClaimsKeys,
+			raw:    noClaimsRaw,
+			now:    now,
+			err:    errClaims,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewStaticOIDCClient(tc.issuer, tc.keys)
+			if err != nil {
+				t.Fatalf("NewStaticOIDCClient: %v", err)
+			}
+			c.WithClock(func() time.Time { return tc.now })
+
+			got, err := c.VerifyToken(context.Background(), tc.raw, tc.audience)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if tc.expected != nil && !tokenEqual(issuer, tc.expected, got) {
+				t.Errorf("unexpected token: %v", cmp.Diff(tc.expected, got))
+			}
+		})
+	}
+}
+
+func TestNewStaticOIDCClient(t *testing.T) {
+	_, keys := NewTestSignedToken(t, "https://example.com", &OIDCToken{})
+
+	testCases := []struct {
+		name   string
+		issuer string
+		keys   *jose.JSONWebKeySet
+		err    error
+	}{
+		{
+			name:   "valid",
+			issuer: "https://example.com",
+			keys:   keys,
+		},
+		{
+			name:   "invalid issuer",
+			issuer: "example.com",
+			keys:   keys,
+			err:    errURLError,
+		},
+		{
+			name:   "no key set",
+			issuer: "https://example.com",
+			err:    ErrInvalidKeySet,
+		},
+		{
+			name:   "no keys",
+			issuer: "https://example.com",
+			keys:   &jose.JSONWebKeySet{},
+			err:    ErrInvalidKeySet,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewStaticOIDCClient(tc.issuer, tc.keys)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			// Static clients can't request tokens.
+			if _, err := c.Token(context.Background(), []string{"hoge"}); !errors.Is(err, errURLError) {
+				t.Errorf("unexpected error, want: %v, got: %v", errURLError, err)
+			}
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	_, keys := NewTestSignedToken(t, "https://example.com", &OIDCToken{})
+	b, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	got, err := ParseJWKS(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want, got := keys.Keys[0].KeyID, got.Keys[0].KeyID; len(keys.Keys) != 1 || want != got {
+		t.Errorf("unexpected keys, want: %q, got: %q", want, got)
+	}
+
+	if _, err := ParseJWKS([]byte("not json")); !errors.Is(err, ErrInvalidKeySet) {
+		t.Errorf("unexpected error, want: %v, got: %v", ErrInvalidKeySet, err)
+	}
+}
+
 func Test_compareStringSlice(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -38,10 +38,72 @@ type jsonToken struct {
 	RepositoryID      string   `json:"repository_id"`
 	RepositoryOwnerID string   `json:"repository_owner_id"`
 	ActorID           string   `json:"actor_id"`
+	Repository        string   `json:"repository,omitempty"`
+	Ref               string   `json:"ref,omitempty"`
+	SHA               string   `json:"sha,omitempty"`
+	WorkflowRef       string   `json:"workflow_ref,omitempty"`
+	JobWorkflowSHA    string   `json:"job_workflow_sha,omitempty"`
+	RunID             string   `json:"run_id,omitempty"`
+	RunAttempt        string   `json:"run_attempt,omitempty"`
+	EventName         string   `json:"event_name,omitempty"`
+	RunnerEnvironment string   `json:"runner_environment,omitempty"`
 	Audience          []string `json:"aud"`
 	Expiry            int64    `json:"exp"`
 }
 
+// newJSONToken returns the claims of the token issued by issuer.
+func newJSONToken(issuer string, token *OIDCToken) jsonToken {
+	return jsonToken{
+		Issuer:            issuer,
+		Audience:          token.Audience,
+		Expiry:            token.Expiry.Unix(),
+		JobWorkflowRef:    token.JobWorkflowRef,
+		RepositoryID:      token.RepositoryID,
+		RepositoryOwnerID: token.RepositoryOwnerID,
+		ActorID:           token.ActorID,
+		Repository:        token.Repository,
+		Ref:               token.Ref,
+		SHA:               token.SHA,
+		WorkflowRef:       token.WorkflowRef,
+		JobWorkflowSHA:    token.JobWorkflowSHA,
+		RunID:             token.RunID,
+		RunAttempt:        token.RunAttempt,
+		EventName:         token.EventName,
+		RunnerEnvironment: token.RunnerEnvironment,
+	}
+}
+
+// NewTestSignedToken returns the token issued by issuer as a raw JWT signed
+// with a new key, and a key set containing the public key. It can be used to
+// test offline verification with NewStaticOIDCClient.
+func NewTestSignedToken(t *testing.T, issuer string, token *OIDCToken) (string, *jose.JSONWebKeySet) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := jose.JSONWebKey{Key: privateKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(newJSONToken(issuer, token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, err := signer.Sign(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := object.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return raw, &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public()}}
+}
+
 // testKeySet is an oidc.KeySet that can be used in tests.
 type testKeySet struct{}
 
@@ -83,15 +145,7 @@ func NewTestOIDCServer(t *testing.T, now time.Time, token *OIDCToken) (*httptest
 			issuer = token.Issuer
 		}
 
-		b, err := json.Marshal(jsonToken{
-			Issuer:            issuer,
-			Audience:          token.Audience,
-			Expiry:            token.Expiry.Unix(),
-			JobWorkflowRef:    token.JobWorkflowRef,
-			RepositoryID:      token.RepositoryID,
-			RepositoryOwnerID: token.RepositoryOwnerID,
-			ActorID:           token.ActorID,
-		})
+		b, err := json.Marshal(newJSONToken(issuer, token))
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
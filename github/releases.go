@@ -0,0 +1,213 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v57/github"
+)
+
+var (
+	// ErrAssetConflict indicates a release asset of the same name but
+	// different content.
+	ErrAssetConflict = errors.New("release asset exists with different content")
+
+	// ErrAssetDigestMismatch indicates an uploaded release asset whose
+	// content doesn't match the file.
+	ErrAssetDigestMismatch = errors.New("release asset digest mismatch")
+)
+
+// ReleaseClient publishes files to the releases of a repository.
+type ReleaseClient struct {
+	client *github.Client
+	owner  string
+	repo   string
+}
+
+// NewReleaseClient returns a ReleaseClient for the repository, given as
+// "owner/name".
+func NewReleaseClient(c *github.Client, repository string) (*ReleaseClient, error) {
+	if c == nil {
+		return nil, ErrNoClient
+	}
+	owner, name, err := splitRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+	return &ReleaseClient{client: c, owner: owner, repo: name}, nil
+}
+
+// FindOrCreateRelease returns the release for the tag, which may be a draft,
+// creating it if it doesn't exist.
+func (c *ReleaseClient) FindOrCreateRelease(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	r, resp, err := c.client.Repositories.GetReleaseByTag(ctx, c.owner, c.repo, tag)
+	if err == nil {
+		return r, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("getting release %q: %w", tag, err)
+	}
+
+	// NOTE: Draft releases are not returned by tag, so they are looked up in
+	// the list of releases.
+	r, err = c.findDraftRelease(ctx, tag)
+	if err != nil || r != nil {
+		return r, err
+	}
+
+	r, _, err = c.client.Repositories.CreateRelease(ctx, c.owner, c.repo, &github.RepositoryRelease{
+		TagName: github.String(tag),
+		Name:    github.String(tag),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating release %q: %w", tag, err)
+	}
+	return r, nil
+}
+
+// UploadAsset uploads the file at path as an asset of the release, named
+// after the file. Nothing is uploaded if the release already has the asset
+// with the same sha256 digest, and ErrAssetConflict is returned if its digest
+// differs. The uploaded asset is downloaded again to verify its digest. It
+// returns whether the asset was uploaded.
+func (c *ReleaseClient) UploadAsset(ctx context.Context, release *github.RepositoryRelease, path string) (bool, error) {
+	name := filepath.Base(path)
+	digest, err := fileDigest(path)
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := c.findAsset(ctx, release.GetID(), name)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		existingDigest, err := c.assetDigest(ctx, existing.GetID())
+		if err != nil {
+			return false, err
+		}
+		if existingDigest != digest {
+			return false, fmt.Errorf("%w: %q has sha256 %s, want %s", ErrAssetConflict, name, existingDigest, digest)
+		}
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	asset, _, err := c.client.Repositories.UploadReleaseAsset(ctx, c.owner, c.repo, release.GetID(),
+		&github.UploadOptions{Name: name, MediaType: "application/octet-stream"}, f)
+	if err != nil {
+		return false, fmt.Errorf("uploading %q: %w", name, err)
+	}
+
+	uploadedDigest, err := c.assetDigest(ctx, asset.GetID())
+	if err != nil {
+		return true, err
+	}
+	if uploadedDigest != digest {
+		// Remove the corrupted asset so that the upload can be retried.
+		if _, err := c.client.Repositories.DeleteReleaseAsset(ctx, c.owner, c.repo, asset.GetID()); err != nil {
+			return true, fmt.Errorf("deleting %q: %w", name, err)
+		}
+		return true, fmt.Errorf("%w: %q has sha256 %s, want %s", ErrAssetDigestMismatch, name, uploadedDigest, digest)
+	}
+	return true, nil
+}
+
+// findDraftRelease returns the draft release for the tag, or nil.
+func (c *ReleaseClient) findDraftRelease(ctx context.Context, tag string) (*github.RepositoryRelease, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, resp, err := c.client.Repositories.ListReleases(ctx, c.owner, c.repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing releases: %w", err)
+		}
+		for _, r := range releases {
+			if r.GetDraft() && r.GetTagName() == tag {
+				return r, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// findAsset returns the asset of the release with the name, or nil.
+func (c *ReleaseClient) findAsset(ctx context.Context, releaseID int64, name string) (*github.ReleaseAsset, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		assets, resp, err := c.client.Repositories.ListReleaseAssets(ctx, c.owner, c.repo, releaseID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("listing release assets: %w", err)
+		}
+		for _, a := range assets {
+			if a.GetName() == name {
+				return a, nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// assetDigest downloads the release asset and returns its hex encoded sha256
+// digest.
+func (c *ReleaseClient) assetDigest(ctx context.Context, id int64) (string, error) {
+	// NOTE: Redirects to the asset storage are followed without the token of
+	// the client, which presigned storage URLs may reject.
+	rc, _, err := c.client.Repositories.DownloadReleaseAsset(ctx, c.owner, c.repo, id, http.DefaultClient)
+	if err != nil {
+		return "", fmt.Errorf("downloading release asset: %w", err)
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, rc); err != nil {
+		return "", fmt.Errorf("downloading release asset: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileDigest returns the hex encoded sha256 digest of the file at path.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
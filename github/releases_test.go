@@ -0,0 +1,149 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReleaseClient_FindOrCreateRelease(t *testing.T) {
+	ctx := context.Background()
+	s := NewTestReleasesServer(t, "owner/repo")
+	id := s.AddRelease("v1.0.0")
+
+	c, err := NewReleaseClient(s.GithubClient(), "owner/repo")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	r, err := c.FindOrCreateRelease(ctx, "v1.0.0")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := id, r.GetID(); want != got {
+		t.Errorf("unexpected release, want: %d, got: %d", want, got)
+	}
+
+	r, err = c.FindOrCreateRelease(ctx, "v2.0.0")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := "v2.0.0", r.GetTagName(); want != got {
+		t.Errorf("unexpected tag, want: %q, got: %q", want, got)
+	}
+	if s.Assets("v2.0.0") == nil {
+		t.Errorf("release was not created")
+	}
+
+	// Draft releases are found instead of creating another release.
+	draftID := s.AddDraftRelease("v3.0.0")
+	r, err = c.FindOrCreateRelease(ctx, "v3.0.0")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := draftID, r.GetID(); want != got {
+		t.Errorf("unexpected draft release, want: %d, got: %d", want, got)
+	}
+}
+
+func TestReleaseClient_UploadAsset(t *testing.T) {
+	content := []byte(`{"payloadType": "application/vnd.in-toto+json"}`)
+
+	testCases := []struct {
+		name     string
+		existing []byte
+		corrupt  bool
+		uploaded bool
+		assets   map[string][]byte
+		err      error
+	}{
+		{
+			name:     "new asset",
+			uploaded: true,
+			assets:   map[string][]byte{"artifact.intoto.jsonl": content},
+		},
+		{
+			name:     "identical asset",
+			existing: content,
+			assets:   map[string][]byte{"artifact.intoto.jsonl": content},
+		},
+		{
+			name:     "different asset",
+			existing: []byte("other"),
+			assets:   map[string][]byte{"artifact.intoto.jsonl": []byte("other")},
+			err:      ErrAssetConflict,
+		},
+		{
+			name:     "corrupted upload",
+			corrupt:  true,
+			uploaded: true,
+			assets:   map[string][]byte{},
+			err:      ErrAssetDigestMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := NewTestReleasesServer(t, "owner/repo")
+			s.CorruptUploads = tc.corrupt
+			s.AddRelease("v1.0.0")
+			if tc.existing != nil {
+				s.AddAsset("v1.0.0", "artifact.intoto.jsonl", tc.existing)
+			}
+
+			path := filepath.Join(t.TempDir(), "artifact.intoto.jsonl")
+			if err := os.WriteFile(path, content, 0o600); err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			c, err := NewReleaseClient(s.GithubClient(), "owner/repo")
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			r, err := c.FindOrCreateRelease(ctx, "v1.0.0")
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			uploaded, err := c.UploadAsset(ctx, r, path)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if want, got := tc.uploaded, uploaded; want != got {
+				t.Errorf("unexpected upload, want: %v, got: %v", want, got)
+			}
+			if diff := cmp.Diff(tc.assets, s.Assets("v1.0.0")); diff != "" {
+				t.Errorf("unexpected assets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewReleaseClient(t *testing.T) {
+	if _, err := NewReleaseClient(nil, "owner/repo"); !errors.Is(err, ErrNoClient) {
+		t.Errorf("unexpected error, want: %v, got: %v", ErrNoClient, err)
+	}
+	s := NewTestReleasesServer(t, "owner/repo")
+	if _, err := NewReleaseClient(s.GithubClient(), "repo"); !errors.Is(err, ErrInvalidRepository) {
+		t.Errorf("unexpected error, want: %v, got: %v", ErrInvalidRepository, err)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package github
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// testRelease is a release of the fake releases API.
+type testRelease struct {
+	release *github.RepositoryRelease
+	assets  []*github.ReleaseAsset
+}
+
+// TestReleasesServer is a fake of the GitHub releases API for a single
+// repository.
+type TestReleasesServer struct {
+	*httptest.Server
+
+	// CorruptUploads makes the server store uploaded assets with different
+	// content.
+	CorruptUploads bool
+
+	mu       sync.Mutex
+	nextID   int64
+	releases map[string]*testRelease
+	content  map[int64][]byte
+	uploads  int
+}
+
+// NewTestReleasesServer returns a started fake of the GitHub releases API of
+// the repository, given as "owner/name". The server is closed when the test
+// finishes.
+func NewTestReleasesServer(t *testing.T, repository string) *TestReleasesServer {
+	s := &TestReleasesServer{
+		releases: map[string]*testRelease{},
+		content:  map[int64][]byte{},
+	}
+
+	prefix := "/repos/" + repository
+	mux := http.NewServeMux()
+	// NOTE: The paths of release tags and assets overlap with the paths of
+	// releases, so they are dispatched by get.
+	mux.HandleFunc("GET "+prefix+"/releases/{a}/{b}", s.get)
+	mux.HandleFunc("GET "+prefix+"/releases", s.listReleases)
+	mux.HandleFunc("POST "+prefix+"/releases", s.createRelease)
+	mux.HandleFunc("POST /uploads"+prefix+"/releases/{id}/assets", s.uploadAsset)
+	mux.HandleFunc("DELETE "+prefix+"/releases/assets/{id}", s.deleteAsset)
+	mux.HandleFunc("GET /storage/{id}", s.downloadStorage)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// GithubClient returns a GitHub API client for the fake API, authenticated
+// with a token.
+func (s *TestReleasesServer) GithubClient() *github.Client {
+	c := github.NewClient(s.Client()).WithAuthToken("test-token")
+	c.BaseURL, _ = url.Parse(s.URL + "/")
+	c.UploadURL, _ = url.Parse(s.URL + "/uploads/")
+	return c
+}
+
+// AddRelease adds a release for the tag and returns its ID.
+func (s *TestReleasesServer) AddRelease(tag string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addRelease(tag).release.GetID()
+}
+
+// AddDraftRelease adds a draft release for the tag and returns its ID.
+func (s *TestReleasesServer) AddDraftRelease(tag string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.addRelease(tag)
+	r.release.Draft = github.Bool(true)
+	return r.release.GetID()
+}
+
+// AddAsset adds an asset with the name and content to the release of the tag.
+func (s *TestReleasesServer) AddAsset(tag, name string, content []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.releases[tag]
+	if r == nil {
+		r = s.addRelease(tag)
+	}
+	s.addAsset(r, name, content)
+}
+
+// Assets returns the content of the assets of the release of the tag by
+// name.
+func (s *TestReleasesServer) Assets(tag string) map[string][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.releases[tag]
+	if r == nil {
+		return nil
+	}
+	assets := map[string][]byte{}
+	for _, a := range r.assets {
+		assets[a.GetName()] = s.content[a.GetID()]
+	}
+	return assets
+}
+
+// Uploads returns the number of uploaded assets.
+func (s *TestReleasesServer) Uploads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.uploads
+}
+
+func (s *TestReleasesServer) addRelease(tag string) *testRelease {
+	s.nextID++
+	r := &testRelease{release: &github.RepositoryRelease{
+		ID:      github.Int64(s.nextID),
+		TagName: github.String(tag),
+		Name:    github.String(tag),
+	}}
+	s.releases[tag] = r
+	return r
+}
+
+func (s *TestReleasesServer) addAsset(r *testRelease, name string, content []byte) *github.ReleaseAsset {
+	s.nextID++
+	a := &github.ReleaseAsset{ID: github.Int64(s.nextID), Name: github.String(name)}
+	r.assets = append(r.assets, a)
+	s.content[a.GetID()] = content
+	return a
+}
+
+func (s *TestReleasesServer) releaseByID(id string) *testRelease {
+	for _, r := range s.releases {
+		if strconv.FormatInt(r.release.GetID(), 10) == id {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *TestReleasesServer) get(w http.ResponseWriter, r *http.Request) {
+	switch a, b := r.PathValue("a"), r.PathValue("b"); {
+	case a == "tags":
+		s.getRelease(w, b)
+	case a == "assets":
+		s.downloadAsset(w, b)
+	case b == "assets":
+		s.listAssets(w, a)
+	default:
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+	}
+}
+
+func (s *TestReleasesServer) getRelease(w http.ResponseWriter, tag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rel := s.releases[tag]
+	// Draft releases are not returned by tag, as in the GitHub API.
+	if rel == nil || rel.release.GetDraft() {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, rel.release)
+}
+
+func (s *TestReleasesServer) listReleases(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	releases := []*github.RepositoryRelease{}
+	for _, r := range s.releases {
+		releases = append(releases, r.release)
+	}
+	writeJSON(w, http.StatusOK, releases)
+}
+
+func (s *TestReleasesServer) createRelease(w http.ResponseWriter, r *http.Request) {
+	var req github.RepositoryRelease
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.GetTagName() == "" {
+		http.Error(w, `{"message": "invalid release"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.releases[req.GetTagName()] != nil {
+		http.Error(w, `{"message": "already_exists"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.addRelease(req.GetTagName()).release)
+}
+
+func (s *TestReleasesServer) listAssets(w http.ResponseWriter, releaseID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rel := s.releaseByID(releaseID)
+	if rel == nil {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, rel.assets)
+}
+
+func (s *TestReleasesServer) uploadAsset(w http.ResponseWriter, r *http.Request) {
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.CorruptUploads {
+		content = append(content, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rel := s.releaseByID(r.PathValue("id"))
+	if rel == nil {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	name := r.URL.Query().Get("name")
+	for _, a := range rel.assets {
+		if a.GetName() == name {
+			http.Error(w, `{"message": "already_exists"}`, http.StatusUnprocessableEntity)
+			return
+		}
+	}
+	s.uploads++
+	writeJSON(w, http.StatusCreated, s.addAsset(rel, name, content))
+}
+
+func (s *TestReleasesServer) downloadAsset(w http.ResponseWriter, assetID string) {
+	// Assets are downloaded from the storage, as in the GitHub API.
+	w.Header().Set("Location", s.URL+"/storage/"+assetID)
+	w.WriteHeader(http.StatusFound)
+}
+
+func (s *TestReleasesServer) downloadStorage(w http.ResponseWriter, r *http.Request) {
+	// Presigned storage URLs reject requests with another authorization.
+	if r.Header.Get("Authorization") != "" {
+		http.Error(w, "unexpected authorization", http.StatusBadRequest)
+		return
+	}
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.content[id]
+	if !ok {
+		http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, _ = w.Write(content)
+}
+
+func (s *TestReleasesServer) deleteAsset(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseInt(r.PathValue("id"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rel := range s.releases {
+		for i, a := range rel.assets {
+			if a.GetID() == id {
+				rel.assets = append(rel.assets[:i], rel.assets[i+1:]...)
+				delete(s.content, id)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+	}
+	http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+}
+
+// writeJSON writes the JSON encoded value as the response of the fake API.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -48,22 +48,24 @@ func GetVarsContext() (VarsContext, error) {
 //
 // See: https://docs.github.com/en/actions/learn-github-actions/contexts#github-context.
 type WorkflowContext struct {
-	Repository      string                 `json:"repository"`
-	RepositoryOwner string                 `json:"repository_owner"`
-	ActionPath      string                 `json:"action_path"`
-	Workflow        string                 `json:"workflow"`
-	EventName       string                 `json:"event_name"`
-	Event           map[string]interface{} `json:"event"`
-	SHA             string                 `json:"sha"`
-	RefType         string                 `json:"ref_type"`
-	Ref             string                 `json:"ref"`
-	BaseRef         string                 `json:"base_ref"`
-	HeadRef         string                 `json:"head_ref"`
-	Actor           string                 `json:"actor"`
-	RunNumber       string                 `json:"run_number"`
-	ServerURL       string                 `json:"server_url"`
-	RunID           string                 `json:"run_id"`
-	RunAttempt      string                 `json:"run_attempt"`
+	Repository      string       `json:"repository"`
+	RepositoryOwner string       `json:"repository_owner"`
+	ActionPath      string       `json:"action_path"`
+	Workflow        string       `json:"workflow"`
+	WorkflowRef     string       `json:"workflow_ref"`
+	JobWorkflowSHA  string       `json:"job_workflow_sha"`
+	EventName       string       `json:"event_name"`
+	Event           EventPayload `json:"event"`
+	SHA             string       `json:"sha"`
+	RefType         string       `json:"ref_type"`
+	Ref             string       `json:"ref"`
+	BaseRef         string       `json:"base_ref"`
+	HeadRef         string       `json:"head_ref"`
+	Actor           string       `json:"actor"`
+	RunNumber       string       `json:"run_number"`
+	ServerURL       string       `json:"server_url"`
+	RunID           string       `json:"run_id"`
+	RunAttempt      string       `json:"run_attempt"`
 }
 
 // RepositoryURI returns a full repository URI for the repo that triggered the workflow.
@@ -95,6 +97,33 @@ func GetWorkflowContext() (WorkflowContext, error) {
 	return w, err
 }
 
+// Runner environments reported in the `runner_environment` claim of OIDC
+// tokens.
+const (
+	RunnerEnvironmentGithubHosted = "github-hosted"
+	RunnerEnvironmentSelfHosted   = "self-hosted"
+)
+
+// RunnerContext is the `runner` context that contains information about the
+// runner executing the job.
+//
+// See: https://docs.github.com/en/actions/learn-github-actions/contexts#runner-context
+type RunnerContext struct {
+	Name string `json:"name"`
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+}
+
+// GetRunnerContext returns the current GitHub Actions 'runner' context from
+// the default environment variables.
+func GetRunnerContext() RunnerContext {
+	return RunnerContext{
+		Name: os.Getenv("RUNNER_NAME"),
+		OS:   os.Getenv("RUNNER_OS"),
+		Arch: os.Getenv("RUNNER_ARCH"),
+	}
+}
+
 // GetToken gets the Github Actions token.
 // See: https://docs.github.com/en/actions/security-guides/automatic-token-authentication
 func GetToken() (string, error) {
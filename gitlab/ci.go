@@ -0,0 +1,107 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gitlab provides access to the environment of GitLab CI/CD jobs and
+// to GitLab ID tokens.
+package gitlab
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotGitlabCI indicates that the process is not running in a GitLab CI/CD
+// job.
+var ErrNotGitlabCI = errors.New("not running in GitLab CI")
+
+// CIContext contains the predefined CI/CD variables of a GitLab CI/CD job.
+//
+// See: https://docs.gitlab.com/ee/ci/variables/predefined_variables.html
+type CIContext struct {
+	ServerURL        string `json:"server_url"`
+	ProjectID        string `json:"project_id"`
+	ProjectPath      string `json:"project_path"`
+	ProjectNamespace string `json:"project_namespace"`
+	ConfigPath       string `json:"config_path"`
+	CommitSHA        string `json:"commit_sha"`
+	CommitRefName    string `json:"commit_ref_name"`
+	CommitTag        string `json:"commit_tag"`
+	PipelineID       string `json:"pipeline_id"`
+	PipelineSource   string `json:"pipeline_source"`
+	JobID            string `json:"job_id"`
+	JobName          string `json:"job_name"`
+	JobStartedAt     string `json:"job_started_at"`
+	UserLogin        string `json:"user_login"`
+}
+
+// IsGitlabCI returns whether the process is running in a GitLab CI/CD job.
+func IsGitlabCI() bool {
+	return os.Getenv("GITLAB_CI") == "true"
+}
+
+// GetCIContext returns the context of the current GitLab CI/CD job from the
+// predefined CI/CD variables.
+func GetCIContext() (CIContext, error) {
+	if !IsGitlabCI() {
+		return CIContext{}, fmt.Errorf("%w: GITLAB_CI environment variable not set", ErrNotGitlabCI)
+	}
+	return CIContext{
+		ServerURL:        os.Getenv("CI_SERVER_URL"),
+		ProjectID:        os.Getenv("CI_PROJECT_ID"),
+		ProjectPath:      os.Getenv("CI_PROJECT_PATH"),
+		ProjectNamespace: os.Getenv("CI_PROJECT_NAMESPACE"),
+		ConfigPath:       os.Getenv("CI_CONFIG_PATH"),
+		CommitSHA:        os.Getenv("CI_COMMIT_SHA"),
+		CommitRefName:    os.Getenv("CI_COMMIT_REF_NAME"),
+		CommitTag:        os.Getenv("CI_COMMIT_TAG"),
+		PipelineID:       os.Getenv("CI_PIPELINE_ID"),
+		PipelineSource:   os.Getenv("CI_PIPELINE_SOURCE"),
+		JobID:            os.Getenv("CI_JOB_ID"),
+		JobName:          os.Getenv("CI_JOB_NAME"),
+		JobStartedAt:     os.Getenv("CI_JOB_STARTED_AT"),
+		UserLogin:        os.Getenv("GITLAB_USER_LOGIN"),
+	}, nil
+}
+
+// Ref returns the fully qualified git ref the pipeline runs for.
+func (c *CIContext) Ref() string {
+	switch {
+	case c.CommitTag != "":
+		return "refs/tags/" + c.CommitTag
+	case c.CommitRefName != "":
+		return "refs/heads/" + c.CommitRefName
+	default:
+		return ""
+	}
+}
+
+// RepositoryURI returns a full repository URI for the project the pipeline
+// runs for.
+func (c *CIContext) RepositoryURI() string {
+	if c.ServerURL == "" || c.ProjectPath == "" {
+		return ""
+	}
+	var ref string
+	if r := c.Ref(); r != "" {
+		ref = "@" + r
+	}
+	return fmt.Sprintf(
+		"git+%s/%s%s",
+		strings.TrimSuffix(c.ServerURL, "/"),
+		c.ProjectPath,
+		ref,
+	)
+}
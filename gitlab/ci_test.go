@@ -0,0 +1,106 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGetCIContext(t *testing.T) {
+	t.Run("not gitlab", func(t *testing.T) {
+		t.Setenv("GITLAB_CI", "")
+		if _, err := GetCIContext(); !errors.Is(err, ErrNotGitlabCI) {
+			t.Errorf("unexpected error, want: %v, got: %v", ErrNotGitlabCI, err)
+		}
+	})
+
+	t.Run("gitlab", func(t *testing.T) {
+		t.Setenv("GITLAB_CI", "true")
+		t.Setenv("CI_SERVER_URL", "https://gitlab.com")
+		t.Setenv("CI_PROJECT_PATH", "group/project")
+		t.Setenv("CI_COMMIT_SHA", "abcde")
+		t.Setenv("CI_COMMIT_REF_NAME", "v1.0.0")
+		t.Setenv("CI_COMMIT_TAG", "v1.0.0")
+		t.Setenv("CI_PIPELINE_ID", "574")
+
+		c, err := GetCIContext()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := CIContext{
+			ServerURL:     "https://gitlab.com",
+			ProjectPath:   "group/project",
+			CommitSHA:     "abcde",
+			CommitRefName: "v1.0.0",
+			CommitTag:     "v1.0.0",
+			PipelineID:    "574",
+		}
+		if diff := cmp.Diff(expected, c); diff != "" {
+			t.Errorf("unexpected context (-want +got):\n%s", diff)
+		}
+	})
+}
+
+func TestCIContext_RepositoryURI(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ctx      CIContext
+		expected string
+	}{
+		{
+			name: "branch",
+			ctx: CIContext{
+				ServerURL:     "https://gitlab.com/",
+				ProjectPath:   "group/project",
+				CommitRefName: "main",
+			},
+			expected: "git+https://gitlab.com/group/project@refs/heads/main",
+		},
+		{
+			name: "tag",
+			ctx: CIContext{
+				ServerURL:     "https://gitlab.com",
+				ProjectPath:   "group/project",
+				CommitRefName: "v1.0.0",
+				CommitTag:     "v1.0.0",
+			},
+			expected: "git+https://gitlab.com/group/project@refs/tags/v1.0.0",
+		},
+		{
+			name: "no ref",
+			ctx: CIContext{
+				ServerURL:   "https://gitlab.com",
+				ProjectPath: "group/project",
+			},
+			expected: "git+https://gitlab.com/group/project",
+		},
+		{
+			name:     "no project",
+			ctx:      CIContext{ServerURL: "https://gitlab.com"},
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.ctx.RepositoryURI(); got != tc.expected {
+				t.Errorf("unexpected URI, want: %q, got: %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+
+	"github.com/Kong/slsa-github-generator/github"
+)
+
+const (
+	// IDTokenEnvKey is the variable the ID token is expected in. It must be
+	// declared with the `id_tokens` keyword of the job.
+	//
+	// See: https://docs.gitlab.com/ee/ci/yaml/#id_tokens
+	IDTokenEnvKey = "SLSA_ID_TOKEN"
+
+	serverURLEnvKey = "CI_SERVER_URL"
+)
+
+// Runner environments reported in the `runner_environment` claim of ID
+// tokens.
+const (
+	RunnerEnvironmentGitlabHosted = "gitlab-hosted"
+	RunnerEnvironmentSelfHosted   = "self-hosted"
+)
+
+var (
+	// ErrNoIDToken indicates that the job has no ID token.
+	ErrNoIDToken = errors.New("no ID token")
+
+	// ErrContextMismatch indicates that the claims of the token disagree with
+	// the CI context.
+	ErrContextMismatch = errors.New("token claims do not match CI context")
+
+	// errURLError indicates the issuer URL is invalid.
+	errURLError = errors.New("url")
+
+	// errToken indicates an error in the format of the token.
+	errToken = errors.New("token")
+
+	// errClaims indicates an error in the claims of the token.
+	errClaims = errors.New("claims")
+
+	// errVerify indicates an error in the token verification process.
+	errVerify = errors.New("verify")
+)
+
+// IDToken represents the contents of a GitLab CI/CD ID token.
+//
+// See: https://docs.gitlab.com/ee/ci/secrets/id_token_authentication.html#token-payload
+type IDToken struct {
+	// Issuer is the token issuer, the URL of the GitLab instance.
+	Issuer string
+
+	// NamespaceID is the unique ID of the group or user owning the project.
+	NamespaceID string `json:"namespace_id"`
+
+	// NamespacePath is the path of the group or user owning the project.
+	NamespacePath string `json:"namespace_path"`
+
+	// ProjectID is the unique ID of the project.
+	ProjectID string `json:"project_id"`
+
+	// ProjectPath is the path of the project.
+	ProjectPath string `json:"project_path"`
+
+	// UserID is the unique ID of the user who started the job.
+	UserID string `json:"user_id"`
+
+	// UserLogin is the username of the user who started the job.
+	UserLogin string `json:"user_login"`
+
+	// PipelineID is the ID of the pipeline.
+	PipelineID string `json:"pipeline_id"`
+
+	// PipelineSource is how the pipeline was triggered.
+	PipelineSource string `json:"pipeline_source"`
+
+	// JobID is the ID of the job.
+	JobID string `json:"job_id"`
+
+	// Ref is the git ref name of the job, without the refs/ prefix.
+	Ref string `json:"ref"`
+
+	// RefType is the type of Ref, either "branch" or "tag".
+	RefType string `json:"ref_type"`
+
+	// RefProtected is "true" if the ref is protected.
+	RefProtected string `json:"ref_protected"`
+
+	// SHA is the commit SHA of the job.
+	SHA string `json:"sha"`
+
+	// CIConfigRefURI is a reference to the top-level pipeline definition,
+	// e.g. gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main.
+	CIConfigRefURI string `json:"ci_config_ref_uri"`
+
+	// CIConfigSHA is the commit SHA of the pipeline definition.
+	CIConfigSHA string `json:"ci_config_sha"`
+
+	// RunnerID is the ID of the runner executing the job.
+	RunnerID int64 `json:"runner_id"`
+
+	// RunnerEnvironment is the environment of the runner executing the job,
+	// either "gitlab-hosted" or "self-hosted".
+	RunnerEnvironment string `json:"runner_environment"`
+
+	// Expiry is the expiration date of the token.
+	Expiry time.Time
+
+	// Audience is the audience the token was issued for.
+	Audience []string
+}
+
+// IDTokenClient verifies the ID token of a GitLab CI/CD job. Unlike GitHub's
+// OIDC tokens, ID tokens are not requested on demand; GitLab issues them when
+// the job starts for the audience configured in the pipeline definition.
+type IDTokenClient struct {
+	// raw is the raw ID token.
+	raw string
+
+	// verifierFunc is a factory to generate an oidc.IDTokenVerifier for
+	// token verification. This is used for tests.
+	verifierFunc func(context.Context) (*oidc.IDTokenVerifier, error)
+
+	// httpClient is used to request the issuer's keys.
+	httpClient *http.Client
+
+	// mu protects verifier.
+	mu sync.Mutex
+
+	// verifier is the verifier shared by all verifications.
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewIDTokenClient returns a client for the ID token of the current job. The
+// token is read from the SLSA_ID_TOKEN variable and verified against the
+// GitLab instance given by CI_SERVER_URL.
+func NewIDTokenClient() (*IDTokenClient, error) {
+	serverURL := os.Getenv(serverURLEnvKey)
+	if _, err := url.ParseRequestURI(serverURL); err != nil {
+		return nil, fmt.Errorf("%w: invalid server URL %q: %w", errURLError, serverURL, err)
+	}
+
+	raw := os.Getenv(IDTokenEnvKey)
+	if raw == "" {
+		return nil, fmt.Errorf(
+			"%w: %s variable not set; does your job declare it with `id_tokens`?",
+			ErrNoIDToken, IDTokenEnvKey,
+		)
+	}
+
+	c := IDTokenClient{
+		raw:        raw,
+		httpClient: github.NewHTTPClient(),
+	}
+	c.verifierFunc = func(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+		// NOTE: The client is also used by the key set to fetch the keys.
+		ctx = oidc.ClientContext(ctx, c.httpClient)
+		provider, err := oidc.NewProvider(ctx, strings.TrimSuffix(serverURL, "/"))
+		if err != nil {
+			return nil, err
+		}
+		return provider.Verifier(&oidc.Config{
+			// NOTE: The audience is checked by Token.
+			SkipClientIDCheck: true,
+		}), nil
+	}
+	return &c, nil
+}
+
+// getVerifier returns the shared token verifier, creating it on first use.
+func (c *IDTokenClient) getVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.verifier == nil {
+		verifier, err := c.verifierFunc(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.verifier = verifier
+	}
+	return c.verifier, nil
+}
+
+// Token verifies the job's ID token and returns its contents. The token must
+// have been issued for all of the given audiences.
+func (c *IDTokenClient) Token(ctx context.Context, audience []string) (*IDToken, error) {
+	verifier, err := c.getVerifier(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: creating verifier: %w", errVerify, err)
+	}
+
+	t, err := verifier.Verify(ctx, c.raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not verify token: %w", errVerify, err)
+	}
+
+	for _, a := range audience {
+		if !slices.Contains(t.Audience, a) {
+			return nil, fmt.Errorf("%w: audience %q not in %q", errVerify, a, t.Audience)
+		}
+	}
+
+	token := IDToken{
+		Issuer:   t.Issuer,
+		Audience: t.Audience,
+		Expiry:   t.Expiry,
+	}
+	if err := t.Claims(&token); err != nil {
+		return nil, fmt.Errorf("%w: getting claims: %w", errToken, err)
+	}
+
+	if err := verifyClaims(&token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// verifyClaims verifies the claims we expect to populate the provenance.
+func verifyClaims(token *IDToken) error {
+	if token.ProjectID == "" {
+		return fmt.Errorf("%w: project ID is empty", errClaims)
+	}
+	if token.NamespaceID == "" {
+		return fmt.Errorf("%w: namespace ID is empty", errClaims)
+	}
+	if token.PipelineID == "" {
+		return fmt.Errorf("%w: pipeline ID is empty", errClaims)
+	}
+	if token.CIConfigRefURI == "" {
+		return fmt.Errorf("%w: CI config ref URI is empty", errClaims)
+	}
+	return nil
+}
+
+// CheckCIContext checks that the claims of the token agree with the CI
+// context, which is not signed. Claims that are not set in the token are not
+// checked.
+func (t *IDToken) CheckCIContext(c *CIContext) error {
+	claims := []struct {
+		name           string
+		token, context string
+	}{
+		{"project_id", t.ProjectID, c.ProjectID},
+		{"project_path", t.ProjectPath, c.ProjectPath},
+		{"sha", t.SHA, c.CommitSHA},
+		{"ref", t.Ref, c.CommitRefName},
+		{"pipeline_id", t.PipelineID, c.PipelineID},
+		{"pipeline_source", t.PipelineSource, c.PipelineSource},
+		{"job_id", t.JobID, c.JobID},
+	}
+
+	var mismatches []string
+	for _, claim := range claims {
+		if claim.token != "" && claim.token != claim.context {
+			mismatches = append(mismatches,
+				fmt.Sprintf("%s: token %q, context %q", claim.name, claim.token, claim.context))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%w: %s", ErrContextMismatch, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
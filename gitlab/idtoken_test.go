@@ -0,0 +1,226 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestNewIDTokenClient(t *testing.T) {
+	testCases := []struct {
+		name      string
+		serverURL string
+		token     string
+		err       error
+	}{
+		{
+			name:      "valid",
+			serverURL: "https://gitlab.com",
+			token:     "a.b.c",
+		},
+		{
+			name:  "no server URL",
+			token: "a.b.c",
+			err:   errURLError,
+		},
+		{
+			name:      "no token",
+			serverURL: "https://gitlab.com",
+			err:       ErrNoIDToken,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(serverURLEnvKey, tc.serverURL)
+			t.Setenv(IDTokenEnvKey, tc.token)
+
+			_, err := NewIDTokenClient()
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestIDTokenClient_Token(t *testing.T) {
+	now := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+
+	token := &IDToken{
+		NamespaceID:       "72",
+		NamespacePath:     "group",
+		ProjectID:         "20",
+		ProjectPath:       "group/project",
+		UserID:            "1",
+		UserLogin:         "user",
+		PipelineID:        "574",
+		PipelineSource:    "push",
+		JobID:             "302",
+		Ref:               "main",
+		RefType:           "branch",
+		RefProtected:      "true",
+		SHA:               "abcde",
+		CIConfigRefURI:    "gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main",
+		CIConfigSHA:       "abcde",
+		RunnerID:          1,
+		RunnerEnvironment: RunnerEnvironmentGitlabHosted,
+		Audience:          []string{"hoge"},
+		Expiry:            now.Add(5 * time.Minute),
+	}
+
+	testCases := []struct {
+		name     string
+		token    *IDToken
+		audience []string
+		now      time.Time
+		err      error
+	}{
+		{
+			name:     "valid",
+			token:    token,
+			audience: []string{"hoge"},
+			now:      now,
+		},
+		{
+			name:     "wrong audience",
+			token:    token,
+			audience: []string{"fuga"},
+			now:      now,
+			err:      errVerify,
+		},
+		{
+			name:     "expired",
+			token:    token,
+			audience: []string{"hoge"},
+			now:      now.Add(time.Hour),
+			err:      errVerify,
+		},
+		{
+			name: "wrong issuer",
+			token: &IDToken{
+				Issuer:         "https://gitlab.example.com",
+				ProjectID:      "20",
+				NamespaceID:    "72",
+				PipelineID:     "574",
+				CIConfigRefURI: "gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main",
+				Audience:       []string{"hoge"},
+				Expiry:         now.Add(5 * time.Minute),
+			},
+			audience: []string{"hoge"},
+			now:      now,
+			err:      errVerify,
+		},
+		{
+			name: "missing claims",
+			token: &IDToken{
+				ProjectID: "20",
+				Audience:  []string{"hoge"},
+				Expiry:    now.Add(5 * time.Minute),
+			},
+			audience: []string{"hoge"},
+			now:      now,
+			err:      errClaims,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, c := NewTestIDTokenServer(t, tc.now, tc.token)
+			defer s.Close()
+
+			got, err := c.Token(context.Background(), tc.audience)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if want, got := s.URL, got.Issuer; want != got {
+				t.Errorf("unexpected issuer, want: %q, got: %q", want, got)
+			}
+			if diff := cmp.Diff(tc.token, got,
+				cmpopts.IgnoreFields(IDToken{}, "Issuer"), cmpopts.EquateApproxTime(time.Second)); diff != "" {
+				t.Errorf("unexpected token (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestIDToken_CheckCIContext(t *testing.T) {
+	token := &IDToken{
+		ProjectID:      "20",
+		ProjectPath:    "group/project",
+		SHA:            "abcde",
+		Ref:            "main",
+		PipelineID:     "574",
+		PipelineSource: "push",
+		JobID:          "302",
+	}
+	ctx := CIContext{
+		ProjectID:      "20",
+		ProjectPath:    "group/project",
+		CommitSHA:      "abcde",
+		CommitRefName:  "main",
+		PipelineID:     "574",
+		PipelineSource: "push",
+		JobID:          "302",
+	}
+
+	testCases := []struct {
+		name  string
+		token *IDToken
+		ctx   func(c CIContext) CIContext
+		err   error
+	}{
+		{
+			name:  "match",
+			token: token,
+			ctx:   func(c CIContext) CIContext { return c },
+		},
+		{
+			name:  "unset claims",
+			token: &IDToken{ProjectID: "20"},
+			ctx:   func(c CIContext) CIContext { c.CommitSHA = "fghij"; return c },
+		},
+		{
+			name:  "sha mismatch",
+			token: token,
+			ctx:   func(c CIContext) CIContext { c.CommitSHA = "fghij"; return c },
+			err:   ErrContextMismatch,
+		},
+		{
+			name:  "job mismatch",
+			token: token,
+			ctx:   func(c CIContext) CIContext { c.JobID = "303"; return c },
+			err:   ErrContextMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := tc.ctx(ctx)
+			if err := tc.token.CheckCIContext(&c); !errors.Is(err, tc.err) {
+				t.Errorf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
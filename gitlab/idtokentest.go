@@ -0,0 +1,141 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gitlab
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"gopkg.in/square/go-jose.v2"
+)
+
+type jsonIDToken struct {
+	Issuer            string   `json:"iss"`
+	NamespaceID       string   `json:"namespace_id,omitempty"`
+	NamespacePath     string   `json:"namespace_path,omitempty"`
+	ProjectID         string   `json:"project_id,omitempty"`
+	ProjectPath       string   `json:"project_path,omitempty"`
+	UserID            string   `json:"user_id,omitempty"`
+	UserLogin         string   `json:"user_login,omitempty"`
+	PipelineID        string   `json:"pipeline_id,omitempty"`
+	PipelineSource    string   `json:"pipeline_source,omitempty"`
+	JobID             string   `json:"job_id,omitempty"`
+	Ref               string   `json:"ref,omitempty"`
+	RefType           string   `json:"ref_type,omitempty"`
+	RefProtected      string   `json:"ref_protected,omitempty"`
+	SHA               string   `json:"sha,omitempty"`
+	CIConfigRefURI    string   `json:"ci_config_ref_uri,omitempty"`
+	CIConfigSHA       string   `json:"ci_config_sha,omitempty"`
+	RunnerID          int64    `json:"runner_id,omitempty"`
+	RunnerEnvironment string   `json:"runner_environment,omitempty"`
+	Audience          []string `json:"aud"`
+	Expiry            int64    `json:"exp"`
+}
+
+// NewTestIDTokenServer returns a httptest.Server that serves the OIDC
+// discovery document and signing keys of a fake GitLab instance, and an
+// IDTokenClient for a job ID token issued by it with the given claims. Now is
+// the time used for token expiration verification by the client.
+func NewTestIDTokenServer(t *testing.T, now time.Time, token *IDToken) (*httptest.Server, *IDTokenClient) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := jose.JSONWebKey{Key: privateKey, KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var issuerURL string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": %q, "jwks_uri": %q, "id_token_signing_alg_values_supported": ["RS256"]}`,
+				issuerURL, issuerURL+"/oauth/discovery/keys")
+		case "/oauth/discovery/keys":
+			if err := json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.Public()}}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	issuerURL = s.URL
+
+	// Allow the token to override the issuer for verification testing.
+	issuer := issuerURL
+	if token.Issuer != "" {
+		issuer = token.Issuer
+	}
+	b, err := json.Marshal(jsonIDToken{
+		Issuer:            issuer,
+		NamespaceID:       token.NamespaceID,
+		NamespacePath:     token.NamespacePath,
+		ProjectID:         token.ProjectID,
+		ProjectPath:       token.ProjectPath,
+		UserID:            token.UserID,
+		UserLogin:         token.UserLogin,
+		PipelineID:        token.PipelineID,
+		PipelineSource:    token.PipelineSource,
+		JobID:             token.JobID,
+		Ref:               token.Ref,
+		RefType:           token.RefType,
+		RefProtected:      token.RefProtected,
+		SHA:               token.SHA,
+		CIConfigRefURI:    token.CIConfigRefURI,
+		CIConfigSHA:       token.CIConfigSHA,
+		RunnerID:          token.RunnerID,
+		RunnerEnvironment: token.RunnerEnvironment,
+		Audience:          token.Audience,
+		Expiry:            token.Expiry.Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, err := signer.Sign(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := object.CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := IDTokenClient{
+		raw:        raw,
+		httpClient: s.Client(),
+	}
+	c.verifierFunc = func(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+		provider, err := oidc.NewProvider(oidc.ClientContext(ctx, c.httpClient), s.URL)
+		if err != nil {
+			return nil, err
+		}
+		return provider.Verifier(&oidc.Config{
+			Now:               func() time.Time { return now },
+			SkipClientIDCheck: true,
+		}), nil
+	}
+
+	return s, &c
+}
@@ -0,0 +1,60 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// AttestationIDOutput is the name of the step output holding the ID of the
+// attestation uploaded to GitHub.
+const AttestationIDOutput = "attestation-id"
+
+// ErrUnsignedAttestation indicates that unsigned provenance can't be uploaded
+// to GitHub.
+var ErrUnsignedAttestation = errors.New("unsigned provenance can't be uploaded to GitHub")
+
+// UploadGithubAttestation uploads a Sigstore bundle to the attestations of
+// the repository of the current workflow and returns the attestation ID.
+// Callers report the ID, such as in the AttestationIDOutput step output. A nil
+// provider uses the default clients.
+func UploadGithubAttestation(ctx context.Context, provider slsa.ClientProvider, bundle []byte) (int64, error) {
+	ghContext, err := github.GetWorkflowContext()
+	if err != nil {
+		return 0, err
+	}
+
+	if provider == nil {
+		provider = &slsa.DefaultClientProvider{}
+	}
+	c, err := provider.GithubClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := github.UploadAttestation(ctx, c, ghContext.Repository, bundle)
+	if err != nil {
+		return 0, err
+	}
+	// NOTE: stdout may hold the output of the command, such as JSON.
+	fmt.Fprintf(os.Stderr, "Uploaded attestation to GitHub with ID %d.\n", id)
+	return id, nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// ErrNotGitRepository indicates that a directory is not in a git working tree.
+var ErrNotGitRepository = errors.New("not a git repository")
+
+// ReadGitState returns the state of the git working tree containing dir.
+func ReadGitState(ctx context.Context, dir string) (*slsa.GitState, error) {
+	commit, err := git(ctx, dir, "rev-parse", "--verify", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotGitRepository, err)
+	}
+
+	// The ref is empty if HEAD is detached.
+	ref, _ := git(ctx, dir, "symbolic-ref", "--quiet", "HEAD")
+
+	// The remote URL is empty if there is no origin remote.
+	remote, _ := git(ctx, dir, "config", "--get", "remote.origin.url")
+
+	status, err := git(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+
+	return &slsa.GitState{
+		Commit:    commit,
+		Ref:       ref,
+		RemoteURL: redactURL(remote),
+		Dirty:     status != "",
+	}, nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// redactURL removes credentials from a remote URL so that they are not
+// recorded in the provenance. URLs in the scp-like syntax are returned as is.
+func redactURL(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Scheme == "" {
+		return remote
+	}
+	if u.Scheme == "ssh" {
+		// The user name of ssh URLs is not a credential.
+		u.User = url.User(u.User.Username())
+		return u.String()
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/local"
+)
+
+// SigningKeyPasswordEnvKey is the variable holding the password of an
+// encrypted signing key for local provenance.
+const SigningKeyPasswordEnvKey = "SLSA_SIGNING_KEY_PASSWORD"
+
+// ErrSigningKeyRequired indicates that local provenance is signed without a
+// signing key.
+var ErrSigningKeyRequired = errors.New("--signing-key is required for local builds")
+
+// AddLocalFlags adds the --local and --signing-key flags to the command.
+func AddLocalFlags(c *cobra.Command, localBuild *bool, signingKeyPath *string) {
+	c.Flags().BoolVar(
+		localBuild, "local", false,
+		"Generate provenance for a local build from the git working tree instead of a CI job.",
+	)
+	c.Flags().StringVar(
+		signingKeyPath, "signing-key", "",
+		fmt.Sprintf("Path to a PEM encoded private key to sign local provenance with. Encrypted keys are decrypted with %s.",
+			SigningKeyPasswordEnvKey),
+	)
+}
+
+// LoadLocalSigner returns the signer of local provenance for the PEM encoded
+// private key at path. The signer is nil if no key is given and the mode is
+// unsigned.
+func LoadLocalSigner(path string, m SigningMode) (signing.Signer, error) {
+	if path == "" {
+		if m != SigningModeUnsigned {
+			return nil, ErrSigningKeyRequired
+		}
+		return nil, nil
+	}
+	ks, err := local.LoadKeySigner(path, []byte(os.Getenv(SigningKeyPasswordEnvKey)))
+	if err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
@@ -0,0 +1,34 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// LoadRedactionPolicy reads the redaction policy in the file at path, which
+// must be under the current directory. If path is empty, the default policy
+// is returned.
+func LoadRedactionPolicy(path string) (*slsa.RedactionPolicy, error) {
+	if path == "" {
+		return slsa.DefaultRedactionPolicy, nil
+	}
+	b, err := utils.SafeReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return slsa.ParseRedactionPolicy(b)
+}
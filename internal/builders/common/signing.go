@@ -0,0 +1,145 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// SigningModeEnvKey is the variable holding the default signing mode of the
+// builders. The --signing-mode flag takes precedence over it.
+const SigningModeEnvKey = "SLSA_SIGNING_MODE"
+
+// SigningMode controls whether the builders sign provenance and upload it to
+// the transparency log.
+type SigningMode string
+
+const (
+	// SigningModeSign signs provenance and uploads it to the transparency
+	// log. It is the default.
+	SigningModeSign SigningMode = "sign"
+
+	// SigningModeNoUpload signs provenance but does not upload it to the
+	// transparency log.
+	SigningModeNoUpload SigningMode = "no-upload"
+
+	// SigningModeUnsigned writes the unsigned provenance statement. It is
+	// meant for dry runs, such as pull request checks, that don't have the
+	// permissions to request an OIDC token.
+	SigningModeUnsigned SigningMode = "unsigned"
+)
+
+// ErrInvalidSigningMode indicates an unknown signing mode.
+var ErrInvalidSigningMode = errors.New("invalid signing mode")
+
+// ParseSigningMode parses a signing mode. An empty string is the default mode.
+func ParseSigningMode(s string) (SigningMode, error) {
+	switch m := SigningMode(s); m {
+	case "":
+		return SigningModeSign, nil
+	case SigningModeSign, SigningModeNoUpload, SigningModeUnsigned:
+		return m, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidSigningMode, s)
+	}
+}
+
+// AddSigningModeFlag adds the --signing-mode flag to the command. The flag
+// defaults to the value of SigningModeEnvKey.
+func AddSigningModeFlag(c *cobra.Command, p *string) {
+	c.Flags().StringVar(p, "signing-mode", os.Getenv(SigningModeEnvKey),
+		fmt.Sprintf("Whether to sign provenance and upload it to the transparency log: %q, %q or %q. (default: $%s or %q)",
+			SigningModeSign, SigningModeNoUpload, SigningModeUnsigned, SigningModeEnvKey, SigningModeSign))
+}
+
+// ClientProvider returns the client provider to generate provenance with in
+// this mode, given the caller's provider. Unsigned provenance is generated
+// without the OIDC token and GitHub API. A nil provider means the generator's
+// default clients.
+func (m SigningMode) ClientProvider(p slsa.ClientProvider) slsa.ClientProvider {
+	if p == nil && m == SigningModeUnsigned {
+		return &slsa.NilClientProvider{}
+	}
+	return p
+}
+
+// Attest signs the provenance statement and uploads the attestation to the
+// transparency log, as allowed by the mode. It returns the contents of the
+// provenance file, which is the JSON encoded statement if the mode is
+// unsigned. The log entry is nil if the attestation wasn't uploaded, including
+// when tlog is nil.
+func Attest(ctx context.Context, m SigningMode, p *intoto.ProvenanceStatement,
+	s signing.Signer, tlog signing.TransparencyLog,
+) ([]byte, signing.LogEntry, error) {
+	if m == SigningModeUnsigned {
+		b, err := json.Marshal(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		return b, nil, nil
+	}
+
+	att, err := s.Sign(ctx, &intoto.Statement{
+		StatementHeader: p.StatementHeader,
+		Predicate:       p.Predicate,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return upload(ctx, m, att, tlog)
+}
+
+// AttestPayload is like Attest for a JSON encoded in-toto statement, such as
+// a statement with annotated subjects. The payload is returned as is if the
+// mode is unsigned.
+func AttestPayload(ctx context.Context, m SigningMode, payload []byte,
+	s signing.Signer, tlog signing.TransparencyLog,
+) ([]byte, signing.LogEntry, error) {
+	if m == SigningModeUnsigned {
+		return payload, nil, nil
+	}
+
+	att, err := s.SignPayload(ctx, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return upload(ctx, m, att, tlog)
+}
+
+// upload uploads the attestation to the transparency log, as allowed by the
+// mode, and returns its contents and log entry.
+func upload(ctx context.Context, m SigningMode, att signing.Attestation,
+	tlog signing.TransparencyLog,
+) ([]byte, signing.LogEntry, error) {
+	if m == SigningModeNoUpload || tlog == nil {
+		return att.Bytes(), nil, nil
+	}
+
+	logEntry, err := tlog.Upload(ctx, att)
+	if err != nil {
+		return nil, nil, err
+	}
+	return att.Bytes(), logEntry, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+func TestParseSigningMode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mode     string
+		expected SigningMode
+		err      error
+	}{
+		{
+			name:     "default",
+			expected: SigningModeSign,
+		},
+		{
+			name:     "sign",
+			mode:     "sign",
+			expected: SigningModeSign,
+		},
+		{
+			name:     "no upload",
+			mode:     "no-upload",
+			expected: SigningModeNoUpload,
+		},
+		{
+			name:     "unsigned",
+			mode:     "unsigned",
+			expected: SigningModeUnsigned,
+		},
+		{
+			name: "invalid",
+			mode: "dry-run",
+			err:  ErrInvalidSigningMode,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseSigningMode(tc.mode)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if want, got := tc.expected, m; want != got {
+				t.Errorf("unexpected mode, want: %q, got: %q", want, got)
+			}
+		})
+	}
+}
+
+func TestAddSigningModeFlag(t *testing.T) {
+	t.Setenv(SigningModeEnvKey, "unsigned")
+
+	var mode string
+	c := &cobra.Command{Run: func(*cobra.Command, []string) {}}
+	AddSigningModeFlag(c, &mode)
+	c.SetArgs(nil)
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := "unsigned", mode; want != got {
+		t.Errorf("unexpected default, want: %q, got: %q", want, got)
+	}
+
+	c.SetArgs([]string{"--signing-mode", "no-upload"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := "no-upload", mode; want != got {
+		t.Errorf("unexpected flag value, want: %q, got: %q", want, got)
+	}
+}
+
+func TestSigningMode_ClientProvider(t *testing.T) {
+	provider := &slsa.NilClientProvider{}
+
+	if got := SigningModeSign.ClientProvider(nil); got != nil {
+		t.Errorf("unexpected provider for signed provenance: %#v", got)
+	}
+	if got := SigningModeUnsigned.ClientProvider(provider); got != provider {
+		t.Errorf("unexpected provider, want: %#v, got: %#v", provider, got)
+	}
+	if _, ok := SigningModeUnsigned.ClientProvider(nil).(*slsa.NilClientProvider); !ok {
+		t.Errorf("expected a nil client provider for unsigned provenance")
+	}
+}
+
+func TestAttest(t *testing.T) {
+	p := &intoto.ProvenanceStatement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: slsa02.PredicateSLSAProvenance,
+			Subject: []intoto.Subject{
+				{Name: "artifact", Digest: slsacommon.DigestSet{"sha256": "1234"}},
+			},
+		},
+	}
+	statement, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	signer := &testutil.TestSigner{Att: testutil.TestAttestation{BytesVal: []byte("envelope")}}
+	entry := &testutil.TestLogEntry{UUIDVal: "uuid"}
+
+	testCases := []struct {
+		name     string
+		mode     SigningMode
+		tlog     signing.TransparencyLog
+		expected []byte
+		uploaded bool
+		err      error
+	}{
+		{
+			name:     "sign",
+			mode:     SigningModeSign,
+			tlog:     &testutil.TestTransparencyLog{Entry: entry},
+			expected: []byte("envelope"),
+			uploaded: true,
+		},
+		{
+			name:     "sign without tlog",
+			mode:     SigningModeSign,
+			expected: []byte("envelope"),
+		},
+		{
+			name: "upload error",
+			mode: SigningModeSign,
+			tlog: &testutil.TransparencyLogWithErr{},
+			err:  testutil.ErrTransparencyLog,
+		},
+		{
+			name:     "no upload",
+			mode:     SigningModeNoUpload,
+			tlog:     &testutil.TransparencyLogWithErr{},
+			expected: []byte("envelope"),
+		},
+		{
+			name:     "unsigned",
+			mode:     SigningModeUnsigned,
+			tlog:     &testutil.TransparencyLogWithErr{},
+			expected: statement,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, logEntry, err := Attest(context.Background(), tc.mode, p, signer, tc.tlog)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, b); diff != "" {
+				t.Errorf("unexpected provenance (-want +got):\n%s", diff)
+			}
+			if want, got := tc.uploaded, logEntry != nil; want != got {
+				t.Errorf("unexpected upload, want: %v, got: %v", want, got)
+			}
+
+			b, logEntry, err = AttestPayload(context.Background(), tc.mode, statement, signer, tc.tlog)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected payload error, want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, b); diff != "" {
+				t.Errorf("unexpected payload provenance (-want +got):\n%s", diff)
+			}
+			if want, got := tc.uploaded, logEntry != nil; want != got {
+				t.Errorf("unexpected payload upload, want: %v, got: %v", want, got)
+			}
+		})
+	}
+}
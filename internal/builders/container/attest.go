@@ -0,0 +1,315 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/sigstore"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// attestCmd returns the 'attest' command.
+func attestCmd(provider slsa.ClientProvider, check func(error),
+	signer signing.Signer, tlog signing.TransparencyLog,
+) *cobra.Command {
+	var opts attestOptions
+	var attPath string
+	var githubAttestation bool
+	var perPlatform bool
+
+	c := &cobra.Command{
+		Use:   "attest IMAGE...",
+		Short: "Create a signed SLSA provenance attestation for container images",
+		Long: `Generate and sign SLSA provenance for container images from a Github Action
+to form an attestation and upload to a Rekor transparency log. This command
+assumes that it is being run in the context of a Github Actions workflow, with
+credentials for the registries in the docker config.
+
+Each image reference is resolved to the digest of its manifest in the registry.
+The subjects of the provenance are named after the repositories of the images.
+Multi-platform images have a subject for the image index and for each platform
+manifest, whose platform is recorded in the 'platform' annotation. The
+provenance is an in-toto v1 statement, whose subjects are resource descriptors
+that can have annotations.
+
+With --per-platform, an attestation is created for each platform manifest,
+with the image index and the platform manifest as subjects. The attestations
+are written to the provenance file one per line.
+
+With --signing-mode=unsigned, the unsigned provenance statement is written
+instead. It is generated without requesting an OIDC token.
+
+With --github-attestation, the signed provenance is also uploaded as a Sigstore
+bundle to the attestations of the repository. This requires the attestations
+write permission. The IDs of the uploaded attestations are set as a JSON list
+in the 'attestation-ids' step output. If a single attestation is uploaded, its
+ID is also set as the 'attestation-id' step output.
+
+With --local, provenance for a local build is generated from the git working
+tree of the current directory and signed with the key given by --signing-key.
+Local provenance is not uploaded to the transparency log.`,
+		Args: cobra.MinimumNArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			mode, err := opts.signingMode()
+			check(err)
+			if githubAttestation {
+				switch {
+				case mode == common.SigningModeUnsigned:
+					check(common.ErrUnsignedAttestation)
+				case opts.local:
+					check(errors.New("--github-attestation is only supported in Github Actions"))
+				}
+			}
+
+			ctx := context.Background()
+
+			images, err := resolveImages(ctx, args)
+			check(err)
+
+			// NOTE: The provenance file path is untrusted and should be
+			// validated. This is done by CreateNewFileUnderCurrentDirectory.
+			if attPath == "" {
+				if len(images) == 1 {
+					attPath = fmt.Sprintf("%s.intoto.jsonl", path.Base(images[0].subject.Name))
+				} else {
+					attPath = "multiple.intoto.jsonl"
+				}
+			}
+			check(utils.VerifyAttestationPath(attPath))
+
+			atts, err := attestImages(ctx, images, perPlatform, &opts, provider, signer, tlog)
+			check(err)
+
+			var attBytes [][]byte
+			var ids []int64
+			for _, att := range atts {
+				if githubAttestation {
+					bundle, err := sigstore.NewBundle(att.bytes, att.logEntry)
+					check(err)
+					id, err := common.UploadGithubAttestation(ctx, provider, bundle)
+					check(err)
+					ids = append(ids, id)
+				}
+				attBytes = append(attBytes, att.bytes)
+			}
+			b := bytes.Join(attBytes, []byte("\n"))
+
+			// Note: the path is validated within CreateNewFileUnderCurrentDirectory().
+			f, err := utils.CreateNewFileUnderCurrentDirectory(attPath, os.O_WRONLY)
+			check(err)
+
+			_, err = f.Write(b)
+			check(err)
+
+			// Print the provenance name and sha256 so it can be used by the workflow.
+			if opts.local {
+				// Local builds use the file directly.
+				return
+			}
+			check(github.SetOutput("provenance-name", attPath))
+			check(github.SetOutput("provenance-sha256", fmt.Sprintf("%x", sha256.Sum256(b))))
+			if len(ids) > 0 {
+				mids, err := json.Marshal(ids)
+				check(err)
+				check(github.SetOutput("attestation-ids", string(mids)))
+			}
+			if len(ids) == 1 {
+				check(github.SetOutput(common.AttestationIDOutput, strconv.FormatInt(ids[0], 10)))
+			}
+		},
+	}
+
+	c.Flags().StringVarP(
+		&attPath, "signature", "g", "",
+		"Path to write the signed provenance.",
+	)
+	c.Flags().BoolVar(
+		&perPlatform, "per-platform", false,
+		"Create an attestation for each platform manifest of multi-platform images.",
+	)
+	c.Flags().BoolVar(
+		&githubAttestation, "github-attestation", false,
+		"Upload the signed provenance to the attestations of the repository.",
+	)
+	common.AddLocalFlags(c, &opts.local, &opts.signingKeyPath)
+	opts.addFlags(c)
+	return c
+}
+
+// attestOptions are the options of the commands creating attestations for
+// container images.
+type attestOptions struct {
+	redactionPolicyPath string
+	allowSelfHosted     bool
+	mode                string
+
+	// local and signingKeyPath are only set by the 'attest' command.
+	local          bool
+	signingKeyPath string
+}
+
+// addFlags adds the flags of the options to the command.
+func (o *attestOptions) addFlags(c *cobra.Command) {
+	c.Flags().BoolVar(
+		&o.allowSelfHosted, "allow-self-hosted-runners", false,
+		"Allow generating provenance on self-hosted runners. The provenance uses a self-hosted builder ID.",
+	)
+	c.Flags().StringVar(
+		&o.redactionPolicyPath, "redaction-policy", "",
+		"Path to a JSON redaction policy for the event payload and vars context. (default: built-in policy)",
+	)
+	common.AddSigningModeFlag(c, &o.mode)
+}
+
+// signingMode returns the parsed signing mode.
+func (o *attestOptions) signingMode() (common.SigningMode, error) {
+	return common.ParseSigningMode(o.mode)
+}
+
+// attestation is an attestation of a group of subjects.
+type attestation struct {
+	// predicateType is the predicate type of the statement.
+	predicateType string
+
+	// bytes is the signed attestation, or the statement if unsigned.
+	bytes []byte
+
+	// logEntry is the transparency log entry, if any.
+	logEntry signing.LogEntry
+}
+
+// attestImages generates the provenance of the images and creates an
+// attestation of all the subjects, or one for each platform manifest if
+// perPlatform is true.
+func attestImages(ctx context.Context, images []resolvedImage, perPlatform bool, opts *attestOptions,
+	provider slsa.ClientProvider, signer signing.Signer, tlog signing.TransparencyLog,
+) ([]attestation, error) {
+	mode, err := opts.signingMode()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := [][]subject{subjects(images)}
+	if perPlatform {
+		groups = platformSubjects(images)
+	}
+
+	var g slsa.Generator
+	if opts.local {
+		// Local provenance is signed with the key and is not uploaded to the
+		// transparency log.
+		signer, err = common.LoadLocalSigner(opts.signingKeyPath, mode)
+		if err != nil {
+			return nil, err
+		}
+		tlog = nil
+		g, err = newLocalGenerator(ctx, intotoSubjects(subjects(images)))
+	} else {
+		g, err = newGenerator(intotoSubjects(subjects(images)), mode.ClientProvider(provider),
+			opts.redactionPolicyPath, opts.allowSelfHosted)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := g.Generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	atts := make([]attestation, 0, len(groups))
+	for _, group := range groups {
+		payload, err := json.Marshal(&statement{
+			Type:          statementInTotoV1,
+			PredicateType: p.PredicateType,
+			Subject:       resourceDescriptors(group),
+			Predicate:     p.Predicate,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		att, logEntry, err := common.AttestPayload(ctx, mode, payload, signer, tlog)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, attestation{
+			predicateType: p.PredicateType,
+			bytes:         att,
+			logEntry:      logEntry,
+		})
+	}
+	return atts, nil
+}
+
+// statementInTotoV1 is the type of in-toto v1 statements, whose subjects are
+// resource descriptors.
+const statementInTotoV1 = "https://in-toto.io/Statement/v1"
+
+// statement is an in-toto v1 statement. Unlike v0.1 statements, its subjects
+// can have annotations.
+type statement struct {
+	Type          string                     `json:"_type"`
+	PredicateType string                     `json:"predicateType"`
+	Subject       []slsa1.ResourceDescriptor `json:"subject"`
+	Predicate     any                        `json:"predicate"`
+}
+
+// resourceDescriptors returns the subjects as resource descriptors, with
+// their annotations.
+func resourceDescriptors(annotated []subject) []slsa1.ResourceDescriptor {
+	rds := make([]slsa1.ResourceDescriptor, 0, len(annotated))
+	for _, a := range annotated {
+		rd := slsa1.ResourceDescriptor{
+			Name:   a.Name,
+			Digest: a.Digest,
+		}
+		if len(a.Annotations) > 0 {
+			rd.Annotations = make(map[string]any, len(a.Annotations))
+			for k, v := range a.Annotations {
+				rd.Annotations[k] = v
+			}
+		}
+		rds = append(rds, rd)
+	}
+	return rds
+}
+
+// intotoSubjects returns the subjects without their annotations.
+func intotoSubjects(annotated []subject) []intoto.Subject {
+	s := make([]intoto.Subject, 0, len(annotated))
+	for _, a := range annotated {
+		s = append(s, a.Subject)
+	}
+	return s
+}
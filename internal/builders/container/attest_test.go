@@ -0,0 +1,419 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ita1 "github.com/in-toto/attestation/go/v1"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+	"github.com/Kong/slsa-github-generator/signing/envelope"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// testImageSubject returns the expected subject of the image.
+func testImageSubject(digest name.Digest) intoto.Subject {
+	return intoto.Subject{
+		Name:   digest.Context().Name(),
+		Digest: slsacommon.DigestSet{"sha256": strings.TrimPrefix(digest.DigestStr(), "sha256:")},
+	}
+}
+
+// testResourceDescriptor returns the expected resource descriptor of the
+// image, with the platform annotation if platform is not empty.
+func testResourceDescriptor(t *testing.T, digest name.Digest, platform string) *ita1.ResourceDescriptor {
+	t.Helper()
+
+	s := testImageSubject(digest)
+	rd := &ita1.ResourceDescriptor{Name: s.Name, Digest: s.Digest}
+	if platform != "" {
+		annotations, err := structpb.NewStruct(map[string]any{platformAnnotation: platform})
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		rd.Annotations = annotations
+	}
+	return rd
+}
+
+// parseStatementV1 parses the payload as a valid in-toto v1 statement.
+func parseStatementV1(t *testing.T, payload []byte) *ita1.Statement {
+	t.Helper()
+
+	var s ita1.Statement
+	if err := protojson.Unmarshal(payload, &s); err != nil {
+		t.Fatalf("unmarshaling in-toto v1 statement: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("invalid in-toto v1 statement: %v", err)
+	}
+	return &s
+}
+
+func Test_attestCmd(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	outputPath := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputPath, nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	host := newTestRegistry(t, false)
+	app := pushTestImage(t, host+"/owner/app:v1")
+	// The latest tag is moved to a new image.
+	pushTestImage(t, host+"/owner/app:latest")
+	latest := pushTestImage(t, host+"/owner/app:latest")
+	sidecar := pushTestImage(t, host+"/owner/sidecar:v1")
+
+	c := attestCmd(&slsa.NilClientProvider{}, checkTest(t), testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		host + "/owner/app:v1",
+		app.String(),
+		host + "/owner/app:latest",
+		host + "/owner/sidecar:v1",
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("multiple.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+	var env envelope.Envelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		t.Fatalf("unmarshaling envelope: %v", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	parseStatementV1(t, payload)
+	var statement intoto.Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		t.Fatalf("unmarshaling statement: %v", err)
+	}
+
+	// Tags are resolved to their current digest, and references to the same
+	// manifest result in a single subject.
+	want := []intoto.Subject{
+		testImageSubject(app),
+		testImageSubject(latest),
+		testImageSubject(sidecar),
+	}
+	if diff := cmp.Diff(want, statement.Subject); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if !strings.HasPrefix(string(output), "provenance-name=multiple.intoto.jsonl\n") {
+		t.Errorf("unexpected output: %q", output)
+	}
+}
+
+func Test_attestCmd_unsigned(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	host := newTestRegistry(t, false)
+	app := pushTestImage(t, host+"/owner/app:v1")
+
+	// No clients are given, so the command must not request an OIDC token,
+	// and signing or uploading fails the test.
+	c := attestCmd(nil, checkTest(t), nil, &testutil.TransparencyLogWithErr{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--signing-mode", "unsigned", host + "/owner/app:v1"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("app.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+	parseStatementV1(t, b)
+	var got intoto.ProvenanceStatement
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if diff := cmp.Diff([]intoto.Subject{testImageSubject(app)}, got.Subject); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+}
+
+func Test_attestCmd_local(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	// Change to a temporary git working tree.
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false",
+			"commit", "--quiet", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	privPEM, pubPEM, err := cryptoutils.GeneratePEMEncodedECDSAKeyPair(elliptic.P256(),
+		cryptoutils.SkipPassword)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, privPEM, 0o600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+
+	host := newTestRegistry(t, false)
+	app := pushTestImage(t, host+"/owner/app:v1")
+
+	// No clients are given, and uploading to the transparency log fails the
+	// test.
+	c := attestCmd(nil, checkTest(t), &testutil.TestSigner{}, &testutil.TransparencyLogWithErr{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--local", "--signing-key", keyPath, host + "/owner/app:v1"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("app.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+
+	// Verify the envelope with the public key.
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("UnmarshalPEMToPublicKey: %v", err)
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("LoadVerifier: %v", err)
+	}
+	if err := dsse.WrapVerifier(v).VerifySignature(bytes.NewReader(b), nil); err != nil {
+		t.Fatalf("verifying attestation: %v", err)
+	}
+
+	var env envelope.Envelope
+	if err := json.Unmarshal(b, &env); err != nil {
+		t.Fatalf("unmarshaling envelope: %v", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	var got intoto.ProvenanceStatement
+	if err := json.Unmarshal(payload, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if diff := cmp.Diff([]intoto.Subject{testImageSubject(app)}, got.Subject); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+	if want, got := slsa.LocalBuilderID, got.Predicate.Builder.ID; want != got {
+		t.Errorf("unexpected builder ID, want: %q, got: %q", want, got)
+	}
+	if want, got := slsa.LocalBuildType, got.Predicate.BuildType; want != got {
+		t.Errorf("unexpected build type, want: %q, got: %q", want, got)
+	}
+}
+
+func Test_attestCmd_githubAttestation(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", `{"repository": "owner/repo", "run_id": "1"}`)
+	t.Setenv("VARS_CONTEXT", "{}")
+	outputPath := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputPath, nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	host := newTestRegistry(t, false)
+	pushTestIndex(t, host+"/owner/app:v1", types.OCIImageIndex)
+
+	s := github.NewTestAttestationsServer(t)
+	s.HandleFunc("/repos/owner/repo/actions/runs/1", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "workflow_id": 2}`)
+	})
+	s.HandleFunc("/repos/owner/repo/actions/workflows/2", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "path": ".github/workflows/release.yml"}`)
+	})
+	c := attestCmd(&testutil.GithubClientProvider{Client: s.GithubClient()}, checkTest(t),
+		testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--github-attestation", "--per-platform", host + "/owner/app:v1"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	// An attestation is uploaded for each platform manifest.
+	bundles := s.Bundles("owner/repo")
+	if len(bundles) != 2 {
+		t.Fatalf("unexpected bundles: %s", bundles)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if !strings.Contains(string(output), "attestation-ids=[1,2]\n") {
+		t.Errorf("unexpected output: %q", output)
+	}
+	if strings.Contains(string(output), "attestation-id=") {
+		t.Errorf("unexpected attestation ID output: %q", output)
+	}
+}
+
+func Test_attestCmd_perPlatform(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	t.Setenv("GITHUB_OUTPUT", "")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	host := newTestRegistry(t, false)
+	index, platforms := pushTestIndex(t, host+"/owner/app:v1", types.OCIImageIndex)
+
+	c := attestCmd(&slsa.NilClientProvider{}, checkTest(t), testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--per-platform", host + "/owner/app:v1"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("app.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+	var got [][]*ita1.ResourceDescriptor
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		var env envelope.Envelope
+		if err := json.Unmarshal(line, &env); err != nil {
+			t.Fatalf("unmarshaling envelope: %v", err)
+		}
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		got = append(got, parseStatementV1(t, payload).GetSubject())
+	}
+
+	app := testResourceDescriptor(t, index, "")
+	want := [][]*ita1.ResourceDescriptor{
+		{app, testResourceDescriptor(t, platforms[0], "linux/amd64")},
+		{app, testResourceDescriptor(t, platforms[1], "linux/arm64/v8")},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"os"
 
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/spf13/cobra"
 
 	"github.com/Kong/slsa-github-generator/github"
@@ -30,43 +31,28 @@ import (
 // generateCmd returns the 'generate' command.
 func generateCmd(provider slsa.ClientProvider, check func(error)) *cobra.Command {
 	var predicatePath string
+	var redactionPolicyPath string
+	var allowSelfHosted bool
+	var signingMode string
 
 	c := &cobra.Command{
 		Use:   "generate",
 		Short: "Create a SLSA provenance predicate from a GitHub Action",
 		Long: `Generate SLSA provenance predicate from a GitHub Action. This command assumes
-that it is being run in the context of a Github Actions workflow.`,
+that it is being run in the context of a Github Actions workflow.
+
+The predicate is signed by the workflow, so the signing mode only affects how
+it is generated: with --signing-mode=unsigned, no OIDC token is requested.`,
 
 		Run: func(_ *cobra.Command, _ []string) {
-			ghContext, err := github.GetWorkflowContext()
+			mode, err := common.ParseSigningMode(signingMode)
 			check(err)
 
-			varsContext, err := github.GetVarsContext()
+			// NOTE: Subjects are nil because we are only writing the predicate.
+			g, err := newGenerator(nil, mode.ClientProvider(provider), redactionPolicyPath, allowSelfHosted)
 			check(err)
 
 			ctx := context.Background()
-
-			b := common.GenericBuild{
-				// NOTE: Subjects are nil because we are only writing the predicate.
-				GithubActionsBuild: slsa.NewGithubActionsBuild(nil, &ghContext, varsContext),
-				BuildTypeURI:       containerBuildType,
-			}
-
-			if provider != nil {
-				b.WithClients(provider)
-			} else if utils.IsPresubmitTests() {
-				// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-				b.WithClients(&slsa.NilClientProvider{})
-			}
-
-			g := slsa.NewHostedActionsGenerator(&b)
-			if provider != nil {
-				g.WithClients(provider)
-			} else if utils.IsPresubmitTests() {
-				// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-				g.WithClients(&slsa.NilClientProvider{})
-			}
-
 			p, err := g.Generate(ctx)
 			check(err)
 
@@ -86,6 +72,60 @@ that it is being run in the context of a Github Actions workflow.`,
 		"predicate", "p", "predicate.json",
 		"Path to write the unsigned provenance predicate.",
 	)
+	c.Flags().BoolVar(
+		&allowSelfHosted, "allow-self-hosted-runners", false,
+		"Allow generating provenance on self-hosted runners. The provenance uses a self-hosted builder ID.",
+	)
+	c.Flags().StringVar(
+		&redactionPolicyPath, "redaction-policy", "",
+		"Path to a JSON redaction policy for the event payload and vars context. (default: built-in policy)",
+	)
+	common.AddSigningModeFlag(c, &signingMode)
 
 	return c
 }
+
+// newGenerator returns a provenance generator for the subjects in the current
+// Github Actions workflow. A nil provider uses the default clients.
+func newGenerator(subjects []intoto.Subject, provider slsa.ClientProvider,
+	redactionPolicyPath string, allowSelfHosted bool,
+) (slsa.Generator, error) {
+	ghContext, err := github.GetWorkflowContext()
+	if err != nil {
+		return nil, err
+	}
+	varsContext, err := github.GetVarsContext()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := common.LoadRedactionPolicy(redactionPolicyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b := common.GenericBuild{
+		GithubActionsBuild: slsa.NewGithubActionsBuild(subjects, &ghContext, varsContext).
+			WithRedactionPolicy(policy),
+		BuildTypeURI: containerBuildType,
+	}
+
+	g := slsa.NewHostedActionsGenerator(&b)
+	if allowSelfHosted {
+		g.WithSelfHostedRunners(github.GetRunnerContext())
+	}
+	if provider != nil {
+		b.WithClients(provider)
+		g.WithClients(provider)
+	}
+	return g, nil
+}
+
+// newLocalGenerator returns a provenance generator for a local build from the
+// git working tree of the current directory.
+func newLocalGenerator(ctx context.Context, subjects []intoto.Subject) (slsa.Generator, error) {
+	git, err := common.ReadGitState(ctx, ".")
+	if err != nil {
+		return nil, err
+	}
+	return slsa.NewLocalGenerator(slsa.NewLocalBuild(subjects, git)), nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+// platformAnnotation is the subject annotation holding the platform of an
+// image manifest of a multi-platform image, such as "linux/arm64/v8".
+const platformAnnotation = "platform"
+
+// subject is a provenance subject with annotations. It is recorded as an
+// in-toto resource descriptor.
+type subject struct {
+	intoto.Subject
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// resolvedImage is an image resolved to the manifest in the registry.
+type resolvedImage struct {
+	// subject is the subject of the manifest, which may be an image index.
+	subject subject
+
+	// digest and desc are the reference and the descriptor of the manifest.
+	digest name.Digest
+	desc   *v1.Descriptor
+
+	// platforms are the subjects of the image manifests of an image index.
+	platforms []subject
+
+	// platformDescs are the descriptors of the image manifests of an image
+	// index, in the order of platforms.
+	platformDescs []v1.Descriptor
+}
+
+// resolveImages resolves the image references to the manifests in the
+// registry. The platform manifests of image indexes and Docker manifest lists
+// are resolved as well. References to the same manifest, such as two tags of
+// an image, result in a single image.
+func resolveImages(ctx context.Context, images []string) ([]resolvedImage, error) {
+	var resolved []resolvedImage
+	seen := map[string]bool{}
+	for _, image := range images {
+		digest, desc, err := resolveImage(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		if seen[digest.String()] {
+			continue
+		}
+		seen[digest.String()] = true
+
+		r := resolvedImage{
+			subject: subject{Subject: imageSubject(digest.Repository, desc.Digest)},
+			digest:  digest,
+			desc:    desc,
+		}
+		if desc.MediaType.IsIndex() {
+			idx, err := remote.Index(digest, registryOptions(ctx)...)
+			if err != nil {
+				return nil, fmt.Errorf("fetching image index %q: %w", digest, err)
+			}
+			m, err := idx.IndexManifest()
+			if err != nil {
+				return nil, fmt.Errorf("fetching image index %q: %w", digest, err)
+			}
+			for _, d := range m.Manifests {
+				// NOTE: Indexes may also hold attestation manifests, such as
+				// those added by buildx, whose platform is unknown.
+				if !d.MediaType.IsImage() || d.Platform == nil || d.Platform.OS == "unknown" {
+					continue
+				}
+				r.platforms = append(r.platforms, subject{
+					Subject:     imageSubject(digest.Repository, d.Digest),
+					Annotations: map[string]string{platformAnnotation: d.Platform.String()},
+				})
+				r.platformDescs = append(r.platformDescs, d)
+			}
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, nil
+}
+
+// subjects returns the subjects of the images, with each index followed by its
+// platform manifests.
+func subjects(images []resolvedImage) []subject {
+	var s []subject
+	for _, r := range images {
+		s = append(s, r.subject)
+		s = append(s, r.platforms...)
+	}
+	return s
+}
+
+// platformSubjects returns the subjects of the images grouped by platform.
+// Each platform manifest of an index is grouped with the index, and other
+// images are grouped alone.
+func platformSubjects(images []resolvedImage) [][]subject {
+	var groups [][]subject
+	for _, r := range images {
+		if len(r.platforms) == 0 {
+			groups = append(groups, []subject{r.subject})
+			continue
+		}
+		for _, p := range r.platforms {
+			groups = append(groups, []subject{r.subject, p})
+		}
+	}
+	return groups
+}
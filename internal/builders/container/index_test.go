@@ -0,0 +1,127 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// testPlatforms are the platforms of the test image indexes.
+var testPlatforms = []v1.Platform{
+	{OS: "linux", Architecture: "amd64"},
+	{OS: "linux", Architecture: "arm64", Variant: "v8"},
+}
+
+// pushTestIndex pushes an image index of the media type with an image for
+// each of testPlatforms and an attestation manifest, as added by buildx. It
+// returns the digests of the index and of the platform images.
+func pushTestIndex(t *testing.T, tag string, mt types.MediaType) (name.Digest, []name.Digest) {
+	ref, err := name.NewTag(tag)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	var idx v1.ImageIndex = empty.Index
+	var platforms []name.Digest
+	for i := range testPlatforms {
+		img, err := random.Image(1024, 1)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &testPlatforms[i]},
+		})
+		d, err := img.Digest()
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		platforms = append(platforms, ref.Context().Digest(d.String()))
+	}
+	att, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+		Add:        att,
+		Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "unknown", Architecture: "unknown"}},
+	})
+	idx = mutate.IndexMediaType(idx, mt)
+
+	if err := remote.WriteIndex(ref, idx); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	d, err := idx.Digest()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return ref.Context().Digest(d.String()), platforms
+}
+
+func Test_resolveImages(t *testing.T) {
+	testCases := []struct {
+		name      string
+		mediaType types.MediaType
+	}{
+		{name: "oci image index", mediaType: types.OCIImageIndex},
+		{name: "docker manifest list", mediaType: types.DockerManifestList},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			host := newTestRegistry(t, false)
+			index, platforms := pushTestIndex(t, host+"/owner/app:v1", tc.mediaType)
+			sidecar := pushTestImage(t, host+"/owner/sidecar:v1")
+
+			images, err := resolveImages(context.Background(), []string{
+				host + "/owner/app:v1",
+				index.String(),
+				host + "/owner/sidecar:v1",
+			})
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			app := subject{Subject: testImageSubject(index)}
+			amd64 := subject{
+				Subject:     testImageSubject(platforms[0]),
+				Annotations: map[string]string{platformAnnotation: "linux/amd64"},
+			}
+			arm64 := subject{
+				Subject:     testImageSubject(platforms[1]),
+				Annotations: map[string]string{platformAnnotation: "linux/arm64/v8"},
+			}
+			side := subject{Subject: testImageSubject(sidecar)}
+
+			if diff := cmp.Diff([]subject{app, amd64, arm64, side}, subjects(images)); diff != "" {
+				t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+			}
+			want := [][]subject{{app, amd64}, {app, arm64}, {side}}
+			if diff := cmp.Diff(want, platformSubjects(images)); diff != "" {
+				t.Errorf("unexpected platform subjects (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -23,6 +23,8 @@ import (
 	_ "github.com/sigstore/cosign/v2/pkg/providers/github"
 
 	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/signing/sigstore"
 )
 
 // containerBuildType is the URI for generic container SLSA generation.
@@ -47,6 +49,9 @@ For more information on SLSA, visit https://slsa.dev`,
 	}
 	c.AddCommand(versionCmd())
 	c.AddCommand(generateCmd(nil, checkExit))
+	c.AddCommand(uploadAttestationCmd(nil, checkExit))
+	c.AddCommand(attestCmd(nil, checkExit, sigstore.NewDefaultFulcio(), sigstore.NewDefaultRekor()))
+	c.AddCommand(pushCmd(nil, checkExit, sigstore.NewDefaultFulcio(), sigstore.NewDefaultRekor()))
 	return c
 }
 
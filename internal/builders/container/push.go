@@ -0,0 +1,143 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// pushCmd returns the 'push' command.
+func pushCmd(provider slsa.ClientProvider, check func(error),
+	signer signing.Signer, tlog signing.TransparencyLog,
+) *cobra.Command {
+	var opts attestOptions
+	var image string
+	var format string
+	var perPlatform bool
+
+	c := &cobra.Command{
+		Use:   "push",
+		Short: "Sign SLSA provenance for a container image and push it to the registry",
+		Long: `Generate and sign SLSA provenance for a container image from a Github Action,
+upload it to a Rekor transparency log and push it to the registry of the image.
+This command assumes that it is being run in the context of a Github Actions
+workflow, with credentials for the registry in the docker config.
+
+The provenance is generated and signed as by the 'attest' command for the
+image, and the attestation is pushed in the format given by --format:
+
+  cosign    attached to the image as by 'cosign attest'. (default)
+  referrer  a Sigstore bundle in an OCI 1.1 artifact referring to the image.
+
+With --per-platform, an attestation is created for each platform manifest of a
+multi-platform image, as by the 'attest' command, and is pushed for the
+platform manifest.
+
+The references of the pushed attestations are set as a JSON list in the
+'attestation-refs' step output. If a single attestation is pushed, its
+reference is also set as the 'attestation-ref' step output.`,
+		Args: cobra.NoArgs,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			if image == "" {
+				check(errors.New("--image is required"))
+			}
+			f, err := parsePushFormat(format)
+			check(err)
+			mode, err := opts.signingMode()
+			check(err)
+			if mode == common.SigningModeUnsigned {
+				check(errUnsignedPush)
+			}
+
+			ctx := context.Background()
+
+			images, err := resolveImages(ctx, []string{image})
+			check(err)
+
+			atts, err := attestImages(ctx, images, perPlatform, &opts, provider, signer, tlog)
+			check(err)
+
+			var refs []string
+			for i, t := range pushTargets(images[0], perPlatform) {
+				att := atts[i]
+				ref, err := pushAttestation(ctx, t.digest, t.desc, att.bytes, att.logEntry, att.predicateType, f)
+				check(err)
+				fmt.Printf("Pushed attestation for %s to %s.\n", t.digest, ref)
+				refs = append(refs, ref.String())
+			}
+
+			mrefs, err := json.Marshal(refs)
+			check(err)
+			check(github.SetOutput("attestation-refs", string(mrefs)))
+			if len(refs) == 1 {
+				check(github.SetOutput("attestation-ref", refs[0]))
+			}
+		},
+	}
+
+	c.Flags().StringVar(
+		&image, "image", "",
+		"Reference of the container image to attest.",
+	)
+	c.Flags().StringVar(
+		&format, "format", string(pushFormatCosign),
+		fmt.Sprintf("Format of the pushed attestation: %q or %q.", pushFormatCosign, pushFormatReferrer),
+	)
+	c.Flags().BoolVar(
+		&perPlatform, "per-platform", false,
+		"Push an attestation for each platform manifest of a multi-platform image.",
+	)
+	opts.addFlags(c)
+
+	return c
+}
+
+// pushTarget is a manifest that an attestation is pushed for.
+type pushTarget struct {
+	digest name.Digest
+	desc   *v1.Descriptor
+}
+
+// pushTargets returns the manifests that the attestations of the image are
+// pushed for, in the order of the attestations created by attestImages. With
+// perPlatform, the attestation of each platform is pushed for the platform
+// manifest.
+func pushTargets(img resolvedImage, perPlatform bool) []pushTarget {
+	if !perPlatform || len(img.platformDescs) == 0 {
+		return []pushTarget{{digest: img.digest, desc: img.desc}}
+	}
+	targets := make([]pushTarget, 0, len(img.platformDescs))
+	for i := range img.platformDescs {
+		d := &img.platformDescs[i]
+		targets = append(targets, pushTarget{
+			digest: img.digest.Context().Digest(d.Digest.String()),
+			desc:   d,
+		})
+	}
+	return targets
+}
@@ -0,0 +1,212 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+	"github.com/Kong/slsa-github-generator/signing/envelope"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+func Test_pushCmd(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	outputPath := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputPath, nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	host := newTestRegistry(t, true)
+	digest := pushTestImage(t, host+"/owner/image:v1")
+
+	c := pushCmd(&slsa.NilClientProvider{}, checkTest(t), testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--image", host + "/owner/image:v1", "--format", "referrer"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	outputs := readOutputs(t, outputPath)
+	ref := outputs["attestation-ref"]
+	if want, got := fmt.Sprintf("[%q]", ref), outputs["attestation-refs"]; want != got {
+		t.Errorf("unexpected attestation-refs output, want: %s, got: %s", want, got)
+	}
+
+	// The attestation is a referrer whose statement has the image as subject.
+	statement := readReferrerStatement(t, ref)
+	want := []intoto.Subject{{
+		Name:   host + "/owner/image",
+		Digest: slsacommon.DigestSet{"sha256": strings.TrimPrefix(digest.DigestStr(), "sha256:")},
+	}}
+	if diff := cmp.Diff(want, statement.Subject); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+}
+
+func Test_pushCmd_perPlatform(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	outputPath := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputPath, nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	host := newTestRegistry(t, true)
+	index, platforms := pushTestIndex(t, host+"/owner/app:v1", types.OCIImageIndex)
+
+	c := pushCmd(&slsa.NilClientProvider{}, checkTest(t), testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--image", host + "/owner/app:v1", "--format", "referrer", "--per-platform"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	// All the references are set in a single output.
+	outputs := readOutputs(t, outputPath)
+	if ref, ok := outputs["attestation-ref"]; ok {
+		t.Errorf("unexpected attestation-ref output: %q", ref)
+	}
+	var refs []string
+	if err := json.Unmarshal([]byte(outputs["attestation-refs"]), &refs); err != nil {
+		t.Fatalf("unmarshaling attestation-refs: %v", err)
+	}
+	if want, got := len(platforms), len(refs); want != got {
+		t.Fatalf("unexpected number of references, want: %d, got: %d", want, got)
+	}
+
+	// Each attestation refers to its platform manifest, whose statement has
+	// the index and the platform manifest as subjects.
+	for i, ref := range refs {
+		attDigest, err := name.NewDigest(ref)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		m, err := remote.Get(attDigest)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		var manifest v1.Manifest
+		if err := json.Unmarshal(m.Manifest, &manifest); err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		if manifest.Subject == nil || manifest.Subject.Digest.String() != platforms[i].DigestStr() {
+			t.Errorf("unexpected subject of attestation %d: %v", i, manifest.Subject)
+		}
+
+		want := []intoto.Subject{testImageSubject(index), testImageSubject(platforms[i])}
+		if diff := cmp.Diff(want, readReferrerStatement(t, ref).Subject); diff != "" {
+			t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+		}
+	}
+}
+
+// readOutputs returns the step outputs written to the file.
+func readOutputs(t *testing.T, path string) map[string]string {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	outputs := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			t.Fatalf("unexpected output: %q", line)
+		}
+		outputs[k] = v
+	}
+	return outputs
+}
+
+// readReferrerStatement returns the statement of the Sigstore bundle pushed as
+// a referrer.
+func readReferrerStatement(t *testing.T, ref string) *intoto.Statement {
+	t.Helper()
+
+	attDigest, err := name.NewDigest(ref)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	img, err := remote.Image(attDigest)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	layers, err := img.Layers()
+	if err != nil || len(layers) != 1 {
+		t.Fatalf("expected a single layer: %v", err)
+	}
+	rc, err := layers[0].Uncompressed()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer rc.Close()
+	var bundle struct {
+		DSSEEnvelope envelope.Envelope `json:"dsseEnvelope"`
+	}
+	if err := json.NewDecoder(rc).Decode(&bundle); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(bundle.DSSEEnvelope.Payload)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	var statement intoto.Statement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return &statement
+}
+
+func Test_pushCmd_unsigned(t *testing.T) {
+	check := func(err error) {
+		if err != nil {
+			if !errors.Is(err, errUnsignedPush) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// Stop the command.
+			t.SkipNow()
+		}
+	}
+
+	c := pushCmd(&slsa.NilClientProvider{}, check, testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--image", "registry.example/owner/image:v1", "--signing-mode", string(common.SigningModeUnsigned)})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Errorf("expected an error to occur.")
+}
@@ -0,0 +1,201 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	cmutate "github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	cstatic "github.com/sigstore/cosign/v2/pkg/oci/static"
+	ctypes "github.com/sigstore/cosign/v2/pkg/types"
+
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/envelope"
+	"github.com/Kong/slsa-github-generator/signing/sigstore"
+)
+
+// pushFormat is the format of attestations pushed to a registry.
+type pushFormat string
+
+const (
+	// pushFormatCosign pushes the DSSE envelope to the attestation tag of the
+	// image, as done by 'cosign attest'.
+	pushFormatCosign pushFormat = "cosign"
+
+	// pushFormatReferrer pushes the Sigstore bundle as an OCI 1.1 artifact
+	// whose subject is the image.
+	pushFormatReferrer pushFormat = "referrer"
+)
+
+// bundleMediaType is the media type of Sigstore bundles pushed as referrers.
+const bundleMediaType types.MediaType = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+var (
+	errInvalidPushFormat = errors.New("invalid push format")
+	errUnsignedPush      = errors.New("unsigned provenance can't be pushed to a registry")
+)
+
+// parsePushFormat parses a push format. An empty string is the cosign format.
+func parsePushFormat(s string) (pushFormat, error) {
+	switch f := pushFormat(s); f {
+	case "":
+		return pushFormatCosign, nil
+	case pushFormatCosign, pushFormatReferrer:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", errInvalidPushFormat, s)
+	}
+}
+
+// registryOptions returns the options to access registries with. Credentials
+// are read from the docker config, such as written by 'docker login'.
+func registryOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}
+}
+
+// resolveImage resolves the image reference to the descriptor of its manifest
+// in the registry. Tags are resolved to the digest they currently point to.
+func resolveImage(ctx context.Context, image string) (name.Digest, *v1.Descriptor, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return name.Digest{}, nil, fmt.Errorf("parsing image %q: %w", image, err)
+	}
+	desc, err := remote.Head(ref, registryOptions(ctx)...)
+	if err != nil {
+		return name.Digest{}, nil, fmt.Errorf("resolving image %q: %w", image, err)
+	}
+	return ref.Context().Digest(desc.Digest.String()), desc, nil
+}
+
+// imageSubject returns the provenance subject of the image manifest with the
+// digest. The subject is named after the repository, as done by cosign.
+func imageSubject(repo name.Repository, h v1.Hash) intoto.Subject {
+	return intoto.Subject{
+		Name:   repo.Name(),
+		Digest: slsacommon.DigestSet{h.Algorithm: h.Hex},
+	}
+}
+
+// pushAttestation pushes the signed attestation and its log entry, which may
+// be nil, to the registry of the image in the format. It returns the
+// reference of the pushed attestation.
+func pushAttestation(ctx context.Context, image name.Digest, subject *v1.Descriptor,
+	att []byte, entry signing.LogEntry, predicateType string, format pushFormat,
+) (name.Reference, error) {
+	switch format {
+	case pushFormatCosign:
+		return pushCosignAttestation(ctx, image, att, entry, predicateType)
+	case pushFormatReferrer:
+		return pushReferrer(ctx, image, subject, att, entry, predicateType)
+	default:
+		return nil, fmt.Errorf("%w: %q", errInvalidPushFormat, format)
+	}
+}
+
+// pushCosignAttestation attaches the attestation to the image in the same way
+// as 'cosign attest', so that it can be verified by 'cosign verify-attestation'.
+func pushCosignAttestation(ctx context.Context, image name.Digest,
+	att []byte, entry signing.LogEntry, predicateType string,
+) (name.Reference, error) {
+	env := &envelope.Envelope{}
+	if err := json.Unmarshal(att, env); err != nil {
+		return nil, fmt.Errorf("%w: %w", sigstore.ErrInvalidEnvelope, err)
+	}
+	if len(env.Signatures) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one signature", sigstore.ErrInvalidEnvelope)
+	}
+
+	opts := []cstatic.Option{
+		cstatic.WithLayerMediaType(ctypes.DssePayloadType),
+		cstatic.WithAnnotations(map[string]string{"predicateType": predicateType}),
+	}
+	if cert := env.Signatures[0].Cert; cert != "" {
+		opts = append(opts, cstatic.WithCertChain([]byte(cert), nil))
+	}
+	if b := sigstore.RekorBundle(entry); b != nil {
+		opts = append(opts, cstatic.WithBundle(b))
+	}
+	sig, err := cstatic.NewAttestation(att, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	remoteOpts := ociremote.WithRemoteOptions(registryOptions(ctx)...)
+	se, err := cmutate.AttachAttestationToEntity(ociremote.SignedUnknown(image, remoteOpts), sig)
+	if err != nil {
+		return nil, err
+	}
+	if err := ociremote.WriteAttestations(image.Repository, se, remoteOpts); err != nil {
+		return nil, fmt.Errorf("pushing attestation: %w", err)
+	}
+	return ociremote.AttestationTag(image, remoteOpts)
+}
+
+// pushReferrer pushes the Sigstore bundle of the attestation as an artifact
+// referring to the image. Registries without the referrers API are supported
+// by the referrers tag schema.
+func pushReferrer(ctx context.Context, image name.Digest, subject *v1.Descriptor,
+	att []byte, entry signing.LogEntry, predicateType string,
+) (name.Reference, error) {
+	bundle, err := sigstore.NewBundle(att, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: The artifact type of the manifest is its config media type.
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer: static.NewLayer(bundle, bundleMediaType),
+	})
+	if err != nil {
+		return nil, err
+	}
+	img = mutate.MediaType(img, types.OCIManifestSchema1)
+	img = mutate.ConfigMediaType(img, bundleMediaType)
+	img = mutate.Annotations(img, map[string]string{
+		"dev.sigstore.bundle.predicateType": predicateType,
+	}).(v1.Image)
+	img = mutate.Subject(img, v1.Descriptor{
+		MediaType: subject.MediaType,
+		Digest:    subject.Digest,
+		Size:      subject.Size,
+	}).(v1.Image)
+
+	d, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	ref := image.Context().Digest(d.String())
+	if err := remote.Write(ref, img, registryOptions(ctx)...); err != nil {
+		return nil, fmt.Errorf("pushing attestation: %w", err)
+	}
+	return ref, nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+)
+
+const testPredicateType = "https://slsa.dev/provenance/v0.2"
+
+// newTestRegistry starts an in-process registry and returns its host.
+func newTestRegistry(t *testing.T, referrers bool) string {
+	s := httptest.NewServer(registry.New(
+		registry.Logger(log.New(io.Discard, "", 0)),
+		registry.WithReferrersSupport(referrers),
+	))
+	t.Cleanup(s.Close)
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// pushTestImage pushes a random image to the tag and returns its digest.
+func pushTestImage(t *testing.T, tag string) name.Digest {
+	ref, err := name.NewTag(tag)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	d, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return ref.Context().Digest(d.String())
+}
+
+// signTestAttestation returns an attestation for the image signed with a
+// self-signed certificate.
+func signTestAttestation(t *testing.T, digest name.Digest) []byte {
+	h, err := v1.NewHash(digest.DigestStr())
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	att, err := testutil.NewCertSigner(t).Sign(context.Background(), &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: testPredicateType,
+			Subject:       []intoto.Subject{imageSubject(digest.Repository, h)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return att.Bytes()
+}
+
+func Test_parsePushFormat(t *testing.T) {
+	testCases := []struct {
+		format string
+		want   pushFormat
+		err    error
+	}{
+		{format: "", want: pushFormatCosign},
+		{format: "cosign", want: pushFormatCosign},
+		{format: "referrer", want: pushFormatReferrer},
+		{format: "oci", err: errInvalidPushFormat},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			got, err := parsePushFormat(tc.format)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if tc.want != got {
+				t.Errorf("unexpected format, want: %q, got: %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func Test_resolveImage(t *testing.T) {
+	host := newTestRegistry(t, false)
+	want := pushTestImage(t, host+"/owner/image:v1")
+
+	got, desc, err := resolveImage(context.Background(), host+"/owner/image:v1")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want.String() != got.String() {
+		t.Errorf("unexpected digest, want: %q, got: %q", want, got)
+	}
+	if diff := cmp.Diff(intoto.Subject{
+		Name:   host + "/owner/image",
+		Digest: slsacommon.DigestSet{"sha256": desc.Digest.Hex},
+	}, imageSubject(got.Repository, desc.Digest)); diff != "" {
+		t.Errorf("unexpected subject (-want +got):\n%s", diff)
+	}
+
+	if _, _, err := resolveImage(context.Background(), host+"/owner/image:missing"); err == nil {
+		t.Errorf("expected an error for a missing image")
+	}
+}
+
+func Test_pushAttestation_cosign(t *testing.T) {
+	ctx := context.Background()
+	host := newTestRegistry(t, false)
+	digest, desc, err := resolveImage(ctx, pushTestImage(t, host+"/owner/image:v1").String())
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	att := signTestAttestation(t, digest)
+
+	ref, err := pushAttestation(ctx, digest, desc, att, &testutil.TestLogEntry{}, testPredicateType, pushFormatCosign)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := host+"/owner/image:sha256-"+desc.Digest.Hex+".att", ref.String(); want != got {
+		t.Errorf("unexpected reference, want: %q, got: %q", want, got)
+	}
+
+	se, err := ociremote.SignedImage(digest)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	atts, err := se.Attestations()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	sigs, err := atts.Get()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if len(sigs) != 1 {
+		t.Fatalf("unexpected number of attestations: %d", len(sigs))
+	}
+	payload, err := sigs[0].Payload()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := string(att), string(payload); want != got {
+		t.Errorf("unexpected payload, want: %q, got: %q", want, got)
+	}
+	annotations, err := sigs[0].Annotations()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := testPredicateType, annotations["predicateType"]; want != got {
+		t.Errorf("unexpected predicate type, want: %q, got: %q", want, got)
+	}
+	cert, err := sigs[0].Cert()
+	if err != nil || cert == nil {
+		t.Errorf("expected a certificate: %v", err)
+	}
+}
+
+func Test_pushAttestation_referrer(t *testing.T) {
+	for _, referrers := range []bool{true, false} {
+		name := "referrers tag schema"
+		if referrers {
+			name = "referrers API"
+		}
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			host := newTestRegistry(t, referrers)
+			digest, desc, err := resolveImage(ctx, pushTestImage(t, host+"/owner/image:v1").String())
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			att := signTestAttestation(t, digest)
+
+			ref, err := pushAttestation(ctx, digest, desc, att, &testutil.TestLogEntry{}, testPredicateType, pushFormatReferrer)
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			idx, err := remote.Referrers(digest)
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			m, err := idx.IndexManifest()
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			if len(m.Manifests) != 1 {
+				t.Fatalf("unexpected number of referrers: %d", len(m.Manifests))
+			}
+			if want, got := string(bundleMediaType), m.Manifests[0].ArtifactType; want != got {
+				t.Errorf("unexpected artifact type, want: %q, got: %q", want, got)
+			}
+			if want, got := ref.Identifier(), m.Manifests[0].Digest.String(); want != got {
+				t.Errorf("unexpected referrer, want: %q, got: %q", want, got)
+			}
+
+			img, err := remote.Image(ref)
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			layers, err := img.Layers()
+			if err != nil || len(layers) != 1 {
+				t.Fatalf("expected a single layer: %v", err)
+			}
+			rc, err := layers[0].Uncompressed()
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			defer rc.Close()
+			b, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			var bundle protobundle.Bundle
+			if err := protojson.Unmarshal(b, &bundle); err != nil {
+				t.Fatalf("unmarshaling bundle: %v", err)
+			}
+			if bundle.GetDsseEnvelope() == nil {
+				t.Errorf("expected a DSSE envelope in the bundle")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// uploadAttestationCmd returns the 'upload-attestation' command.
+func uploadAttestationCmd(provider slsa.ClientProvider, check func(error)) *cobra.Command {
+	var bundlePath string
+
+	c := &cobra.Command{
+		Use:   "upload-attestation",
+		Short: "Upload a signed attestation to the attestations of the repository",
+		Long: `Upload a Sigstore bundle of a signed attestation, such as the provenance
+signed by cosign, to the attestations of the repository. The ID of the
+attestation is set as the 'attestation-id' step output. This command assumes
+that it is being run in the context of a Github Actions workflow with the
+attestations write permission.`,
+		Args: cobra.NoArgs,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			if bundlePath == "" {
+				check(errors.New("--bundle is required"))
+			}
+
+			bundle, err := utils.SafeReadFile(bundlePath)
+			check(err)
+
+			id, err := common.UploadGithubAttestation(context.Background(), provider, bundle)
+			check(err)
+			check(github.SetOutput(common.AttestationIDOutput, strconv.FormatInt(id, 10)))
+		},
+	}
+
+	c.Flags().StringVar(
+		&bundlePath, "bundle", "",
+		"Path to the Sigstore bundle of the attestation, in JSON format.",
+	)
+
+	return c
+}
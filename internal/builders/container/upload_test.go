@@ -0,0 +1,75 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+)
+
+func Test_uploadAttestationCmd(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", `{"repository": "owner/repo"}`)
+	outputPath := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputPath, nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	bundle := []byte(`{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`)
+	if err := os.WriteFile("provenance.sigstore.json", bundle, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	s := github.NewTestAttestationsServer(t)
+	c := uploadAttestationCmd(&testutil.GithubClientProvider{Client: s.GithubClient()}, checkTest(t))
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--bundle", "provenance.sigstore.json"})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if diff := cmp.Diff([]json.RawMessage{bundle}, s.Bundles("owner/repo")); diff != "" {
+		t.Errorf("unexpected bundles (-want +got):\n%s", diff)
+	}
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if want, got := "attestation-id=1\n", string(output); want != got {
+		t.Errorf("unexpected output, want: %q, got: %q", want, got)
+	}
+}
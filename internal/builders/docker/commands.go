@@ -18,19 +18,23 @@ package main
 // `slsa-container-based-generator` command.
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/spf13/cobra"
 
+	"github.com/Kong/slsa-github-generator/github"
 	"github.com/Kong/slsa-github-generator/internal/builders/docker/pkg"
 	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/slsa"
 )
 
 // DryRunCmd returns a new *cobra.Command that validates the input flags, and
@@ -73,6 +77,7 @@ func DryRunCmd(check func(error)) *cobra.Command {
 func BuildCmd(check func(error)) *cobra.Command {
 	inputOptions := &pkg.InputOptions{}
 	var subjectsPath string
+	var buildMetadataPath string
 	var outputFolder string
 
 	cmd := &cobra.Command{
@@ -101,15 +106,23 @@ func BuildCmd(check func(error)) *cobra.Command {
 			defer db.RepoInfo.Cleanup()
 
 			// Build artifacts and write them to the output folder.
+			startedOn := time.Now()
 			artifacts, err := db.BuildArtifacts(absoluteOutputFolder)
 			check(err)
+			finishedOn := time.Now()
 			check(writeJSONToFile(artifacts, w))
+
+			if buildMetadataPath != "" {
+				check(writeBuildMetadata(buildMetadataPath, startedOn, finishedOn))
+			}
 		},
 	}
 
 	inputOptions.AddFlags(cmd)
 	cmd.Flags().StringVarP(&subjectsPath, "subjects-path", "o", "",
 		"Required - Path to store a JSON-encoded array of subjects of the generated artifacts.")
+	cmd.Flags().StringVar(&buildMetadataPath, "build-metadata-path", "",
+		"Path to store the JSON-encoded SLSA v1.0 build metadata, with the measured build times. Requires GITHUB_CONTEXT.")
 	cmd.Flags().StringVar(&outputFolder, "output-folder", "",
 		"Required - Path to a folder to store the generated artifacts. MUST be under /tmp.")
 	check(cmd.MarkFlagRequired("output-folder"))
@@ -184,6 +197,32 @@ func verifyProvenance(provenancePath string) error {
 	return nil
 }
 
+// writeBuildMetadata writes the metadata of the build measured from startedOn
+// to finishedOn, as recorded in the runDetails of SLSA v1.0 provenance, to the
+// path.
+func writeBuildMetadata(path string, startedOn, finishedOn time.Time) error {
+	gh, err := github.GetWorkflowContext()
+	if err != nil {
+		return err
+	}
+
+	// NOTE: The build times are measured, so no client is needed to fetch
+	// them from the workflow run.
+	b := slsa.NewGithubActionsBuild(nil, &gh, nil).
+		WithClients(&slsa.NilClientProvider{}).
+		WithBuildTimes(startedOn, finishedOn)
+	m, err := b.BuildMetadata(context.Background())
+	if err != nil {
+		return err
+	}
+
+	w, err := utils.CreateNewFileUnderCurrentDirectory(path, os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	return writeJSONToFile(m, w)
+}
+
 func writeJSONToFile[T any](obj T, w io.Writer) error {
 	bytes, err := json.Marshal(obj)
 	if err != nil {
@@ -0,0 +1,66 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+func Test_writeBuildMetadata(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", `{"run_id":"12345","run_attempt":"2"}`)
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	startedOn := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishedOn := startedOn.Add(time.Minute)
+	if err := writeBuildMetadata("build-metadata.json", startedOn, finishedOn); err != nil {
+		t.Fatalf("writeBuildMetadata: %v", err)
+	}
+
+	b, err := os.ReadFile("build-metadata.json")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	var got slsa1.BuildMetadata
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling build metadata: %v", err)
+	}
+	want := slsa1.BuildMetadata{
+		InvocationID: "12345-2",
+		StartedOn:    &startedOn,
+		FinishedOn:   &finishedOn,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected build metadata (-want +got):\n%s", diff)
+	}
+}
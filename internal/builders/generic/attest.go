@@ -17,19 +17,21 @@ package main
 import (
 	"context"
 	"crypto/sha256"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/spf13/cobra"
 
 	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/gitlab"
 	"github.com/Kong/slsa-github-generator/internal/builders/common"
 	"github.com/Kong/slsa-github-generator/internal/utils"
 	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/sigstore"
 	"github.com/Kong/slsa-github-generator/slsa"
 )
 
@@ -39,20 +41,43 @@ func attestCmd(provider slsa.ClientProvider, check func(error),
 ) *cobra.Command {
 	var attPath string
 	var subjectsFilename string
+	var redactionPolicyPath string
+	var allowSelfHosted bool
+	var localBuild bool
+	var signingKeyPath string
+	var signingMode string
+	var githubAttestation bool
 
 	c := &cobra.Command{
 		Use:   "attest",
 		Short: "Create a signed SLSA provenance attestation from a Github Action",
 		Long: `Generate and sign SLSA provenance from a Github Action to form an attestation
 and upload to a Rekor transparency log. This command assumes that it is being
-run in the context of a Github Actions workflow.`,
+run in the context of a Github Actions workflow, or of a GitLab CI/CD job if
+GITLAB_CI is set.
 
-		Run: func(_ *cobra.Command, _ []string) {
-			ghContext, err := github.GetWorkflowContext()
-			check(err)
+With --local, provenance for a local build is generated from the git working
+tree of the current directory and signed with the key given by --signing-key.
+Local provenance is not uploaded to the transparency log.
+
+With --signing-mode=unsigned, the unsigned provenance statement is written
+instead. It is generated without requesting an OIDC token.
 
-			varsContext, err := github.GetVarsContext()
+With --github-attestation, the signed provenance is also uploaded as a Sigstore
+bundle to the attestations of the repository. This requires the attestations
+write permission.`,
+
+		Run: func(_ *cobra.Command, _ []string) {
+			mode, err := common.ParseSigningMode(signingMode)
 			check(err)
+			if githubAttestation {
+				switch {
+				case mode == common.SigningModeUnsigned:
+					check(common.ErrUnsignedAttestation)
+				case localBuild || gitlab.IsGitlabCI():
+					check(errors.New("--github-attestation is only supported in Github Actions"))
+				}
+			}
 
 			subjectsBytes, err := utils.SafeReadFile(subjectsFilename)
 			check(err)
@@ -80,46 +105,37 @@ run in the context of a Github Actions workflow.`,
 
 			ctx := context.Background()
 
-			b := common.GenericBuild{
-				GithubActionsBuild: slsa.NewGithubActionsBuild(parsedSubjects, &ghContext, varsContext),
-				BuildTypeURI:       provenanceOnlyBuildType,
-			}
-			if provider != nil {
-				b.WithClients(provider)
-			} else if utils.IsPresubmitTests() {
-				// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-				b.WithClients(&slsa.NilClientProvider{})
-			}
-
-			g := slsa.NewHostedActionsGenerator(&b)
-			if provider != nil {
-				g.WithClients(provider)
-			} else if utils.IsPresubmitTests() {
-				// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-				g.WithClients(&slsa.NilClientProvider{})
+			attSigner, attLog := signer, tlog
+			clients := mode.ClientProvider(provider)
+			var g slsa.Generator
+			switch {
+			case localBuild:
+				attSigner, err = common.LoadLocalSigner(signingKeyPath, mode)
+				check(err)
+				attLog = nil
+				g, err = newLocalGenerator(ctx, parsedSubjects)
+			case gitlab.IsGitlabCI():
+				g, err = newGitlabGenerator(parsedSubjects, clients, allowSelfHosted)
+			default:
+				g, err = newGithubGenerator(parsedSubjects, clients, redactionPolicyPath, allowSelfHosted)
 			}
+			check(err)
 
 			p, err := g.Generate(ctx)
 			check(err)
 
-			// Note: the path is validated within CreateNewFileUnderCurrentDirectory().
-			var attBytes []byte
-			if utils.IsPresubmitTests() {
-				attBytes, err = json.Marshal(p)
-				check(err)
-			} else {
-				att, err := signer.Sign(ctx, &intoto.Statement{
-					StatementHeader: p.StatementHeader,
-					Predicate:       p.Predicate,
-				})
-				check(err)
+			attBytes, logEntry, err := common.Attest(ctx, mode, p, attSigner, attLog)
+			check(err)
 
-				_, err = tlog.Upload(ctx, att)
+			if githubAttestation {
+				bundle, err := sigstore.NewBundle(attBytes, logEntry)
 				check(err)
-
-				attBytes = att.Bytes()
+				id, err := common.UploadGithubAttestation(ctx, provider, bundle)
+				check(err)
+				check(github.SetOutput(common.AttestationIDOutput, strconv.FormatInt(id, 10)))
 			}
 
+			// Note: the path is validated within CreateNewFileUnderCurrentDirectory().
 			f, err := utils.CreateNewFileUnderCurrentDirectory(attPath, os.O_WRONLY)
 			check(err)
 
@@ -127,6 +143,10 @@ run in the context of a Github Actions workflow.`,
 			check(err)
 
 			// Print the provenance name and sha256 so it can be used by the workflow.
+			if localBuild || gitlab.IsGitlabCI() {
+				// Local builds and GitLab jobs use the file directly.
+				return
+			}
 			check(github.SetOutput("provenance-name", attPath))
 			check(github.SetOutput("provenance-sha256", fmt.Sprintf("%x", sha256.Sum256(attBytes))))
 		},
@@ -140,5 +160,90 @@ run in the context of a Github Actions workflow.`,
 		&subjectsFilename, "subjects-filename", "f", "",
 		"Filename containing a formatted list of subjects in the same format as sha256sum (base64 encoded).",
 	)
+	c.Flags().BoolVar(
+		&allowSelfHosted, "allow-self-hosted-runners", false,
+		"Allow generating provenance on self-hosted runners. The provenance uses a self-hosted builder ID.",
+	)
+	c.Flags().StringVar(
+		&redactionPolicyPath, "redaction-policy", "",
+		"Path to a JSON redaction policy for the event payload and vars context. (default: built-in policy)",
+	)
+	common.AddLocalFlags(c, &localBuild, &signingKeyPath)
+	c.Flags().BoolVar(
+		&githubAttestation, "github-attestation", false,
+		"Upload the signed provenance to the attestations of the repository.",
+	)
+	common.AddSigningModeFlag(c, &signingMode)
 	return c
 }
+
+// newLocalGenerator returns a provenance generator for a local build in the
+// git working tree of the current directory.
+func newLocalGenerator(ctx context.Context, subjects []intoto.Subject) (slsa.Generator, error) {
+	git, err := common.ReadGitState(ctx, ".")
+	if err != nil {
+		return nil, err
+	}
+	return slsa.NewLocalGenerator(slsa.NewLocalBuild(subjects, git)), nil
+}
+
+// newGithubGenerator returns a provenance generator for the current GitHub
+// Actions workflow run.
+func newGithubGenerator(subjects []intoto.Subject, provider slsa.ClientProvider,
+	redactionPolicyPath string, allowSelfHosted bool,
+) (slsa.Generator, error) {
+	ghContext, err := github.GetWorkflowContext()
+	if err != nil {
+		return nil, err
+	}
+
+	varsContext, err := github.GetVarsContext()
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := common.LoadRedactionPolicy(redactionPolicyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b := common.GenericBuild{
+		GithubActionsBuild: slsa.NewGithubActionsBuild(subjects, &ghContext, varsContext).
+			WithRedactionPolicy(policy),
+		BuildTypeURI: provenanceOnlyBuildType,
+	}
+	if provider != nil {
+		b.WithClients(provider)
+	}
+
+	g := slsa.NewHostedActionsGenerator(&b)
+	if allowSelfHosted {
+		g.WithSelfHostedRunners(github.GetRunnerContext())
+	}
+	if provider != nil {
+		g.WithClients(provider)
+	}
+	return g, nil
+}
+
+// newGitlabGenerator returns a provenance generator for the current GitLab
+// CI/CD job. The provider is used if it also provides GitLab clients.
+func newGitlabGenerator(subjects []intoto.Subject, provider slsa.ClientProvider,
+	allowSelfHosted bool,
+) (slsa.Generator, error) {
+	ciContext, err := gitlab.GetCIContext()
+	if err != nil {
+		return nil, err
+	}
+
+	b := slsa.NewGitlabCIBuild(subjects, &ciContext)
+	g := slsa.NewGitlabCIGenerator(b)
+	if p, ok := provider.(slsa.GitlabClientProvider); ok {
+		b.WithClients(p)
+		g.WithClients(p)
+	}
+	if allowSelfHosted {
+		g.WithSelfHostedRunners()
+	}
+	return g, nil
+}
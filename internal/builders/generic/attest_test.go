@@ -16,19 +16,34 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"crypto/elliptic"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
 
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
 	"github.com/Kong/slsa-github-generator/internal/testutil"
 	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/signing"
 	"github.com/Kong/slsa-github-generator/slsa"
 )
 
@@ -505,3 +520,304 @@ func Test_attestCmd_subdirectory_artifact(t *testing.T) {
 		t.Errorf("error checking file: %v", err)
 	}
 }
+
+// recordingSigner is a Signer that returns the statement as JSON.
+type recordingSigner struct{}
+
+// Sign implements Signer.Sign.
+func (recordingSigner) Sign(_ context.Context, s *intoto.Statement) (signing.Attestation, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return &testutil.TestAttestation{BytesVal: b}, nil
+}
+
+// SignPayload implements Signer.SignPayload.
+func (recordingSigner) SignPayload(_ context.Context, b []byte) (signing.Attestation, error) {
+	return &testutil.TestAttestation{BytesVal: b}, nil
+}
+
+func Test_attestCmd_gitlab(t *testing.T) {
+	t.Setenv("GITLAB_CI", "true")
+	t.Setenv("CI_SERVER_URL", "https://gitlab.com")
+	t.Setenv("CI_PROJECT_PATH", "group/project")
+	t.Setenv("CI_CONFIG_PATH", ".gitlab-ci.yml")
+	t.Setenv("CI_COMMIT_SHA", "abcde")
+	t.Setenv("CI_COMMIT_REF_NAME", "main")
+	t.Setenv("CI_PIPELINE_ID", "574")
+	t.Setenv("CI_JOB_ID", "302")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	c := attestCmd(&slsa.NilClientProvider{}, checkTest(t), recordingSigner{}, &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--subjects-filename", fn,
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("artifact1.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+	var got struct {
+		Predicate struct {
+			BuildType string `json:"buildType"`
+			Builder   struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+			Materials []slsacommon.ProvenanceMaterial `json:"materials"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if want, got := slsa.GitlabCIBuildType, got.Predicate.BuildType; want != got {
+		t.Errorf("unexpected build type, want: %q, got: %q", want, got)
+	}
+	if want, got := slsa.GitlabCIBuilderID, got.Predicate.Builder.ID; want != got {
+		t.Errorf("unexpected builder ID, want: %q, got: %q", want, got)
+	}
+	expected := []slsacommon.ProvenanceMaterial{
+		{
+			URI:    "git+https://gitlab.com/group/project@refs/heads/main",
+			Digest: slsacommon.DigestSet{"sha1": "abcde"},
+		},
+	}
+	if diff := cmp.Diff(expected, got.Predicate.Materials); diff != "" {
+		t.Errorf("unexpected materials (-want +got):\n%s", diff)
+	}
+}
+
+func Test_attestCmd_local(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	// Change to a temporary git working tree.
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false",
+			"commit", "--quiet", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	t.Setenv(common.SigningKeyPasswordEnvKey, "hunter2")
+	privPEM, pubPEM, err := cryptoutils.GeneratePEMEncodedECDSAKeyPair(elliptic.P256(),
+		cryptoutils.StaticPasswordFunc([]byte("hunter2")))
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, privPEM, 0o600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	c := attestCmd(nil, checkTest(t), &testutil.TestSigner{}, nil)
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--local",
+		"--signing-key", keyPath,
+		"--subjects-filename", fn,
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("artifact1.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+
+	// Verify the envelope with the public key.
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("UnmarshalPEMToPublicKey: %v", err)
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("LoadVerifier: %v", err)
+	}
+	if err := dsse.WrapVerifier(v).VerifySignature(bytes.NewReader(b), nil); err != nil {
+		t.Fatalf("verifying attestation: %v", err)
+	}
+
+	var env struct {
+		Payload []byte `json:"payload"`
+	}
+	if err := json.Unmarshal(b, &env); err != nil {
+		t.Fatalf("unmarshaling envelope: %v", err)
+	}
+	var got intoto.ProvenanceStatement
+	if err := json.Unmarshal(env.Payload, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if want, got := slsa.LocalBuilderID, got.Predicate.Builder.ID; want != got {
+		t.Errorf("unexpected builder ID, want: %q, got: %q", want, got)
+	}
+	if want, got := slsa.LocalBuildType, got.Predicate.BuildType; want != got {
+		t.Errorf("unexpected build type, want: %q, got: %q", want, got)
+	}
+}
+
+func Test_attestCmd_unsigned(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	// No clients are given, so the command must not request an OIDC token,
+	// and signing or uploading fails the test.
+	c := attestCmd(nil, checkTest(t), nil, &testutil.TransparencyLogWithErr{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--signing-mode", "unsigned",
+		"--subjects-filename", fn,
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("artifact1.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+	var got intoto.ProvenanceStatement
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if want, got := slsa02.PredicateSLSAProvenance, got.PredicateType; want != got {
+		t.Errorf("unexpected predicate type, want: %q, got: %q", want, got)
+	}
+	if want, got := "artifact1", got.Subject[0].Name; want != got {
+		t.Errorf("unexpected subject, want: %q, got: %q", want, got)
+	}
+}
+
+func Test_attestCmd_githubAttestation(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", `{"repository": "owner/repo", "run_id": "1"}`)
+	t.Setenv("VARS_CONTEXT", "{}")
+	outputPath := filepath.Join(t.TempDir(), "output")
+	if err := os.WriteFile(outputPath, nil, 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", outputPath)
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	s := github.NewTestAttestationsServer(t)
+	s.HandleFunc("/repos/owner/repo/actions/runs/1", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"id": 1, "workflow_id": 2}`)
+	})
+	s.HandleFunc("/repos/owner/repo/actions/workflows/2", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{"id": 2, "path": ".github/workflows/release.yml"}`)
+	})
+	c := attestCmd(&testutil.GithubClientProvider{Client: s.GithubClient()}, checkTest(t),
+		testutil.NewCertSigner(t), &testutil.TestTransparencyLog{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--github-attestation",
+		"--subjects-filename", fn,
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	bundles := s.Bundles("owner/repo")
+	if len(bundles) != 1 {
+		t.Fatalf("unexpected bundles: %s", bundles)
+	}
+	var bundle struct {
+		DSSEEnvelope struct {
+			Payload []byte `json:"payload"`
+		} `json:"dsseEnvelope"`
+	}
+	if err := json.Unmarshal(bundles[0], &bundle); err != nil {
+		t.Fatalf("unmarshaling bundle: %v", err)
+	}
+	var got intoto.ProvenanceStatement
+	if err := json.Unmarshal(bundle.DSSEEnvelope.Payload, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if want, got := "artifact1", got.Subject[0].Name; want != got {
+		t.Errorf("unexpected subject, want: %q, got: %q", want, got)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if !strings.Contains(string(output), "attestation-id=1\n") {
+		t.Errorf("unexpected output: %q", output)
+	}
+}
@@ -20,6 +20,8 @@ import (
 	// TODO: Allow use of other OIDC providers?
 	// Enable the github OIDC auth provider.
 	_ "github.com/sigstore/cosign/v2/pkg/providers/github"
+	// Enable reading ID tokens from SIGSTORE_ID_TOKEN, e.g. on GitLab.
+	_ "github.com/sigstore/cosign/v2/pkg/providers/envvar"
 	"github.com/Kong/slsa-github-generator/signing/sigstore"
 
 	"github.com/spf13/cobra"
@@ -37,6 +39,8 @@ For more information on SLSA, visit https://slsa.dev`,
 	}
 	c.AddCommand(versionCmd())
 	c.AddCommand(attestCmd(nil, checkExit, sigstore.NewDefaultFulcio(), sigstore.NewDefaultRekor()))
+	c.AddCommand(oidcCmd(checkExit))
+	c.AddCommand(publishCmd(nil, checkExit))
 	return c
 }
 
@@ -0,0 +1,114 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+)
+
+// errInvalidTime indicates an invalid verification time.
+var errInvalidTime = errors.New("invalid time")
+
+// oidcCmd returns the 'oidc' command.
+func oidcCmd(check func(error)) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "oidc",
+		Short: "Work with GitHub Actions OIDC tokens",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return errors.New("expected command")
+		},
+	}
+	c.AddCommand(oidcInspectCmd(check))
+	return c
+}
+
+// oidcInspectCmd returns the 'oidc inspect' command.
+func oidcInspectCmd(check func(error)) *cobra.Command {
+	var jwksPath string
+	var issuer string
+	var audience []string
+	var at string
+
+	c := &cobra.Command{
+		Use:   "inspect TOKEN_FILE",
+		Short: "Verify a GitHub Actions OIDC token offline and print its claims",
+		Long: `Verify the signature, issuer, expiry and claims of a GitHub Actions OIDC token
+using a JSON Web Key Set file and print the claims as JSON. Nothing is fetched
+from the network. Use "-" as TOKEN_FILE to read the token from stdin.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			raw, err := readToken(cmd.InOrStdin(), args[0])
+			check(err)
+
+			jwksBytes, err := utils.SafeReadFile(jwksPath)
+			check(err)
+			keys, err := github.ParseJWKS(jwksBytes)
+			check(err)
+
+			client, err := github.NewStaticOIDCClient(issuer, keys)
+			check(err)
+
+			if at != "" {
+				t, err := time.Parse(time.RFC3339, at)
+				if err != nil {
+					check(fmt.Errorf("%w: %w", errInvalidTime, err))
+				}
+				client.WithClock(func() time.Time { return t })
+			}
+
+			token, err := client.VerifyToken(context.Background(), raw, audience)
+			check(err)
+
+			e := json.NewEncoder(cmd.OutOrStdout())
+			e.SetIndent("", "  ")
+			check(e.Encode(token))
+		},
+	}
+
+	c.Flags().StringVar(&jwksPath, "jwks", "", "Path to the JSON Web Key Set of the issuer.")
+	c.Flags().StringVar(&issuer, "issuer", github.ActionsIssuer, "The expected issuer of the token.")
+	c.Flags().StringSliceVar(&audience, "audience", nil, "The expected audience of the token. (default: any audience)")
+	c.Flags().StringVar(&at, "at", "",
+		"Verify the token's expiry at this RFC 3339 time instead of now, e.g. to replay a saved token.")
+	check(c.MarkFlagRequired("jwks"))
+
+	return c
+}
+
+// readToken reads a raw token from the file at path, or from r if path is "-".
+func readToken(r io.Reader, path string) (string, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = io.ReadAll(r)
+	} else {
+		b, err = utils.SafeReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading token: %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Kong/slsa-github-generator/github"
+)
+
+func Test_oidcInspectCmd(t *testing.T) {
+	expiry := time.Date(2023, 4, 14, 13, 0, 0, 0, time.UTC)
+	token := &github.OIDCToken{
+		Audience:          []string{"hoge"},
+		Expiry:            expiry,
+		JobWorkflowRef:    "owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0",
+		RepositoryID:      "1234",
+		RepositoryOwnerID: "4321",
+		ActorID:           "4567",
+		Repository:        "owner/repo",
+	}
+	raw, keys := github.NewTestSignedToken(t, github.ActionsIssuer, token)
+
+	// SafeReadFile only reads files under the current directory.
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	jwks, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	if err := os.WriteFile("jwks.json", jwks, 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	if err := os.WriteFile("token", []byte(raw+"\n"), 0o600); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+	}{
+		{
+			name: "file",
+			args: []string{"--jwks", "jwks.json", "--at", "2023-04-14T12:00:00Z", "token"},
+		},
+		{
+			name:  "stdin",
+			stdin: raw,
+			args:  []string{"--jwks", "jwks.json", "--audience", "hoge", "--at", "2023-04-14T12:00:00Z", "-"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			c := oidcInspectCmd(checkTest(t))
+			c.SetOut(out)
+			c.SetIn(strings.NewReader(tt.stdin))
+			c.SetArgs(tt.args)
+			if err := c.Execute(); err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			var got github.OIDCToken
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatalf("unmarshaling output: %v", err)
+			}
+			want := *token
+			want.Issuer = github.ActionsIssuer
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected token (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+// publishCmd returns the 'publish' command.
+func publishCmd(provider slsa.ClientProvider, check func(error)) *cobra.Command {
+	var tag string
+	var repository string
+
+	c := &cobra.Command{
+		Use:   "publish FILE...",
+		Short: "Attach attestations to a GitHub release",
+		Long: `Attach attestation files to the GitHub release of a tag, creating the release
+if it doesn't exist. Files must have the .intoto.jsonl extension and be under
+the current directory.
+
+Publishing is idempotent: a file is skipped if the release already has an asset
+of the same name and sha256 digest, and fails if the digest differs. Each
+uploaded asset is downloaded again to verify its digest. The repository
+defaults to the repository of the Github Actions workflow.`,
+		Args: cobra.MinimumNArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if tag == "" {
+				check(errors.New("--tag is required"))
+			}
+			for _, path := range args {
+				check(utils.VerifyAttestationPath(path))
+			}
+
+			if repository == "" {
+				ghContext, err := github.GetWorkflowContext()
+				check(err)
+				repository = ghContext.Repository
+			}
+
+			ctx := context.Background()
+			p := provider
+			if p == nil {
+				p = &slsa.DefaultClientProvider{}
+			}
+			ghClient, err := p.GithubClient(ctx)
+			check(err)
+			rc, err := github.NewReleaseClient(ghClient, repository)
+			check(err)
+
+			release, err := rc.FindOrCreateRelease(ctx, tag)
+			check(err)
+
+			for _, path := range args {
+				uploaded, err := rc.UploadAsset(ctx, release, path)
+				check(err)
+				if uploaded {
+					fmt.Fprintf(cmd.OutOrStdout(), "Uploaded %s to release %s.\n", filepath.Base(path), tag)
+				} else {
+					fmt.Fprintf(cmd.OutOrStdout(), "Skipped %s: release %s already has it.\n", filepath.Base(path), tag)
+				}
+			}
+		},
+	}
+
+	c.Flags().StringVar(&tag, "tag", "", "Tag of the release to publish to.")
+	c.Flags().StringVar(&repository, "repository", "",
+		"Repository of the release, as owner/name. (default: the repository of the workflow)")
+
+	return c
+}
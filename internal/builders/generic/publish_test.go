@@ -0,0 +1,143 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+)
+
+func Test_publishCmd(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", `{"repository": "owner/repo"}`)
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	files := map[string][]byte{
+		"artifact1.intoto.jsonl": []byte("provenance1"),
+		"artifact2.intoto.jsonl": []byte("provenance2"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(name, content, 0o600); err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+	}
+
+	s := github.NewTestReleasesServer(t, "owner/repo")
+	s.AddAsset("v1.0.0", "artifact1.intoto.jsonl", files["artifact1.intoto.jsonl"])
+	provider := &testutil.GithubClientProvider{Client: s.GithubClient()}
+
+	// Publishing again doesn't upload anything.
+	for i, uploads := range []int{1, 1} {
+		c := publishCmd(provider, checkTest(t))
+		c.SetOut(new(bytes.Buffer))
+		c.SetArgs([]string{"--tag", "v1.0.0", "artifact1.intoto.jsonl", "artifact2.intoto.jsonl"})
+		if err := c.Execute(); err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		if want, got := uploads, s.Uploads(); want != got {
+			t.Errorf("run %d: unexpected uploads, want: %d, got: %d", i, want, got)
+		}
+	}
+	if diff := cmp.Diff(files, s.Assets("v1.0.0")); diff != "" {
+		t.Errorf("unexpected assets (-want +got):\n%s", diff)
+	}
+}
+
+func Test_publishCmd_errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{
+			name: "conflicting asset",
+			args: []string{"--tag", "v1.0.0", "artifact.intoto.jsonl"},
+			err:  github.ErrAssetConflict,
+		},
+		{
+			name: "not an attestation",
+			args: []string{"--tag", "v1.0.0", "artifact"},
+			err:  utils.ErrInvalidPath,
+		},
+		{
+			name: "invalid repository",
+			args: []string{"--tag", "v1.0.0", "--repository", "repo", "artifact.intoto.jsonl"},
+			err:  github.ErrInvalidRepository,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Change to temporary dir
+			currentDir, err := os.Getwd()
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			if err := os.Chdir(t.TempDir()); err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			defer func() {
+				if err := os.Chdir(currentDir); err != nil {
+					t.Errorf("unexpected failure: %v", err)
+				}
+			}()
+			if err := os.WriteFile("artifact.intoto.jsonl", []byte("provenance"), 0o600); err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			s := github.NewTestReleasesServer(t, "owner/repo")
+			s.AddAsset("v1.0.0", "artifact.intoto.jsonl", []byte("other"))
+
+			check := func(err error) {
+				if err != nil {
+					if !errors.Is(err, tc.err) {
+						t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+					}
+					// Check should exit the program so we skip the rest of the test if we got the expected error.
+					t.SkipNow()
+				}
+			}
+			c := publishCmd(&testutil.GithubClientProvider{Client: s.GithubClient()}, check)
+			c.SetOut(new(bytes.Buffer))
+			c.SetArgs(append([]string{"--repository", "owner/repo"}, tc.args...))
+			if err := c.Execute(); err != nil {
+				t.Errorf("unexpected failure: %v", err)
+			}
+
+			// If no error occurs we catch it here.
+			t.Errorf("expected an error to occur.")
+		})
+	}
+}
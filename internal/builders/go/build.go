@@ -0,0 +1,165 @@
+// Copyright 2022 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/internal/builders/go/pkg"
+	"github.com/Kong/slsa-github-generator/internal/runner"
+)
+
+// buildCmd returns the 'build' command.
+func buildCmd(check func(error)) *cobra.Command {
+	var dry bool
+	var opts buildOptions
+	var output string
+
+	c := &cobra.Command{
+		Use:   "build [--dry] [--reproducible] CONFIG_FILE [EVALUATED_ENVS]",
+		Short: "Build a Go project using a builder configuration file",
+		Long: `Build a Go project using a builder configuration file. EVALUATED_ENVS is a
+comma-separated list of 'NAME:value' pairs used to resolve variables in the
+configuration file.
+
+A dry run resolves the command, env variables, working directory and binary
+name without invoking the compiler. These are written as GitHub Actions step
+outputs, or printed to stdout as JSON with '--output json'.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile := args[0]
+			var evalEnvs string
+			if len(args) > 1 {
+				evalEnvs = args[1]
+			}
+
+			switch output {
+			case outputGithub:
+				check(runBuild(dry, opts, configFile, evalEnvs))
+			case outputJSON:
+				if !dry {
+					check(fmt.Errorf("%w: %q requires --dry", errOutputFormat, output))
+				}
+				info, err := resolveBuild(opts, configFile, evalEnvs)
+				check(err)
+				check(writeJSON(cmd.OutOrStdout(), info))
+			default:
+				check(fmt.Errorf("%w: %q", errOutputFormat, output))
+			}
+		},
+	}
+
+	c.Flags().BoolVar(&dry, "dry", false, "Dry run of the build without invoking the compiler.")
+	c.Flags().BoolVar(&opts.reproducible, "reproducible", false, "Build twice and fail if the binaries differ.")
+	c.Flags().BoolVar(&opts.hermetic, "hermetic", true,
+		"Run the compiler without inheriting the environment, except for PATH, HOME, TMPDIR and Go toolchain variables.")
+	c.Flags().BoolVar(&opts.sandbox, "sandbox", false,
+		"Run the compiler in a Linux sandbox with a read-only source directory and no network access.")
+	c.Flags().StringVar(&opts.recordsPath, "step-records", "",
+		"Path to write the execution records of the build steps to as JSON, including the steps that failed.")
+	c.Flags().StringVar(&output, "output", outputGithub,
+		fmt.Sprintf("Output format for dry runs: %q or %q.", outputGithub, outputJSON))
+
+	return c
+}
+
+// buildOptions are the command-line options of a build.
+type buildOptions struct {
+	// reproducible enables the reproducible build check.
+	reproducible bool
+
+	// hermetic runs the compiler without inheriting the environment.
+	hermetic bool
+
+	// sandbox runs the compiler in a sandbox.
+	sandbox bool
+
+	// recordsPath is the file the execution records of the build steps are
+	// written to, if any.
+	recordsPath string
+}
+
+// newGoBuild creates a GoBuild from the configuration file and the evaluated
+// env variables.
+func newGoBuild(opts buildOptions, configFile, evalEnvs string) (*pkg.GoBuild, *pkg.GoReleaserConfig, error) {
+	goc, err := exec.LookPath("go")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := pkg.ConfigFromFile(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gobuild := pkg.GoBuildNew(goc, cfg)
+	gobuild.SetReproducible(opts.reproducible)
+	gobuild.SetHermetic(opts.hermetic)
+	gobuild.SetSandbox(opts.sandbox)
+
+	// Set env variables encoded as arguments.
+	if err := gobuild.SetArgEnvVariables(evalEnvs); err != nil {
+		return nil, nil, err
+	}
+
+	return gobuild, cfg, nil
+}
+
+func runBuild(dry bool, opts buildOptions, configFile, evalEnvs string) error {
+	gobuild, cfg, err := newGoBuild(opts, configFile, evalEnvs)
+	if err != nil {
+		return err
+	}
+	fmt.Println(cfg)
+
+	err = gobuild.Run(dry)
+	if opts.recordsPath != "" && !dry {
+		// Write the records even if the build failed, to help debugging.
+		if werr := writeRecords(opts.recordsPath, gobuild.Records()); werr != nil {
+			return errors.Join(err, werr)
+		}
+	}
+	return err
+}
+
+// writeRecords writes the execution records of the build steps to the file as
+// JSON.
+func writeRecords(path string, records []*runner.StepRecord) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := writeJSON(f, records); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// resolveBuild returns the resolved build information without invoking the
+// compiler.
+func resolveBuild(opts buildOptions, configFile, evalEnvs string) (*pkg.BuildInfo, error) {
+	gobuild, _, err := newGoBuild(opts, configFile, evalEnvs)
+	if err != nil {
+		return nil, err
+	}
+
+	return gobuild.Resolve()
+}
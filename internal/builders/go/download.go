@@ -0,0 +1,48 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+)
+
+// downloadCmd returns the 'download' command.
+func downloadCmd(check func(error)) *cobra.Command {
+	c := &cobra.Command{
+		Use:   "download CONFIG_FILE [EVALUATED_ENVS]",
+		Short: "Download the dependencies of a Go project before it is built",
+		Long: `Download the dependencies of a Go project using a builder configuration file.
+Dependencies are vendored with 'go mod vendor', or the module cache is
+populated with 'go mod download' if the module mode is 'readonly'. The command
+is run in the working directory of the build and retried on failure.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(_ *cobra.Command, args []string) {
+			configFile := args[0]
+			var evalEnvs string
+			if len(args) > 1 {
+				evalEnvs = args[1]
+			}
+
+			gobuild, _, err := newGoBuild(buildOptions{}, configFile, evalEnvs)
+			check(err)
+
+			check(gobuild.Download(context.Background()))
+		},
+	}
+
+	return c
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/internal/builders/go/pkg"
+)
+
+// inspectResult is the result of the 'inspect-config' command.
+type inspectResult struct {
+	// Config is the parsed configuration file.
+	Config *pkg.GoReleaserConfig `json:"config"`
+
+	// Build is the build information resolved from the configuration.
+	Build *pkg.BuildInfo `json:"build"`
+}
+
+// inspectConfigCmd returns the 'inspect-config' command.
+func inspectConfigCmd(check func(error)) *cobra.Command {
+	var opts buildOptions
+	var output string
+
+	c := &cobra.Command{
+		Use:   "inspect-config CONFIG_FILE [EVALUATED_ENVS]",
+		Short: "Validate a builder configuration file and print the resolved build",
+		Long: `Validate a builder configuration file and print the resolved command, env
+variables, working directory and binary name. The compiler is not invoked.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			configFile := args[0]
+			var evalEnvs string
+			if len(args) > 1 {
+				evalEnvs = args[1]
+			}
+
+			gobuild, cfg, err := newGoBuild(opts, configFile, evalEnvs)
+			check(err)
+
+			info, err := gobuild.Resolve()
+			check(err)
+
+			res := &inspectResult{
+				Config: cfg,
+				Build:  info,
+			}
+
+			switch output {
+			case outputText:
+				check(writeInspectText(cmd.OutOrStdout(), res))
+			case outputJSON:
+				check(writeJSON(cmd.OutOrStdout(), res))
+			default:
+				check(fmt.Errorf("%w: %q", errOutputFormat, output))
+			}
+		},
+	}
+
+	c.Flags().BoolVar(&opts.reproducible, "reproducible", false, "Resolve the build with reproducible build settings.")
+	c.Flags().BoolVar(&opts.hermetic, "hermetic", true, "Resolve the build without inheriting the environment.")
+	c.Flags().StringVar(&output, "output", outputText,
+		fmt.Sprintf("Output format: %q or %q.", outputText, outputJSON))
+
+	return c
+}
+
+func writeInspectText(w io.Writer, res *inspectResult) error {
+	_, err := fmt.Fprintf(w,
+		"binary:      %s\ncommand:     %s\nenv:         %s\nworking dir: %s\nmod mode:    %s\n",
+		res.Build.BinaryName,
+		strings.Join(res.Build.Command, " "),
+		strings.Join(res.Build.Env, " "),
+		res.Build.WorkingDir,
+		res.Build.ModMode,
+	)
+	if err != nil || res.Build.EffectiveEnv == nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "effective env: %s\n", strings.Join(res.Build.EffectiveEnv, " "))
+	return err
+}
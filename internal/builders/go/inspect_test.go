@@ -0,0 +1,73 @@
+// Copyright 2022 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_inspectConfigCmd(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+	}{
+		{
+			name:   "text",
+			output: "text",
+		},
+		{
+			name:   "json",
+			output: "json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			c := inspectConfigCmd(checkTest(t))
+			c.SetOut(out)
+			c.SetArgs([]string{
+				"--output", tt.output,
+				"./testdata/valid-main.yml", "VERSION_LDFLAGS:bla, ELSE:else",
+			})
+			if err := c.Execute(); err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			if tt.output == "text" {
+				if !strings.Contains(out.String(), "binary:      binary-linux-amd64\n") {
+					t.Errorf("unexpected output: %q", out.String())
+				}
+				return
+			}
+
+			var res inspectResult
+			if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+				t.Fatalf("unmarshaling output: %v", err)
+			}
+			if diff := cmp.Diff("binary-linux-amd64", res.Build.BinaryName); diff != "" {
+				t.Errorf("unexpected binary name (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("./path/to/main.go", *res.Config.Main); diff != "" {
+				t.Errorf("unexpected main (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -15,149 +15,66 @@
 package main
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
-	"flag"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 
-	"github.com/Kong/slsa-github-generator/github"
-	"github.com/Kong/slsa-github-generator/signing/sigstore"
+	"github.com/spf13/cobra"
 
 	// Enable the GitHub OIDC auth provider.
 	_ "github.com/sigstore/cosign/v2/pkg/providers/github"
-
-	"github.com/Kong/slsa-github-generator/internal/builders/go/pkg"
-	"github.com/Kong/slsa-github-generator/internal/utils"
 )
 
-func usage(p string) {
-	panic(fmt.Sprintf(`Usage:
-	 %s build [--dry] slsa-releaser.yml
-	 %s provenance --binary-name $NAME --digest $DIGEST --command $COMMAND --env $ENV`, p, p))
-}
-
-func check(e error) {
-	if e != nil {
-		fmt.Fprint(os.Stderr, e.Error())
-		os.Exit(1)
-	}
-}
-
-func runBuild(dry bool, configFile, evalEnvs string) error {
-	goc, err := exec.LookPath("go")
-	if err != nil {
-		return err
-	}
+const (
+	// outputGithub writes results as GitHub Actions step outputs.
+	outputGithub = "github"
 
-	cfg, err := pkg.ConfigFromFile(configFile)
-	if err != nil {
-		return err
-	}
-	fmt.Println(cfg)
+	// outputJSON writes results as JSON to stdout.
+	outputJSON = "json"
 
-	gobuild := pkg.GoBuildNew(goc, cfg)
+	// outputText writes results as human-readable text to stdout.
+	outputText = "text"
+)
 
-	// Set env variables encoded as arguments.
-	err = gobuild.SetArgEnvVariables(evalEnvs)
-	if err != nil {
-		return err
-	}
+// errOutputFormat indicates an unsupported output format.
+var errOutputFormat = errors.New("unsupported output format")
 
-	err = gobuild.Run(dry)
+func checkExit(err error) {
 	if err != nil {
-		return err
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
-
-	return nil
 }
 
-func runProvenanceGeneration(subject, digest, commands, envs, workingDir, rekor string) error {
-	r := sigstore.NewRekor(rekor)
-	s := sigstore.NewDefaultFulcio()
-	attBytes, err := pkg.GenerateProvenance(subject, digest,
-		commands, envs, workingDir, s, r, nil)
-	if err != nil {
-		return err
-	}
-
-	filename := fmt.Sprintf("%s.intoto.jsonl", subject)
-	f, err := utils.CreateNewFileUnderCurrentDirectory(filename, os.O_WRONLY)
-	if err != nil {
-		return err
-	}
-	_, err = f.Write(attBytes)
-	if err != nil {
-		return err
-	}
-
-	if err := github.SetOutput("signed-provenance-name", filename); err != nil {
-		return err
+func rootCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "slsa-builder-go",
+		Short: "Build Go projects and generate SLSA provenance on Github Actions",
+		Long: `Build Go projects and generate SLSA provenance on Github Actions.
+For more information on SLSA, visit https://slsa.dev`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return errors.New("expected command")
+		},
 	}
-
-	h, err := computeSHA256(attBytes)
-	if err != nil {
-		return err
-	}
-
-	return github.SetOutput("signed-provenance-sha256", h)
+	c.AddCommand(versionCmd())
+	c.AddCommand(buildCmd(checkExit))
+	c.AddCommand(downloadCmd(checkExit))
+	c.AddCommand(provenanceCmd(checkExit))
+	c.AddCommand(inspectConfigCmd(checkExit))
+	return c
 }
 
 func main() {
-	// Build command.
-	buildCmd := flag.NewFlagSet("build", flag.ExitOnError)
-	buildDry := buildCmd.Bool("dry", false, "dry run of the build without invoking compiler")
-
-	// Provenance command.
-	provenanceCmd := flag.NewFlagSet("provenance", flag.ExitOnError)
-	provenanceName := provenanceCmd.String("binary-name", "", "untrusted binary name of the artifact built")
-	provenanceDigest := provenanceCmd.String("digest", "", "sha256 digest of the untrusted binary")
-	provenanceCommand := provenanceCmd.String("command", "", "command used to compile the binary")
-	provenanceEnv := provenanceCmd.String("env", "", "env variables used to compile the binary")
-	provenanceWorkingDir := provenanceCmd.String("workingDir", "", "working directory used to issue compilation commands")
-	provenanceRekor := provenanceCmd.String("rekor", sigstore.DefaultRekorAddr, "rekor server to use for provenance")
-
-	// Expect a sub-command.
-	if len(os.Args) < 2 {
-		usage(os.Args[0])
-	}
-
-	switch os.Args[1] {
-	case buildCmd.Name():
-		check(buildCmd.Parse(os.Args[2:]))
-		if len(buildCmd.Args()) < 1 {
-			usage(os.Args[0])
-		}
-		configFile := buildCmd.Args()[0]
-		evaluatedEnvs := buildCmd.Args()[1]
-
-		check(runBuild(*buildDry, configFile, evaluatedEnvs))
-
-	case provenanceCmd.Name():
-		check(provenanceCmd.Parse(os.Args[2:]))
-		// Note: *provenanceEnv may be empty.
-		if *provenanceName == "" || *provenanceDigest == "" ||
-			*provenanceCommand == "" || *provenanceWorkingDir == "" {
-			usage(os.Args[0])
-		}
-
-		err := runProvenanceGeneration(*provenanceName, *provenanceDigest,
-			*provenanceCommand, *provenanceEnv, *provenanceWorkingDir, *provenanceRekor)
-		check(err)
-
-	default:
-		fmt.Println("expected 'build' or 'provenance' subcommands")
-		os.Exit(1)
-	}
+	checkExit(rootCmd().Execute())
 }
 
-func computeSHA256(data []byte) (string, error) {
-	hash := sha256.New()
-	if _, err := io.Copy(hash, bytes.NewReader(data)); err != nil {
-		return "", err
+func writeJSON(w io.Writer, obj any) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err := e.Encode(obj); err != nil {
+		return fmt.Errorf("encoding JSON: %w", err)
 	}
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return nil
 }
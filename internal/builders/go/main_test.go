@@ -16,11 +16,14 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -73,14 +76,15 @@ func errInvalidEnvironmentVariableFunc(t *testing.T, got error) {
 
 func Test_runBuild(t *testing.T) {
 	tests := []struct {
-		subject    string
-		name       string
-		config     string
-		evalEnvs   string
-		workingDir string
-		err        func(*testing.T, error)
-		commands   []string
-		envs       []string
+		subject      string
+		name         string
+		config       string
+		evalEnvs     string
+		workingDir   string
+		err          func(*testing.T, error)
+		commands     []string
+		envs         []string
+		reproducible bool
 	}{
 		{
 			name:     "two ldflags",
@@ -101,6 +105,28 @@ func Test_runBuild(t *testing.T) {
 				"CGO_ENABLED=0",
 			},
 		},
+		{
+			name:         "two ldflags reproducible",
+			subject:      "binary-linux-amd64",
+			config:       "./testdata/two-ldflags.yml",
+			evalEnvs:     "VERSION_LDFLAGS:bla, ELSE:else",
+			reproducible: true,
+			commands: []string{
+				"-trimpath",
+				"-tags=netgo",
+				"-buildvcs=false",
+				"-ldflags=bla something-else",
+				"-o",
+				"binary-linux-amd64",
+			},
+			envs: []string{
+				"GOOS=linux",
+				"GOARCH=amd64",
+				"GO111MODULE=on",
+				"CGO_ENABLED=0",
+				"SOURCE_DATE_EPOCH=0",
+			},
+		},
 		{
 			name:     "two ldflags empty env",
 			subject:  "binary-linux-amd64",
@@ -308,8 +334,9 @@ func Test_runBuild(t *testing.T) {
 			// http://craigwickesser.com/2015/01/capture-stdout-in-go/
 
 			t.Setenv("GITHUB_OUTPUT", file.Name())
+			t.Setenv("SOURCE_DATE_EPOCH", "")
 
-			err = runBuild(true,
+			err = runBuild(true, buildOptions{reproducible: tt.reproducible},
 				tt.config,
 				tt.evalEnvs)
 
@@ -405,3 +432,125 @@ func extract(file *os.File) ([]string, []string, string, string, error) {
 
 	return cmd, env, subject, wd, nil
 }
+
+func checkTest(t *testing.T) func(err error) {
+	return func(err error) {
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+	}
+}
+
+func Test_buildCmd_json(t *testing.T) {
+	goc, err := exec.LookPath("go")
+	if err != nil {
+		t.Fatalf("exec.LookPath: %v", err)
+	}
+
+	// Not inherited in hermetic mode.
+	t.Setenv("TESTVAR", "VALUE")
+
+	out := new(bytes.Buffer)
+	c := buildCmd(checkTest(t))
+	c.SetOut(out)
+	c.SetArgs([]string{
+		"--dry", "--output", "json",
+		"./testdata/two-ldflags.yml", "VERSION_LDFLAGS:bla, ELSE:else",
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	var info pkg.BuildInfo
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("unmarshaling output: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+
+	sorted := cmpopts.SortSlices(func(a, b string) bool { return a < b })
+	want := pkg.BuildInfo{
+		BinaryName: "binary-linux-amd64",
+		Command: []string{
+			goc, "build", "-mod=vendor",
+			"-trimpath",
+			"-tags=netgo",
+			"-ldflags=bla something-else",
+			"-o",
+			"binary-linux-amd64",
+		},
+		Env: []string{
+			"GOOS=linux",
+			"GOARCH=amd64",
+			"GO111MODULE=on",
+			"CGO_ENABLED=0",
+		},
+		WorkingDir: wd,
+		ModMode:    "vendor",
+	}
+	ignoreEffectiveEnv := cmpopts.IgnoreFields(pkg.BuildInfo{}, "EffectiveEnv")
+	if diff := cmp.Diff(want, info, sorted, ignoreEffectiveEnv); diff != "" {
+		t.Errorf("unexpected build info (-want +got):\n%s", diff)
+	}
+
+	for _, e := range want.Env {
+		if !slices.Contains(info.EffectiveEnv, e) {
+			t.Errorf("effective env does not contain %q: %v", e, info.EffectiveEnv)
+		}
+	}
+	if slices.Contains(info.EffectiveEnv, "TESTVAR=VALUE") {
+		t.Errorf("effective env contains the builder's env: %v", info.EffectiveEnv)
+	}
+}
+
+func Test_buildCmd_invalid_output(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{
+			name: "unknown format",
+			args: []string{"--dry", "--output", "yaml", "./testdata/two-ldflags.yml"},
+		},
+		{
+			name: "json without dry",
+			args: []string{"--output", "json", "./testdata/two-ldflags.yml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := func(err error) {
+				if err != nil {
+					if got, want := err, errOutputFormat; !errors.Is(got, want) {
+						t.Fatalf("unexpected error, got: %v, want %v", got, want)
+					}
+					// Check should exit the program so we skip the rest of the test if we got the expected error.
+					t.SkipNow()
+				}
+			}
+
+			c := buildCmd(check)
+			c.SetOut(new(bytes.Buffer))
+			c.SetArgs(tt.args)
+			if err := c.Execute(); err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+
+			t.Errorf("expected an error to occur.")
+		})
+	}
+}
+
+func Test_buildCmd_args(t *testing.T) {
+	c := buildCmd(checkTest(t))
+	c.SetOut(new(bytes.Buffer))
+	c.SetErr(new(bytes.Buffer))
+	c.SetArgs([]string{"--dry"})
+	if err := c.Execute(); err == nil {
+		t.Errorf("expected an error for missing config file argument")
+	}
+}
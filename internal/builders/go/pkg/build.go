@@ -16,12 +16,15 @@ package pkg
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Kong/slsa-github-generator/github"
 	"github.com/Kong/slsa-github-generator/internal/runner"
@@ -30,6 +33,22 @@ import (
 
 var unknownTag = "unknown"
 
+// dependencyPolicy is the execution policy of the commands fetching the
+// dependencies. They access the network, so failures are retried.
+var dependencyPolicy = &runner.StepPolicy{
+	Timeout: 10 * time.Minute,
+	Retries: 3,
+	Backoff: 5 * time.Second,
+}
+
+// Module download modes. In vendor mode, dependencies are vendored before
+// the build. In readonly mode, dependencies are resolved from a pre-populated
+// module cache with network access disabled.
+const (
+	modVendor   = "vendor"
+	modReadOnly = "readonly"
+)
+
 // See `go build help`.
 // `-asmflags`, `-n`, `-mod`, `-installsuffix`, `-modfile`,
 // `-workfile`, `-overlay`, `-pkgdir`, `-toolexec`, `-o`,
@@ -44,6 +63,14 @@ var allowedBuildArgs = map[string]bool{
 	"-tags": true, "-trimpath": true,
 }
 
+// hermeticEnvAllowList are the variables inherited from the builder's
+// environment by compilation commands in hermetic mode. They locate the
+// toolchain and the module and build caches.
+var hermeticEnvAllowList = []string{
+	"PATH", "HOME", "TMPDIR",
+	"GOROOT", "GOPATH", "GOMODCACHE", "GOCACHE", "GOTOOLCHAIN",
+}
+
 var allowedEnvVariablePrefix = map[string]bool{
 	"GO": true, "CGO_": true,
 }
@@ -54,6 +81,7 @@ var (
 	errInvalidEnvArgument        = errors.New("invalid env argument")
 	errEnvVariableNameNotAllowed = errors.New("invalid variable name")
 	errInvalidFilename           = errors.New("invalid filename")
+	errNotReproducible           = errors.New("build is not reproducible")
 )
 
 // GoBuild implements building a Go application.
@@ -62,42 +90,140 @@ type GoBuild struct {
 	// Note: static env variables are contained in cfg.Env.
 	argEnv map[string]string
 	goc    string
+	// reproducible enforces reproducible build settings and builds the
+	// binary twice to check that the outputs are identical.
+	reproducible bool
+	// hermetic runs commands without inheriting the builder's environment,
+	// except for the variables in hermeticEnvAllowList.
+	hermetic bool
+	// sandbox runs the compilation in a sandbox where the source directory
+	// is read-only and there is no network access.
+	sandbox bool
+	// now returns the current time. It is used to measure the build.
+	now func() time.Time
+	// records are the execution records of the steps run by the build.
+	records []*runner.StepRecord
 }
 
-// GoBuildNew returns a new GoBuild.
+// GoBuildNew returns a new GoBuild. Commands are run in hermetic mode by
+// default.
 func GoBuildNew(goc string, cfg *GoReleaserConfig) *GoBuild {
 	c := GoBuild{
-		cfg:    cfg,
-		goc:    goc,
-		argEnv: make(map[string]string),
+		cfg:      cfg,
+		goc:      goc,
+		argEnv:   make(map[string]string),
+		hermetic: true,
+		now:      time.Now,
 	}
 
 	return &c
 }
 
-// Run executes the build.
-func (b *GoBuild) Run(dry bool) error {
+// BuildInfo is the trusted information about a build, resolved before the
+// compiler is invoked.
+type BuildInfo struct {
+	// BinaryName is the resolved name of the binary.
+	BinaryName string `json:"binaryName"`
+
+	// Command is the compilation command.
+	Command []string `json:"command"`
+
+	// Env are the environment variables passed to the compilation command.
+	Env []string `json:"env"`
+
+	// WorkingDir is the directory the compilation command is run in.
+	WorkingDir string `json:"workingDir"`
+
+	// ModMode is the module download mode.
+	ModMode string `json:"modMode"`
+
+	// GoSumDigest is the sha256 digest of the go.sum file. It is only set in
+	// module mode.
+	GoSumDigest string `json:"goSumDigest,omitempty"`
+
+	// EffectiveEnv is the complete environment of the compilation command,
+	// with secrets redacted. It is only set in hermetic mode.
+	EffectiveEnv []string `json:"effectiveEnv,omitempty"`
+}
+
+// setOutputs shares the build information as GitHub Actions step outputs.
+func (i *BuildInfo) setOutputs() error {
+	menv, err := utils.MarshalToString(i.Env)
+	if err != nil {
+		return err
+	}
+	command, err := utils.MarshalToString(i.Command)
+	if err != nil {
+		return err
+	}
+
+	// Share the resolved name of the binary.
+	if err := github.SetOutput("go-binary-name", i.BinaryName); err != nil {
+		return err
+	}
+
+	// Share the command used.
+	if err := github.SetOutput("go-command", command); err != nil {
+		return err
+	}
+
+	// Share the env variables used.
+	if err := github.SetOutput("go-env", menv); err != nil {
+		return err
+	}
+
+	// Share the complete environment of the compiler in hermetic mode.
+	if len(i.EffectiveEnv) > 0 {
+		meffenv, err := utils.MarshalToString(i.EffectiveEnv)
+		if err != nil {
+			return err
+		}
+		if err := github.SetOutput("go-effective-env", meffenv); err != nil {
+			return err
+		}
+	}
+
+	// Share the module mode.
+	if err := github.SetOutput("go-mod-mode", i.ModMode); err != nil {
+		return err
+	}
+
+	// In module mode, share the digest of the go.sum file that the
+	// module cache is verified against.
+	if i.ModMode == modReadOnly {
+		if err := github.SetOutput("go-sum-sha256", i.GoSumDigest); err != nil {
+			return err
+		}
+	}
+
+	// Share working directory necessary for issuing the vendoring command.
+	return github.SetOutput("go-working-dir", i.WorkingDir)
+}
+
+// prepare resolves the working directory, compiler flags and env variables
+// for the build.
+func (b *GoBuild) prepare() (string, []string, []string, error) {
 	// Get directory.
 	dir, err := b.getDir()
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
 	// Set flags.
 	flags, err := b.generateFlags()
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
 
 	// Generate env variables.
 	envs, err := b.generateCommandEnvVariables()
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
 
 	// Generate ldflags.
 	ldflags, err := b.generateLdflags()
 	if err != nil {
-		return err
+		return "", nil, nil, err
 	}
 
 	// Add ldflags.
@@ -105,100 +231,297 @@ func (b *GoBuild) Run(dry bool) error {
 		flags = append(flags, fmt.Sprintf("-ldflags=%s", ldflags))
 	}
 
+	return dir, flags, envs, nil
+}
+
+// Resolve returns the information that is trusted, before the compiler is
+// invoked.
+func (b *GoBuild) Resolve() (*BuildInfo, error) {
+	dir, flags, envs, err := b.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate filename.
+	// Note: the filename uses the config file and is resolved if it contains env variables.
+	// `OUTPUT_BINARY` is only used during the actual compilation, an is a trusted
+	// variable hardcoded in the reusable workflow, to avoid weird looking name
+	// that may interfere with the compilation.
+	filename, err := b.generateOutputFilename()
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate the command.
+	com := b.generateCommand(flags, filename)
+
+	r := b.newRunner(nil, &runner.CommandStep{
+		Command:    com,
+		Env:        envs,
+		WorkingDir: dir,
+	})
+
+	steps, err := r.Dry(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	// There is a single command in steps given to the runner so we are
+	// assured to have only one step.
+	info := BuildInfo{
+		BinaryName:   filename,
+		Command:      steps[0].Command,
+		Env:          steps[0].Env,
+		WorkingDir:   dir,
+		ModMode:      b.modMode(),
+		EffectiveEnv: steps[0].EffectiveEnv,
+	}
+
+	if info.ModMode == modReadOnly {
+		info.GoSumDigest, err = goSumDigest(dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &info, nil
+}
+
+// Run executes the build.
+func (b *GoBuild) Run(dry bool) error {
 	// A dry run prints the information that is trusted, before
 	// the compiler is invoked.
 	if dry {
-		// Generate filename.
-		// Note: the filename uses the config file and is resolved if it contains env variables.
-		// `OUTPUT_BINARY` is only used during the actual compilation, an is a trusted
-		// variable hardcoded in the reusable workflow, to avoid weird looking name
-		// that may interfere with the compilation.
-		filename, err := b.generateOutputFilename()
+		info, err := b.Resolve()
 		if err != nil {
 			return err
 		}
+		return info.setOutputs()
+	}
 
-		// Generate the command.
-		com := b.generateCommand(flags, filename)
+	dir, flags, envs, err := b.prepare()
+	if err != nil {
+		return err
+	}
 
-		env, err := b.generateCommandEnvVariables()
-		if err != nil {
-			return err
-		}
+	binary, err := getOutputBinaryPath(os.Getenv("OUTPUT_BINARY"))
+	if err != nil {
+		return err
+	}
 
-		r := runner.CommandRunner{
-			Steps: []*runner.CommandStep{
-				{
-					Command:    com,
-					Env:        env,
-					WorkingDir: dir,
-				},
-			},
-		}
+	// Generate the command.
+	command := b.generateCommand(flags, binary)
 
-		steps, err := r.Dry()
-		if err != nil {
-			return err
-		}
+	fmt.Println("dir", dir)
+	fmt.Println("binary", binary)
+	fmt.Println("command", command)
+	fmt.Println("env", envs)
 
-		// There is a single command in steps given to the runner so we are
-		// assured to have only one step.
-		menv, err := utils.MarshalToString(steps[0].Env)
+	// TODO: Add a timeout?
+	ctx := context.Background()
+
+	// Measure the build, from the toolchain recording to the binary
+	// being written.
+	now := b.now
+	if now == nil {
+		now = time.Now
+	}
+	startedOn := now()
+
+	// Record the C toolchain used by cgo. It is hashed on the machine that
+	// performs the compilation.
+	if b.cfg.Cgo != nil {
+		materials, err := toolchainMaterials(b.cfg.Cgo)
 		if err != nil {
 			return err
 		}
-		command, err := utils.MarshalToString(steps[0].Command)
+		mmaterials, err := utils.MarshalToString(materials)
 		if err != nil {
 			return err
 		}
-
-		// Share the resolved name of the binary.
-		if err := github.SetOutput("go-binary-name", filename); err != nil {
+		if err := github.SetOutput("go-toolchains", mmaterials); err != nil {
 			return err
 		}
+	}
 
-		// Share the command used.
-		if err := github.SetOutput("go-command", command); err != nil {
+	if b.modMode() == modReadOnly {
+		// Verify the pre-populated module cache against go.sum before
+		// compiling.
+		r := b.newRunner(nil, &runner.CommandStep{
+			Command:    []string{b.goc, "mod", "verify"},
+			Env:        envs,
+			WorkingDir: dir,
+		})
+		if _, err := b.run(ctx, r); err != nil {
 			return err
 		}
+	}
 
-		// Share the env variables used.
-		if err := github.SetOutput("go-env", menv); err != nil {
-			return err
+	switch {
+	case b.reproducible:
+		err = b.runReproducible(ctx, flags, envs, dir, binary)
+	case b.sandbox:
+		// The source directory is read-only in the sandbox, so the binary
+		// is built in a temporary directory.
+		var output []byte
+		output, err = b.buildInTempDir(ctx, flags, envs, dir, binary)
+		if err == nil {
+			err = writeBinary(binary, output)
 		}
-
-		// Share working directory necessary for issuing the vendoring command.
-		return github.SetOutput("go-working-dir", dir)
+	default:
+		r := b.newRunner(nil, &runner.CommandStep{
+			Command:    command,
+			Env:        envs,
+			WorkingDir: dir,
+		})
+		_, err = b.run(ctx, r)
 	}
-
-	binary, err := getOutputBinaryPath(os.Getenv("OUTPUT_BINARY"))
 	if err != nil {
 		return err
 	}
 
-	// Generate the command.
-	command := b.generateCommand(flags, binary)
+	// Share the measured build times.
+	finishedOn := now()
+	if err := github.SetOutput("go-build-started-on", startedOn.UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	if err := github.SetOutput("go-build-finished-on", finishedOn.UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
 
-	fmt.Println("dir", dir)
-	fmt.Println("binary", binary)
-	fmt.Println("command", command)
-	fmt.Println("env", envs)
+	if b.reproducible {
+		// Share that the reproducibility check passed.
+		return github.SetOutput("go-reproducible", "true")
+	}
+	return nil
+}
+
+// Download fetches the dependencies of the build before it is run. In vendor
+// mode, dependencies are vendored. In readonly mode, the module cache is
+// populated and verified against go.sum by Run. No user-defined env is set for
+// the commands, which inherit the builder's environment.
+func (b *GoBuild) Download(ctx context.Context) error {
+	dir, err := b.getDir()
+	if err != nil {
+		return err
+	}
 
-	r := runner.CommandRunner{
+	command := []string{b.goc, "mod", "vendor"}
+	if b.modMode() == modReadOnly {
+		command = []string{b.goc, "mod", "download"}
+	}
+	r := &runner.CommandRunner{
 		Steps: []*runner.CommandStep{
 			{
 				Command:    command,
-				Env:        envs,
 				WorkingDir: dir,
+				Policy:     dependencyPolicy,
 			},
 		},
 	}
-
-	// TODO: Add a timeout?
-	_, err = r.Run(context.Background())
+	_, err = r.Run(ctx)
 	return err
 }
 
+// runReproducible builds the binary twice at the same time in separate
+// temporary directories, each with an empty build cache, and checks that both
+// builds produce the same binary. The binary is written to the binary path
+// only if the check passes.
+func (b *GoBuild) runReproducible(ctx context.Context, flags, envs []string, dir, binary string) error {
+	var outs [2]string
+	var steps []*runner.CommandStep
+	for i := range outs {
+		tmpDir, err := os.MkdirTemp("", "slsa-go-build-")
+		if err != nil {
+			return fmt.Errorf("creating temp dir: %w", err)
+		}
+		defer os.RemoveAll(tmpDir)
+
+		outs[i] = filepath.Join(tmpDir, filepath.Base(binary))
+		steps = append(steps, b.tempDirStep(flags, envs, dir, tmpDir, outs[i], true))
+	}
+
+	// The builds are independent, so they are run concurrently.
+	r := b.newRunner(nil, steps...)
+	r.Workers = len(steps)
+	if _, err := b.run(ctx, r); err != nil {
+		return err
+	}
+
+	var outputs [2][]byte
+	for i, out := range outs {
+		output, err := os.ReadFile(out)
+		if err != nil {
+			return fmt.Errorf("reading binary: %w", err)
+		}
+		outputs[i] = output
+	}
+
+	d1 := sha256.Sum256(outputs[0])
+	d2 := sha256.Sum256(outputs[1])
+	if d1 != d2 {
+		return fmt.Errorf("%w: sha256 digests differ: %x != %x", errNotReproducible, d1, d2)
+	}
+	fmt.Printf("reproducible build check passed: sha256:%x\n", d1)
+
+	return writeBinary(binary, outputs[0])
+}
+
+// buildInTempDir builds the binary in a new temporary directory and returns
+// its content.
+func (b *GoBuild) buildInTempDir(ctx context.Context, flags, envs []string, dir, binary string) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "slsa-go-build-")
+	if err != nil {
+		return nil, fmt.Errorf("creating temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	out := filepath.Join(tmpDir, filepath.Base(binary))
+	if _, err := b.run(ctx, b.newRunner(nil, b.tempDirStep(flags, envs, dir, tmpDir, out, false))); err != nil {
+		return nil, err
+	}
+
+	output, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("reading binary: %w", err)
+	}
+	return output, nil
+}
+
+// tempDirStep returns the step compiling the binary to out in the temporary
+// directory tmpDir. If freshCache is true, the build uses an empty build cache
+// in tmpDir.
+func (b *GoBuild) tempDirStep(flags, envs []string, dir, tmpDir, out string, freshCache bool) *runner.CommandStep {
+	step := &runner.CommandStep{
+		Command:    b.generateCommand(flags, out),
+		Env:        envs,
+		WorkingDir: dir,
+	}
+	if freshCache {
+		// Use a fresh build cache so that builds do not reuse each
+		// other's outputs.
+		step.Env = append(append([]string{}, envs...), fmt.Sprintf("GOCACHE=%s", filepath.Join(tmpDir, "cache")))
+	}
+	if b.sandbox {
+		// The build directory holds the sources, and is read-only so that
+		// the build cannot modify them.
+		step.Sandbox = &runner.Sandbox{
+			ReadOnlyPaths: []string{dir},
+			WritablePaths: []string{tmpDir},
+		}
+	}
+	return step
+}
+
+// writeBinary writes the binary to its output path.
+func writeBinary(binary string, content []byte) error {
+	// Match the permissions of binaries written by go build.
+	if err := os.WriteFile(binary, content, 0o755); err != nil {
+		return fmt.Errorf("writing binary: %w", err)
+	}
+	return nil
+}
+
 func getOutputBinaryPath(binary string) (string, error) {
 	// Use the name provider via env variable for the compilation.
 	// This variable is trusted and defined by the re-usable workflow.
@@ -264,12 +587,116 @@ func (b *GoBuild) generateCommandEnvVariables() ([]string, error) {
 			return env, fmt.Errorf("%w: %s", errEnvVariableNameNotAllowed, v)
 		}
 
+		// CGO_ENABLED is set with the cgo toolchain, and the sysroot flags
+		// are merged with the user-defined flags below.
+		if b.cfg.Cgo != nil && (k == "CGO_ENABLED" ||
+			(b.cfg.Cgo.Sysroot != "" && (k == "CGO_CFLAGS" || k == "CGO_LDFLAGS"))) {
+			continue
+		}
+
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
 
+	// Set the C toolchain for cgo.
+	env = append(env, b.generateCgoEnvVariables()...)
+
+	// In module mode, dependencies must come from the pre-populated module
+	// cache. These take precedence over any values set in the config file.
+	if b.modMode() == modReadOnly {
+		env = append(env, "GOPROXY=off", "GOFLAGS=-mod=readonly")
+	}
+
+	// Reproducible builds use a fixed timestamp for tools that embed one.
+	if b.reproducible {
+		epoch, err := getSourceDateEpoch()
+		if err != nil {
+			return env, err
+		}
+		env = append(env, fmt.Sprintf("SOURCE_DATE_EPOCH=%s", epoch))
+	}
+
 	return env, nil
 }
 
+// generateCgoEnvVariables returns the env variables that select the C
+// toolchain configured for cgo.
+func (b *GoBuild) generateCgoEnvVariables() []string {
+	cgo := b.cfg.Cgo
+	if cgo == nil {
+		return nil
+	}
+
+	env := []string{"CGO_ENABLED=1", fmt.Sprintf("CC=%s", cgo.CC)}
+	if cgo.CXX != "" {
+		env = append(env, fmt.Sprintf("CXX=%s", cgo.CXX))
+	}
+	if cgo.Sysroot != "" {
+		for _, k := range []string{"CGO_CFLAGS", "CGO_LDFLAGS"} {
+			v := fmt.Sprintf("--sysroot=%s", cgo.Sysroot)
+			if userFlags := b.cfg.Env[k]; userFlags != "" {
+				v = fmt.Sprintf("%s %s", v, userFlags)
+			}
+			env = append(env, fmt.Sprintf("%s=%s", k, v))
+		}
+	}
+	return env
+}
+
+// newRunner returns a runner for the steps with the global env variables env.
+func (b *GoBuild) newRunner(env []string, steps ...*runner.CommandStep) *runner.CommandRunner {
+	r := &runner.CommandRunner{
+		Env:   env,
+		Steps: steps,
+	}
+	if b.hermetic {
+		r.Hermetic = true
+		r.AllowEnv = hermeticEnvAllowList
+	}
+	return r
+}
+
+// run executes the steps of the runner and keeps their execution records. It
+// returns the steps that completed successfully, like runner.CommandRunner.Run.
+func (b *GoBuild) run(ctx context.Context, r *runner.CommandRunner) ([]*runner.CommandStep, error) {
+	records, err := r.RunRecords(ctx)
+	b.records = append(b.records, records...)
+
+	var steps []*runner.CommandStep
+	for _, rec := range records {
+		if rec.Status == runner.StatusSucceeded {
+			steps = append(steps, rec.Step)
+		}
+	}
+	return steps, err
+}
+
+// Records returns the execution records of the steps run by the build,
+// including the steps that failed or were cancelled.
+func (b *GoBuild) Records() []*runner.StepRecord {
+	return b.records
+}
+
+// SetHermetic sets whether commands inherit the builder's environment.
+func (b *GoBuild) SetHermetic(hermetic bool) {
+	b.hermetic = hermetic
+}
+
+// SetSandbox enables compiling in a sandbox.
+func (b *GoBuild) SetSandbox(sandbox bool) {
+	b.sandbox = sandbox
+}
+
+// SetClock overrides the function used to measure the build. This is useful
+// for tests that need deterministic timestamps.
+func (b *GoBuild) SetClock(now func() time.Time) {
+	b.now = now
+}
+
+// SetReproducible enables the reproducible build mode.
+func (b *GoBuild) SetReproducible(reproducible bool) {
+	b.reproducible = reproducible
+}
+
 // SetArgEnvVariables sets static environment variables.
 func (b *GoBuild) SetArgEnvVariables(envs string) error {
 	// Notes:
@@ -293,7 +720,7 @@ func (b *GoBuild) SetArgEnvVariables(envs string) error {
 		name := strings.Trim(sp[0], " ")
 		value := strings.Trim(sp[1], " ")
 
-		fmt.Printf("arg env: %s:%s\n", name, value)
+		fmt.Fprintf(os.Stderr, "arg env: %s:%s\n", name, value)
 		b.argEnv[name] = value
 	}
 	return nil
@@ -338,7 +765,7 @@ func (b *GoBuild) generateOutputFilename() (string, error) {
 
 func (b *GoBuild) generateFlags() ([]string, error) {
 	// -x
-	flags := []string{b.goc, "build", "-mod=vendor"}
+	flags := []string{b.goc, "build", fmt.Sprintf("-mod=%s", b.modMode())}
 
 	for _, v := range b.cfg.Flags {
 		if !isAllowedArg(v) {
@@ -346,9 +773,71 @@ func (b *GoBuild) generateFlags() ([]string, error) {
 		}
 		flags = append(flags, v)
 	}
+
+	if b.reproducible {
+		return addReproducibleFlags(flags)
+	}
 	return flags, nil
 }
 
+// addReproducibleFlags adds the -trimpath and -buildvcs=false flags if they
+// are not already present. VCS stamping is disabled because it embeds the
+// state of the working tree.
+func addReproducibleFlags(flags []string) ([]string, error) {
+	var trimpath, buildvcs bool
+	for _, v := range flags {
+		switch {
+		case v == "-trimpath" || v == "-trimpath=true":
+			trimpath = true
+		case v == "-buildvcs=false":
+			buildvcs = true
+		case strings.HasPrefix(v, "-trimpath"), strings.HasPrefix(v, "-buildvcs"):
+			return nil, fmt.Errorf("%w: %s not allowed for reproducible builds", errUnsupportedArguments, v)
+		}
+	}
+	if !trimpath {
+		flags = append(flags, "-trimpath")
+	}
+	if !buildvcs {
+		flags = append(flags, "-buildvcs=false")
+	}
+	return flags, nil
+}
+
+// getSourceDateEpoch returns the SOURCE_DATE_EPOCH set by the workflow, or 0
+// if it is not set.
+func getSourceDateEpoch() (string, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return "0", nil
+	}
+	if _, err := strconv.ParseUint(epoch, 10, 64); err != nil {
+		return "", fmt.Errorf("%w: SOURCE_DATE_EPOCH: %q", errInvalidEnvArgument, epoch)
+	}
+	return epoch, nil
+}
+
+func (b *GoBuild) modMode() string {
+	if b.cfg.Mod == "" {
+		return modVendor
+	}
+	return b.cfg.Mod
+}
+
+// goSumDigest returns the hex-encoded sha256 digest of the go.sum file in dir.
+// An empty digest is returned if the module has no go.sum file, i.e., it has
+// no dependencies.
+func goSumDigest(dir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading go.sum: %w", err)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+}
+
 func isAllowedArg(arg string) bool {
 	for k := range allowedBuildArgs {
 		if strings.HasPrefix(arg, k) {
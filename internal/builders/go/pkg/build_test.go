@@ -15,14 +15,18 @@
 package pkg
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/Kong/slsa-github-generator/internal/runner"
 )
 
 func errEnvVariableNameEmptyFunc(t *testing.T, got error) {
@@ -627,12 +631,55 @@ func Test_generateEnvVariables(t *testing.T) {
 		name     string
 		goos     string
 		goarch   string
+		mod      string
 		env      []string
+		cgo      *cgoConfigFile
 		expected struct {
 			err   func(*testing.T, error)
 			flags []string
 		}
 	}{
+		{
+			name:   "cgo with sysroot",
+			goos:   "linux",
+			goarch: "arm64",
+			env:    []string{"CGO_ENABLED=1", "CGO_CFLAGS=-O2"},
+			cgo: &cgoConfigFile{
+				CC:      "/usr/bin/aarch64-linux-gnu-gcc",
+				Sysroot: "/usr/aarch64-linux-gnu",
+			},
+			expected: struct {
+				err   func(*testing.T, error)
+				flags []string
+			}{
+				flags: []string{
+					"GOOS=linux", "GOARCH=arm64",
+					"CGO_ENABLED=1",
+					"CC=/usr/bin/aarch64-linux-gnu-gcc",
+					"CGO_CFLAGS=--sysroot=/usr/aarch64-linux-gnu -O2",
+					"CGO_LDFLAGS=--sysroot=/usr/aarch64-linux-gnu",
+				},
+				err: nil,
+			},
+		},
+		{
+			name:   "readonly mode",
+			goos:   "linux",
+			goarch: "x86",
+			mod:    "readonly",
+			env:    []string{"GOPROXY=https://proxy.golang.org"},
+			expected: struct {
+				err   func(*testing.T, error)
+				flags []string
+			}{
+				flags: []string{
+					"GOOS=linux", "GOARCH=x86",
+					"GOPROXY=https://proxy.golang.org",
+					"GOPROXY=off", "GOFLAGS=-mod=readonly",
+				},
+				err: nil,
+			},
+		},
 		{
 			name:   "empty flags",
 			goos:   "linux",
@@ -707,6 +754,8 @@ func Test_generateEnvVariables(t *testing.T) {
 				Goos:    tt.goos,
 				Goarch:  tt.goarch,
 				Env:     tt.env,
+				Mod:     tt.mod,
+				Cgo:     tt.cgo,
 			}
 			c, err := fromConfig(&cfg)
 			if err != nil {
@@ -909,6 +958,7 @@ func Test_generateFlags(t *testing.T) {
 
 	tests := []struct {
 		name  string
+		mod   string
 		err   func(*testing.T, error)
 		flags []string
 	}{
@@ -917,6 +967,12 @@ func Test_generateFlags(t *testing.T) {
 			flags: []string{"-race", "-x"},
 			err:   nil,
 		},
+		{
+			name:  "valid flags readonly mode",
+			mod:   "readonly",
+			flags: []string{"-race", "-x"},
+			err:   nil,
+		},
 		{
 			name:  "invalid -mod flags",
 			flags: []string{"-mod=whatever", "-x"},
@@ -953,6 +1009,7 @@ func Test_generateFlags(t *testing.T) {
 			cfg := goReleaserConfigFile{
 				Version: 1,
 				Flags:   tt.flags,
+				Mod:     tt.mod,
 			}
 			c, err := fromConfig(&cfg)
 			if err != nil {
@@ -960,8 +1017,13 @@ func Test_generateFlags(t *testing.T) {
 			}
 			b := GoBuildNew("gocompiler", c)
 
+			mod := "-mod=vendor"
+			if tt.mod != "" {
+				mod = "-mod=" + tt.mod
+			}
+
 			flags, err := b.generateFlags()
-			expectedFlags := append([]string{"gocompiler", "build", "-mod=vendor"}, tt.flags...)
+			expectedFlags := append([]string{"gocompiler", "build", mod}, tt.flags...)
 
 			if tt.err != nil {
 				tt.err(t, err)
@@ -978,6 +1040,100 @@ func Test_generateFlags(t *testing.T) {
 	}
 }
 
+func Test_addReproducibleFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      func(*testing.T, error)
+		flags    []string
+		expected []string
+	}{
+		{
+			name:     "no flags",
+			expected: []string{"-trimpath", "-buildvcs=false"},
+		},
+		{
+			name:     "flags already set",
+			flags:    []string{"-buildvcs=false", "-x", "-trimpath"},
+			expected: []string{"-buildvcs=false", "-x", "-trimpath"},
+		},
+		{
+			name:     "trimpath set",
+			flags:    []string{"-trimpath=true", "-tags=netgo"},
+			expected: []string{"-trimpath=true", "-tags=netgo", "-buildvcs=false"},
+		},
+		{
+			name:  "vcs stamping enabled",
+			flags: []string{"-buildvcs=true"},
+			err:   errUnsupportedArgumentsFunc,
+		},
+		{
+			name:  "trimpath disabled",
+			flags: []string{"-trimpath=false"},
+			err:   errUnsupportedArgumentsFunc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			flags, err := addReproducibleFlags(tt.flags)
+			if tt.err != nil {
+				tt.err(t, err)
+			}
+			if err != nil {
+				return
+			}
+			if !cmp.Equal(flags, tt.expected) {
+				t.Error(cmp.Diff(flags, tt.expected))
+			}
+		})
+	}
+}
+
+func Test_getSourceDateEpoch(t *testing.T) {
+	tests := []struct {
+		name     string
+		epoch    string
+		err      func(*testing.T, error)
+		expected string
+	}{
+		{
+			name:     "not set",
+			expected: "0",
+		},
+		{
+			name:     "set",
+			epoch:    "1700000000",
+			expected: "1700000000",
+		},
+		{
+			name:  "invalid",
+			epoch: "yesterday",
+			err:   errInvalidEnvArgumentFunc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+
+			epoch, err := getSourceDateEpoch()
+			if tt.err != nil {
+				tt.err(t, err)
+			}
+			if err != nil {
+				return
+			}
+			if !cmp.Equal(epoch, tt.expected) {
+				t.Error(cmp.Diff(epoch, tt.expected))
+			}
+		})
+	}
+}
+
 func Test_generateCommand(t *testing.T) {
 	t.Parallel()
 
@@ -1058,11 +1214,31 @@ func asPointer(s string) *string {
 	return &s
 }
 
+// skipIfNoSandbox skips the test if the runner's sandbox is not supported on
+// the host, e.g. if unprivileged user namespaces are disabled.
+func skipIfNoSandbox(t *testing.T) {
+	t.Helper()
+
+	r := runner.CommandRunner{
+		Steps: []*runner.CommandStep{
+			{
+				Command: []string{"true"},
+				Sandbox: &runner.Sandbox{},
+			},
+		},
+	}
+	if _, err := r.Run(context.Background()); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+}
+
 func TestGoBuild_Run(t *testing.T) {
 	type fields struct {
-		cfg    *GoReleaserConfig
-		argEnv map[string]string
-		goc    string
+		cfg          *GoReleaserConfig
+		argEnv       map[string]string
+		goc          string
+		reproducible bool
+		sandbox      bool
 	}
 	type args struct {
 		dry bool
@@ -1110,6 +1286,54 @@ func TestGoBuild_Run(t *testing.T) {
 				dry: false,
 			},
 		},
+		{
+			name: "non-dry valid flags readonly mode",
+			fields: fields{
+				cfg: &GoReleaserConfig{
+					Goos:   "linux",
+					Goarch: "amd64",
+					Binary: "/tmp/binary-readonly",
+					Main:   asPointer("main.go"),
+					Dir:    asPointer("./testdata/go"),
+					Mod:    "readonly",
+				},
+			},
+			args: args{
+				dry: false,
+			},
+		},
+		{
+			name: "non-dry reproducible",
+			fields: fields{
+				cfg: &GoReleaserConfig{
+					Goos:   "linux",
+					Goarch: "amd64",
+					Binary: "/tmp/binary-reproducible",
+					Main:   asPointer("main.go"),
+					Dir:    asPointer("./testdata/go"),
+				},
+				reproducible: true,
+			},
+			args: args{
+				dry: false,
+			},
+		},
+		{
+			name: "non-dry sandbox",
+			fields: fields{
+				cfg: &GoReleaserConfig{
+					Goos:   "linux",
+					Goarch: "amd64",
+					Binary: "/tmp/binary-sandbox",
+					Main:   asPointer("main.go"),
+					Dir:    asPointer("./testdata/go"),
+				},
+				sandbox: true,
+			},
+			args: args{
+				dry: false,
+			},
+		},
 		{
 			name: "slash in the binary name",
 			fields: fields{
@@ -1147,10 +1371,15 @@ func TestGoBuild_Run(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if tt.fields.sandbox {
+				skipIfNoSandbox(t)
+			}
 			b := &GoBuild{
-				cfg:    tt.fields.cfg,
-				goc:    tt.fields.goc,
-				argEnv: tt.fields.argEnv,
+				cfg:          tt.fields.cfg,
+				goc:          tt.fields.goc,
+				argEnv:       tt.fields.argEnv,
+				reproducible: tt.fields.reproducible,
+				sandbox:      tt.fields.sandbox,
 			}
 			t.Setenv("OUTPUT_BINARY", tt.fields.cfg.Binary)
 			// if the test is not dry run , then code has to look for golang binary
@@ -1175,3 +1404,139 @@ func TestGoBuild_Run(t *testing.T) {
 		})
 	}
 }
+
+func TestGoBuild_Download(t *testing.T) {
+	goc, err := exec.LookPath("go")
+	if err != nil {
+		t.Fatalf("exec.LookPath: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mod    string
+		vendor bool
+	}{
+		{
+			name:   "vendor mode",
+			vendor: true,
+		},
+		{
+			name: "readonly mode",
+			mod:  modReadOnly,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A module with a local dependency, so that no network access
+			// is needed.
+			dir := t.TempDir()
+			files := map[string]string{
+				"go.mod":     "module example.com/app\n\ngo 1.21\n\nrequire example.com/dep v0.0.0\n\nreplace example.com/dep => ./dep\n",
+				"main.go":    "package main\n\nimport _ \"example.com/dep\"\n\nfunc main() {}\n",
+				"dep/go.mod": "module example.com/dep\n\ngo 1.21\n",
+				"dep/dep.go": "package dep\n",
+			}
+			for name, content := range files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatalf("unexpected failure: %v", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+					t.Fatalf("unexpected failure: %v", err)
+				}
+			}
+
+			b := GoBuildNew(goc, &GoReleaserConfig{
+				Dir: asPointer(dir),
+				Mod: tt.mod,
+			})
+			if err := b.Download(context.Background()); err != nil {
+				t.Fatalf("Download: %v", err)
+			}
+
+			_, err := os.Stat(filepath.Join(dir, "vendor", "modules.txt"))
+			if got := err == nil; got != tt.vendor {
+				t.Errorf("unexpected vendor directory, want: %t, got: %t (%v)", tt.vendor, got, err)
+			}
+		})
+	}
+}
+
+func TestGoBuild_buildInTempDir_sandbox(t *testing.T) {
+	skipIfNoSandbox(t)
+
+	goc, err := exec.LookPath("go")
+	if err != nil {
+		t.Fatalf("exec.LookPath: %v", err)
+	}
+	b := GoBuildNew(goc, &GoReleaserConfig{
+		Goos:   "linux",
+		Goarch: "amd64",
+		Binary: "binary",
+		Main:   asPointer("main.go"),
+		Dir:    asPointer("./testdata/go"),
+	})
+	b.sandbox = true
+
+	dir, flags, envs, err := b.prepare()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	// The build directory, not the current directory, is mounted read-only.
+	if _, err := b.buildInTempDir(context.Background(), flags, envs, dir, "binary"); err != nil {
+		t.Fatalf("buildInTempDir: %v", err)
+	}
+	want, err := filepath.Abs("./testdata/go")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	records := b.Records()
+	if len(records) != 1 {
+		t.Fatalf("unexpected number of records, want: 1, got: %d", len(records))
+	}
+	if diff := cmp.Diff([]string{want}, records[0].Step.Sandbox.ReadOnlyPaths); diff != "" {
+		t.Errorf("unexpected read-only paths (-want +got):\n%s", diff)
+	}
+}
+
+func TestGoBuild_Records(t *testing.T) {
+	goc, err := exec.LookPath("go")
+	if err != nil {
+		t.Fatalf("exec.LookPath: %v", err)
+	}
+	t.Setenv("GITHUB_OUTPUT", "")
+	t.Setenv("OUTPUT_BINARY", filepath.Join(t.TempDir(), "binary"))
+
+	b := GoBuildNew(goc, &GoReleaserConfig{
+		Goos:   "linux",
+		Goarch: "amd64",
+		Binary: "binary",
+		Main:   asPointer("main.go"),
+		Dir:    asPointer("./testdata/go"),
+		Mod:    "readonly",
+	})
+	if err := b.Run(false); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	// The module cache is verified before the compilation.
+	records := b.Records()
+	if want, got := 2, len(records); want != got {
+		t.Fatalf("unexpected number of records, want: %d, got: %d", want, got)
+	}
+	if want, got := []string{goc, "mod", "verify"}, records[0].Step.Command; !cmp.Equal(want, got) {
+		t.Errorf("unexpected command (-want +got):\n%s", cmp.Diff(want, got))
+	}
+	for _, rec := range records {
+		if rec.Status != runner.StatusSucceeded || rec.ExitCode != 0 {
+			t.Errorf("unexpected status %q and exit code %d", rec.Status, rec.ExitCode)
+		}
+		if rec.Stdout == nil || rec.Stderr == nil {
+			t.Errorf("missing log records: %v, %v", rec.Stdout, rec.Stderr)
+		}
+		if rec.FinishedOn.Before(rec.StartedOn) {
+			t.Errorf("unexpected timestamps: %v, %v", rec.StartedOn, rec.FinishedOn)
+		}
+	}
+}
@@ -30,28 +30,63 @@ var supportedVersions = map[int]bool{
 	1: true,
 }
 
+var supportedModModes = map[string]bool{
+	"": true, modVendor: true, modReadOnly: true,
+}
+
+// Directories that C compilers and sysroots must live under. Toolchains
+// installed by the system package manager or in the runner's tool cache
+// are allowed.
+var (
+	allowedCompilerDirs = []string{"/usr/bin", "/usr/local/bin", "/opt"}
+	allowedSysrootDirs  = []string{"/usr", "/opt"}
+)
+
+type cgoConfigFile struct {
+	CC      string `yaml:"cc"`
+	CXX     string `yaml:"cxx"`
+	Sysroot string `yaml:"sysroot"`
+}
+
 type goReleaserConfigFile struct {
-	Main    *string  `yaml:"main"`
-	Dir     *string  `yaml:"dir"`
-	Goos    string   `yaml:"goos"`
-	Goarch  string   `yaml:"goarch"`
-	Binary  string   `yaml:"binary"`
-	Env     []string `yaml:"env"`
-	Flags   []string `yaml:"flags"`
-	Ldflags []string `yaml:"ldflags"`
-	Version int      `yaml:"version"`
+	Main    *string        `yaml:"main"`
+	Dir     *string        `yaml:"dir"`
+	Goos    string         `yaml:"goos"`
+	Goarch  string         `yaml:"goarch"`
+	Binary  string         `yaml:"binary"`
+	Env     []string       `yaml:"env"`
+	Flags   []string       `yaml:"flags"`
+	Ldflags []string       `yaml:"ldflags"`
+	Mod     string         `yaml:"mod"`
+	Cgo     *cgoConfigFile `yaml:"cgo"`
+	Version int            `yaml:"version"`
 }
 
 // GoReleaserConfig tracks configuration for goreleaser.
 type GoReleaserConfig struct {
-	Env     map[string]string
-	Main    *string
-	Dir     *string
-	Goos    string
-	Goarch  string
-	Binary  string
-	Flags   []string
-	Ldflags []string
+	Env     map[string]string `json:"env,omitempty"`
+	Main    *string           `json:"main,omitempty"`
+	Dir     *string           `json:"dir,omitempty"`
+	Goos    string            `json:"goos"`
+	Goarch  string            `json:"goarch"`
+	Binary  string            `json:"binary"`
+	Flags   []string          `json:"flags,omitempty"`
+	Ldflags []string          `json:"ldflags,omitempty"`
+	// Mod is the module download mode. An empty value means "vendor".
+	Mod string `json:"mod,omitempty"`
+	// Cgo is the C toolchain configuration. If nil, cgo is not configured
+	// by the builder.
+	Cgo *CgoConfig `json:"cgo,omitempty"`
+}
+
+// CgoConfig is the C toolchain used to compile cgo code for the target.
+type CgoConfig struct {
+	// CC is the absolute path to the C compiler.
+	CC string `json:"cc"`
+	// CXX is the absolute path to the C++ compiler. It is optional.
+	CXX string `json:"cxx,omitempty"`
+	// Sysroot is the absolute path to the target's sysroot. It is optional.
+	Sysroot string `json:"sysroot,omitempty"`
 }
 
 var (
@@ -63,6 +98,12 @@ var (
 
 	// ErrInvalidEnvironmentVariable indicates  an invalid environment variable.
 	ErrInvalidEnvironmentVariable = errors.New("invalid environment variable")
+
+	// ErrUnsupportedModMode indicates an unsupported module download mode.
+	ErrUnsupportedModMode = errors.New("unsupported mod mode")
+
+	// ErrInvalidToolchain indicates an invalid cgo toolchain configuration.
+	ErrInvalidToolchain = errors.New("invalid toolchain")
 )
 
 func configFromString(b []byte) (*GoReleaserConfig, error) {
@@ -106,6 +147,10 @@ func fromConfig(cf *goReleaserConfigFile) (*GoReleaserConfig, error) {
 		return nil, err
 	}
 
+	if err := validateModMode(cf); err != nil {
+		return nil, err
+	}
+
 	cfg := GoReleaserConfig{
 		Goos:    cf.Goos,
 		Goarch:  cf.Goarch,
@@ -114,12 +159,17 @@ func fromConfig(cf *goReleaserConfigFile) (*GoReleaserConfig, error) {
 		Binary:  cf.Binary,
 		Main:    cf.Main,
 		Dir:     cf.Dir,
+		Mod:     cf.Mod,
 	}
 
 	if err := cfg.setEnvs(cf); err != nil {
 		return nil, err
 	}
 
+	if err := cfg.setCgo(cf); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
@@ -170,6 +220,14 @@ func validateVersion(cf *goReleaserConfigFile) error {
 	return nil
 }
 
+func validateModMode(cf *goReleaserConfigFile) error {
+	if !supportedModModes[cf.Mod] {
+		return fmt.Errorf("%w: '%s'", ErrUnsupportedModMode, cf.Mod)
+	}
+
+	return nil
+}
+
 func (r *GoReleaserConfig) setEnvs(cf *goReleaserConfigFile) error {
 	m := make(map[string]string)
 	for _, e := range cf.Env {
@@ -186,3 +244,51 @@ func (r *GoReleaserConfig) setEnvs(cf *goReleaserConfigFile) error {
 
 	return nil
 }
+
+func (r *GoReleaserConfig) setCgo(cf *goReleaserConfigFile) error {
+	if cf.Cgo == nil {
+		return nil
+	}
+
+	if cf.Cgo.CC == "" {
+		return fmt.Errorf("%w: cc is empty", ErrInvalidToolchain)
+	}
+	if err := validateToolchainPath(cf.Cgo.CC, allowedCompilerDirs); err != nil {
+		return err
+	}
+	if cf.Cgo.CXX != "" {
+		if err := validateToolchainPath(cf.Cgo.CXX, allowedCompilerDirs); err != nil {
+			return err
+		}
+	}
+	if cf.Cgo.Sysroot != "" {
+		if err := validateToolchainPath(cf.Cgo.Sysroot, allowedSysrootDirs); err != nil {
+			return err
+		}
+	}
+
+	if v, ok := r.Env["CGO_ENABLED"]; ok && v != "1" {
+		return fmt.Errorf("%w: cgo is configured but CGO_ENABLED=%s", ErrInvalidToolchain, v)
+	}
+
+	r.Cgo = &CgoConfig{
+		CC:      cf.Cgo.CC,
+		CXX:     cf.Cgo.CXX,
+		Sysroot: cf.Cgo.Sysroot,
+	}
+	return nil
+}
+
+// validateToolchainPath checks that path is a clean absolute path under one
+// of the allowed directories.
+func validateToolchainPath(path string, allowedDirs []string) error {
+	if !filepath.IsAbs(path) || filepath.Clean(path) != path {
+		return fmt.Errorf("%w: %q is not a clean absolute path", ErrInvalidToolchain, path)
+	}
+	for _, dir := range allowedDirs {
+		if strings.HasPrefix(path, dir+"/") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not under %s", ErrInvalidToolchain, path, strings.Join(allowedDirs, ", "))
+}
@@ -43,6 +43,20 @@ func errInvalidEnvironmentVariableFunc(t *testing.T, got error) {
 	}
 }
 
+func errUnsupportedModModeFunc(t *testing.T, got error) {
+	want := ErrUnsupportedModMode
+	if !errors.Is(got, want) {
+		t.Fatalf("unexpected error: %v", cmp.Diff(got, want, cmpopts.EquateErrors()))
+	}
+}
+
+func errInvalidToolchainFunc(t *testing.T, got error) {
+	want := ErrInvalidToolchain
+	if !errors.Is(got, want) {
+		t.Fatalf("unexpected error: %v", cmp.Diff(got, want, cmpopts.EquateErrors()))
+	}
+}
+
 func Test_ConfigFromFile(t *testing.T) {
 	t.Parallel()
 
@@ -118,6 +132,51 @@ func Test_ConfigFromFile(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "valid mod readonly",
+			path: "./testdata/releaser-valid-mod-readonly.yml",
+			config: GoReleaserConfig{
+				Goos: "linux", Goarch: "amd64",
+				Flags:   []string{"-trimpath", "-tags=netgo"},
+				Ldflags: []string{"{{ .Env.VERSION_LDFLAGS }}"},
+				Binary:  "binary-{{ .OS }}-{{ .Arch }}",
+				Env: map[string]string{
+					"GO111MODULE": "on", "CGO_ENABLED": "0",
+				},
+				Mod: "readonly",
+			},
+		},
+		{
+			name: "invalid mod",
+			path: "./testdata/releaser-invalid-mod.yml",
+			err:  errUnsupportedModModeFunc,
+		},
+		{
+			name: "valid cgo",
+			path: "./testdata/releaser-valid-cgo.yml",
+			config: GoReleaserConfig{
+				Goos: "linux", Goarch: "arm64",
+				Binary: "binary-{{ .OS }}-{{ .Arch }}",
+				Env: map[string]string{
+					"CGO_ENABLED": "1", "CGO_CFLAGS": "-O2",
+				},
+				Cgo: &CgoConfig{
+					CC:      "/usr/bin/aarch64-linux-gnu-gcc",
+					CXX:     "/usr/bin/aarch64-linux-gnu-g++",
+					Sysroot: "/usr/aarch64-linux-gnu",
+				},
+			},
+		},
+		{
+			name: "cgo compiler not allowed",
+			path: "./testdata/releaser-invalid-cgo-path.yml",
+			err:  errInvalidToolchainFunc,
+		},
+		{
+			name: "cgo disabled",
+			path: "./testdata/releaser-invalid-cgo-disabled.yml",
+			err:  errInvalidToolchainFunc,
+		},
 		{
 			name: "missing version",
 			path: "./testdata/releaser-noversion.yml",
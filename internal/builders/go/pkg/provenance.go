@@ -17,18 +17,27 @@ package pkg
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/Kong/slsa-github-generator/signing"
 
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/runner"
 	"github.com/Kong/slsa-github-generator/internal/utils"
 	"github.com/Kong/slsa-github-generator/slsa"
 )
 
+var (
+	errInvalidBuildTime = errors.New("invalid build time")
+)
+
 const (
 	buildConfigVersion int = 1
 	buildType              = "https://github.com/Kong/slsa-github-generator/go@v1"
@@ -39,10 +48,23 @@ type (
 		WorkingDir string   `json:"workingDir"`
 		Command    []string `json:"command"`
 		Env        []string `json:"env"`
+		// EffectiveEnv is the complete environment of the step, with
+		// secrets redacted, as recorded by the runner in hermetic mode.
+		EffectiveEnv []string `json:"effectiveEnv,omitempty"`
+		// Sandbox is the isolation the step was run in, if any.
+		Sandbox *runner.Sandbox `json:"sandbox,omitempty"`
+	}
+	modules struct {
+		// Mode is the module download mode used for the build.
+		Mode string `json:"mode"`
+		// GoSumDigest is the digest of the go.sum file the module cache
+		// was verified against.
+		GoSumDigest slsacommon.DigestSet `json:"goSumDigest,omitempty"`
 	}
 	buildConfig struct {
-		Steps   []step `json:"steps"`
-		Version int    `json:"version"`
+		Steps   []step   `json:"steps"`
+		Modules *modules `json:"modules,omitempty"`
+		Version int      `json:"version"`
 	}
 )
 
@@ -61,83 +83,218 @@ func (b *goProvenanceBuild) BuildConfig(context.Context) (interface{}, error) {
 	return b.buildConfig, nil
 }
 
+// goLocalBuild is the build type of a local build. It records the Go build
+// config, but not the GitHub Actions environment.
+type goLocalBuild struct {
+	*slsa.LocalBuild
+	buildConfig buildConfig
+}
+
+// BuildConfig implements BuildType.BuildConfig.
+func (b *goLocalBuild) BuildConfig(context.Context) (interface{}, error) {
+	return b.buildConfig, nil
+}
+
+// ProvenanceOptions are the inputs of the provenance of a Go binary. The
+// values shared by the build steps are untrusted and are validated by
+// GenerateProvenance.
+type ProvenanceOptions struct {
+	// Name is the name of the binary.
+	Name string
+
+	// Digest is the hex-encoded sha256 digest of the binary.
+	Digest string
+
+	// Command is the base64-encoded compilation command.
+	Command string
+
+	// Env is the base64-encoded list of env variables passed to the
+	// compilation command.
+	Env string
+
+	// EffectiveEnv is the base64-encoded environment of the compilation
+	// command recorded by the runner in hermetic mode. It may be empty.
+	EffectiveEnv string
+
+	// WorkingDir is the directory the compilation command is run in.
+	WorkingDir string
+
+	// GoSumDigest is the hex-encoded sha256 digest of the go.sum file. It is
+	// only used for builds in module mode.
+	GoSumDigest string
+
+	// Toolchains is the base64-encoded list of cgo toolchain materials
+	// recorded by the build step.
+	Toolchains string
+
+	// Sandbox must only be set if the compilation was run in a sandbox.
+	Sandbox bool
+
+	// StartedOn and FinishedOn are the RFC 3339 timestamps measured by the
+	// build step. They may be empty.
+	StartedOn  string
+	FinishedOn string
+
+	// Reproducible must only be set if the build passed the reproducibility
+	// check.
+	Reproducible bool
+
+	// AllowSelfHosted allows generating provenance on self-hosted runners.
+	// The provenance then uses a self-hosted builder ID.
+	AllowSelfHosted bool
+
+	// Local generates provenance for a local build from the git working
+	// tree of WorkingDir, instead of the GitHub Actions workflow run.
+	Local bool
+
+	// Redaction is the policy applied to the event payload and the `vars`
+	// context.
+	Redaction *slsa.RedactionPolicy
+
+	// Mode is the signing mode of the provenance.
+	Mode common.SigningMode
+}
+
 // GenerateProvenance translates github context into a SLSA provenance
-// attestation.
+// attestation for the binary described by opts. The log entry is nil if the
+// attestation was not uploaded to the transparency log.
 // Spec: https://slsa.dev/provenance/v0.2
-func GenerateProvenance(name, digest, command, envs, workingDir string,
-	s signing.Signer, r signing.TransparencyLog, provider slsa.ClientProvider,
-) ([]byte, error) {
-	gh, err := github.GetWorkflowContext()
+func GenerateProvenance(opts *ProvenanceOptions, s signing.Signer, r signing.TransparencyLog,
+	provider slsa.ClientProvider,
+) ([]byte, signing.LogEntry, error) {
+	if _, err := hex.DecodeString(opts.Digest); err != nil || len(opts.Digest) != 64 {
+		return nil, nil, fmt.Errorf("sha256 digest is not valid: %s", opts.Digest)
+	}
+
+	com, err := utils.UnmarshalList(opts.Command)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if _, err := hex.DecodeString(digest); err != nil || len(digest) != 64 {
-		return nil, fmt.Errorf("sha256 digest is not valid: %s", digest)
+	env, err := utils.UnmarshalList(opts.Env)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	com, err := utils.UnmarshalList(command)
+	effEnv, err := utils.UnmarshalList(opts.EffectiveEnv)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if opts.GoSumDigest != "" {
+		if _, err := hex.DecodeString(opts.GoSumDigest); err != nil || len(opts.GoSumDigest) != 64 {
+			return nil, nil, fmt.Errorf("go.sum sha256 digest is not valid: %s", opts.GoSumDigest)
+		}
 	}
 
-	env, err := utils.UnmarshalList(envs)
+	toolchainMaterials, err := parseToolchainMaterials(opts.Toolchains)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var cmd []string
-	if len(com) > 0 {
-		cmd = []string{com[0], "mod", "vendor"}
+	started, err := parseBuildTime(opts.StartedOn)
+	if err != nil {
+		return nil, nil, err
+	}
+	finished, err := parseBuildTime(opts.FinishedOn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !started.IsZero() && !finished.IsZero() && finished.Before(started) {
+		return nil, nil, fmt.Errorf("%w: build finished on %s before it started on %s",
+			errInvalidBuildTime, opts.FinishedOn, opts.StartedOn)
 	}
 
-	b := goProvenanceBuild{
-		GithubActionsBuild: slsa.NewGithubActionsBuild([]intoto.Subject{
-			{
-				Name: name,
-				Digest: slsacommon.DigestSet{
-					"sha256": digest,
-				},
-			},
-		}, &gh, nil),
-		buildConfig: buildConfig{
-			Version: buildConfigVersion,
-			Steps: []step{
-				// Vendoring step.
-				{
-					// Note: vendoring and compilation are
-					// performed in the same VM, so the compiler is
-					// the same.
-					Command:    cmd,
-					WorkingDir: workingDir,
-					// Note: No user-defined env set for this step.
-				},
-				// Compilation step.
-				{
-					Command:    com,
-					Env:        env,
-					WorkingDir: workingDir,
-				},
+	steps, mods := dependencySteps(com, env, opts.WorkingDir, opts.GoSumDigest)
+	// Compilation step.
+	steps = append(steps, step{
+		Command:      com,
+		Env:          env,
+		EffectiveEnv: effEnv,
+		WorkingDir:   opts.WorkingDir,
+	})
+	if opts.Sandbox {
+		// The build directory is mounted read-only in the sandbox. The
+		// writable output directory is temporary and is not recorded.
+		steps[len(steps)-1].Sandbox = (&runner.Sandbox{
+			ReadOnlyPaths: []string{opts.WorkingDir},
+		}).Record()
+	}
+
+	subjects := []intoto.Subject{
+		{
+			Name: opts.Name,
+			Digest: slsacommon.DigestSet{
+				"sha256": opts.Digest,
 			},
 		},
 	}
-
-	// Pre-submit tests don't have access to write OIDC token.
-	if provider != nil {
-		b.WithClients(provider)
-	} else if utils.IsPresubmitTests() {
-		// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-		b.GithubActionsBuild.WithClients(&slsa.NilClientProvider{})
+	cfg := buildConfig{
+		Version: buildConfigVersion,
+		Steps:   steps,
+		Modules: mods,
 	}
 
 	ctx := context.Background()
+	var p *intoto.ProvenanceStatement
+	if opts.Local {
+		p, err = generateLocalProvenance(ctx, opts, subjects, cfg)
+	} else {
+		p, err = generateHostedProvenance(ctx, opts, subjects, cfg, started, finished, provider)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Add the C toolchain used by cgo to the materials.
+	p.Predicate.Materials = append(p.Predicate.Materials, toolchainMaterials...)
+
+	// The build was checked to produce bit-for-bit identical binaries.
+	if opts.Reproducible && p.Predicate.Metadata != nil {
+		p.Predicate.Metadata.Reproducible = true
+	}
+
+	// Sign the provenance and upload it to rekor, as allowed by the mode.
+	attBytes, logEntry, err := common.Attest(ctx, opts.Mode, p, s, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case opts.Mode == common.SigningModeUnsigned:
+		fmt.Fprintln(os.Stderr, "Signing disabled. Writing unsigned provenance.")
+	case logEntry == nil:
+		fmt.Fprintln(os.Stderr, "Transparency log upload disabled. Writing signed provenance.")
+	default:
+		fmt.Fprintf(os.Stderr, "Uploaded signed attestation to rekor with UUID %s.\n", logEntry.UUID())
+	}
+
+	return attBytes, logEntry, nil
+}
+
+// generateHostedProvenance generates the provenance of a build in the GitHub
+// Actions workflow run.
+func generateHostedProvenance(ctx context.Context, opts *ProvenanceOptions, subjects []intoto.Subject,
+	cfg buildConfig, started, finished time.Time, provider slsa.ClientProvider,
+) (*intoto.ProvenanceStatement, error) {
+	gh, err := github.GetWorkflowContext()
+	if err != nil {
+		return nil, err
+	}
+
+	b := goProvenanceBuild{
+		GithubActionsBuild: slsa.NewGithubActionsBuild(subjects, &gh, nil).
+			WithBuildTimes(started, finished).WithRedactionPolicy(opts.Redaction),
+		buildConfig: cfg,
+	}
+
 	g := slsa.NewHostedActionsGenerator(&b)
-	// Pre-submit tests don't have access to write OIDC token.
-	if provider != nil {
+	if opts.AllowSelfHosted {
+		g.WithSelfHostedRunners(github.GetRunnerContext())
+	}
+	if provider := opts.Mode.ClientProvider(provider); provider != nil {
+		b.WithClients(provider)
 		g.WithClients(provider)
-	} else if utils.IsPresubmitTests() {
-		// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-		g.WithClients(&slsa.NilClientProvider{})
 	}
 	p, err := g.Generate(ctx)
 	if err != nil {
@@ -167,27 +324,98 @@ func GenerateProvenance(name, digest, command, envs, workingDir string,
 	}
 	p.Predicate.Materials = append(p.Predicate.Materials, runnerMaterials)
 
-	if utils.IsPresubmitTests() {
-		fmt.Println("Pre-submit tests detected. Skipping signing.")
-		return utils.MarshalToBytes(*p)
-	}
+	return p, nil
+}
 
-	// Sign the provenance.
-	att, err := s.Sign(ctx, &intoto.Statement{
-		StatementHeader: p.StatementHeader,
-		Predicate:       p.Predicate,
-	})
+// generateLocalProvenance generates the provenance of a local build in the git
+// working tree of the build's working directory.
+func generateLocalProvenance(ctx context.Context, opts *ProvenanceOptions, subjects []intoto.Subject,
+	cfg buildConfig,
+) (*intoto.ProvenanceStatement, error) {
+	git, err := common.ReadGitState(ctx, opts.WorkingDir)
 	if err != nil {
 		return nil, err
 	}
 
-	// Upload the signed attestation to rekor.
-	logEntry, err := r.Upload(ctx, att)
+	b := goLocalBuild{
+		LocalBuild:  slsa.NewLocalBuild(subjects, git),
+		buildConfig: cfg,
+	}
+	return slsa.NewLocalGenerator(&b).Generate(ctx)
+}
+
+// parseBuildTime parses a build timestamp shared by the build step. It returns
+// the zero time if s is empty.
+func parseBuildTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
 	if err != nil {
-		return nil, err
+		return time.Time{}, fmt.Errorf("%w: %w", errInvalidBuildTime, err)
 	}
+	return t, nil
+}
 
-	fmt.Printf("Uploaded signed attestation to rekor with UUID %s.\n", logEntry.UUID())
+// dependencySteps returns the steps performed to fetch dependencies before
+// the compilation command com is run. The module mode is derived from the
+// trusted compilation command.
+func dependencySteps(com, env []string, workingDir, goSumDigest string) ([]step, *modules) {
+	// Note: fetching dependencies and compilation are performed in
+	// the same VM, so the compiler is the same.
+	var goc string
+	if len(com) > 0 {
+		goc = com[0]
+	}
 
-	return att.Bytes(), nil
+	if modModeFromCommand(com) != modReadOnly {
+		var cmd []string
+		if goc != "" {
+			cmd = []string{goc, "mod", "vendor"}
+		}
+		return []step{
+			// Vendoring step.
+			{
+				Command:    cmd,
+				WorkingDir: workingDir,
+				// Note: No user-defined env set for this step.
+			},
+		}, nil
+	}
+
+	mods := &modules{
+		Mode: modReadOnly,
+	}
+	if goSumDigest != "" {
+		mods.GoSumDigest = slsacommon.DigestSet{
+			"sha256": goSumDigest,
+		}
+	}
+
+	return []step{
+		// Module cache population step.
+		{
+			Command:    []string{goc, "mod", "download"},
+			WorkingDir: workingDir,
+			// Note: No user-defined env set for this step.
+		},
+		// Module cache verification step. This runs with the same env
+		// as the compilation step.
+		{
+			Command:    []string{goc, "mod", "verify"},
+			Env:        env,
+			WorkingDir: workingDir,
+		},
+	}, mods
+}
+
+// modModeFromCommand returns the module mode passed via -mod in the
+// compilation command.
+func modModeFromCommand(com []string) string {
+	for _, arg := range com {
+		if mode, found := strings.CutPrefix(arg, "-mod="); found {
+			return mode
+		}
+	}
+	return modVendor
 }
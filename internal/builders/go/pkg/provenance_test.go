@@ -15,25 +15,266 @@
 package pkg
 
 import (
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/runner"
 	"github.com/Kong/slsa-github-generator/internal/testutil"
+	"github.com/Kong/slsa-github-generator/internal/utils"
 	"github.com/Kong/slsa-github-generator/slsa"
 )
 
 func TestGenerateProvenance_withErr(t *testing.T) {
-	// Disable pre-submit detection.
-	// TODO(github.com/Kong/slsa-github-generator/issues/124): Remove
-	t.Setenv("GITHUB_EVENT_NAME", "non_event")
 	t.Setenv("GITHUB_CONTEXT", "{}")
 	t.Setenv("VARS_CONTEXT", "{}")
 	sha256 := "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2"
-	_, err := GenerateProvenance(
-		"foo", sha256, "", "", "/home/foo",
-		&testutil.TestSigner{}, &testutil.TransparencyLogWithErr{},
+	opts := &ProvenanceOptions{
+		Name:       "foo",
+		Digest:     sha256,
+		WorkingDir: "/home/foo",
+		Redaction:  slsa.DefaultRedactionPolicy,
+		Mode:       common.SigningModeSign,
+	}
+	_, _, err := GenerateProvenance(opts, &testutil.TestSigner{}, &testutil.TransparencyLogWithErr{},
 		&slsa.NilClientProvider{},
 	)
 	if want, got := testutil.ErrTransparencyLog, err; want != got {
 		t.Errorf("expected error, want: %v, got: %v", want, got)
 	}
 }
+
+func TestGenerateProvenance_effectiveEnv(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	sha256 := "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2"
+	com := []string{"/usr/bin/go", "build", "-mod=vendor", "-o", "binary"}
+	env := []string{"GOOS=linux", "GOARCH=amd64"}
+	effEnv := []string{"PATH=/usr/bin:/bin", "HOME=/home/foo", "GOOS=linux", "GOARCH=amd64"}
+
+	mcom, err := utils.MarshalToString(com)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	menv, err := utils.MarshalToString(env)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	meffEnv, err := utils.MarshalToString(effEnv)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	opts := &ProvenanceOptions{
+		Name:         "foo",
+		Digest:       sha256,
+		Command:      mcom,
+		Env:          menv,
+		EffectiveEnv: meffEnv,
+		WorkingDir:   "/home/foo",
+		Redaction:    slsa.DefaultRedactionPolicy,
+		Mode:         common.SigningModeUnsigned,
+	}
+	b, _, err := GenerateProvenance(opts, nil, &testutil.TransparencyLogWithErr{},
+		&slsa.NilClientProvider{},
+	)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	var got struct {
+		Predicate struct {
+			BuildConfig buildConfig `json:"buildConfig"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+
+	// Only the compilation step is run by the runner in hermetic mode.
+	want := []step{
+		{
+			Command:    []string{"/usr/bin/go", "mod", "vendor"},
+			WorkingDir: "/home/foo",
+		},
+		{
+			Command:      com,
+			Env:          env,
+			EffectiveEnv: effEnv,
+			WorkingDir:   "/home/foo",
+		},
+	}
+	if diff := cmp.Diff(want, got.Predicate.BuildConfig.Steps); diff != "" {
+		t.Errorf("unexpected steps (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateProvenance_sandbox(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+	sha256 := "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2"
+	com := []string{"/usr/bin/go", "build", "-mod=vendor", "-o", "binary"}
+
+	mcom, err := utils.MarshalToString(com)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	opts := &ProvenanceOptions{
+		Name:       "foo",
+		Digest:     sha256,
+		Command:    mcom,
+		WorkingDir: "/home/foo",
+		Sandbox:    true,
+		Redaction:  slsa.DefaultRedactionPolicy,
+		Mode:       common.SigningModeUnsigned,
+	}
+	b, _, err := GenerateProvenance(opts, nil, &testutil.TransparencyLogWithErr{},
+		&slsa.NilClientProvider{},
+	)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	var got struct {
+		Predicate struct {
+			BuildConfig buildConfig `json:"buildConfig"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+
+	// Only the compilation step is run in the sandbox.
+	want := []step{
+		{
+			Command:    []string{"/usr/bin/go", "mod", "vendor"},
+			WorkingDir: "/home/foo",
+		},
+		{
+			Command:    com,
+			WorkingDir: "/home/foo",
+			Sandbox: &runner.Sandbox{
+				ReadOnlyPaths: []string{"/home/foo"},
+				Namespaces:    []string{"user", "mount", "network"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got.Predicate.BuildConfig.Steps); diff != "" {
+		t.Errorf("unexpected steps (-want +got):\n%s", diff)
+	}
+}
+
+func Test_dependencySteps(t *testing.T) {
+	t.Parallel()
+
+	goSum := "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2"
+	tests := []struct {
+		name    string
+		com     []string
+		env     []string
+		steps   []step
+		modules *modules
+	}{
+		{
+			name: "vendor mode",
+			com:  []string{"/usr/bin/go", "build", "-mod=vendor", "-o", "binary"},
+			env:  []string{"GOOS=linux", "GOARCH=amd64"},
+			steps: []step{
+				{
+					Command:    []string{"/usr/bin/go", "mod", "vendor"},
+					WorkingDir: "/home/foo",
+				},
+			},
+		},
+		{
+			name: "empty command",
+			steps: []step{
+				{
+					WorkingDir: "/home/foo",
+				},
+			},
+		},
+		{
+			name: "readonly mode",
+			com:  []string{"/usr/bin/go", "build", "-mod=readonly", "-o", "binary"},
+			env:  []string{"GOOS=linux", "GOARCH=amd64", "GOPROXY=off", "GOFLAGS=-mod=readonly"},
+			steps: []step{
+				{
+					Command:    []string{"/usr/bin/go", "mod", "download"},
+					WorkingDir: "/home/foo",
+				},
+				{
+					Command:    []string{"/usr/bin/go", "mod", "verify"},
+					Env:        []string{"GOOS=linux", "GOARCH=amd64", "GOPROXY=off", "GOFLAGS=-mod=readonly"},
+					WorkingDir: "/home/foo",
+				},
+			},
+			modules: &modules{
+				Mode:        "readonly",
+				GoSumDigest: slsacommon.DigestSet{"sha256": goSum},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			steps, mods := dependencySteps(tt.com, tt.env, "/home/foo", goSum)
+			if diff := cmp.Diff(tt.steps, steps); diff != "" {
+				t.Errorf("unexpected steps (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.modules, mods); diff != "" {
+				t.Errorf("unexpected modules (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_parseBuildTime(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		s        string
+		expected time.Time
+		err      error
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "utc",
+			s:        "2023-04-14T12:00:00Z",
+			expected: time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "offset",
+			s:        "2023-04-14T14:00:00+02:00",
+			expected: time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "not rfc3339",
+			s:    "1681473600",
+			err:  errInvalidBuildTime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseBuildTime(tt.s)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tt.err, err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("unexpected time, want: %v, got: %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+const (
+	// toolchainURIPrefix is the URI prefix of toolchain materials.
+	toolchainURIPrefix = "file://"
+
+	// dirhashDigestAlg is the digest algorithm used for directories. The
+	// value is the Go checksum database "h1:" hash of the directory.
+	dirhashDigestAlg = "dirhash1"
+)
+
+// toolchainMaterials hashes the C toolchain used by cgo and returns it as a
+// list of materials. Compilers are hashed after resolving symlinks, and the
+// sysroot is hashed as a directory tree.
+func toolchainMaterials(cgo *CgoConfig) ([]slsacommon.ProvenanceMaterial, error) {
+	var materials []slsacommon.ProvenanceMaterial
+
+	for _, p := range []string{cgo.CC, cgo.CXX} {
+		if p == "" {
+			continue
+		}
+		m, err := compilerMaterial(p)
+		if err != nil {
+			return nil, err
+		}
+		materials = append(materials, *m)
+	}
+
+	if cgo.Sysroot != "" {
+		m, err := sysrootMaterial(cgo.Sysroot)
+		if err != nil {
+			return nil, err
+		}
+		materials = append(materials, *m)
+	}
+
+	return materials, nil
+}
+
+func compilerMaterial(path string) (*slsacommon.ProvenanceMaterial, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: resolving %q: %w", ErrInvalidToolchain, path, err)
+	}
+	// The symlink target must also be an allowed compiler.
+	if err := validateToolchainPath(resolved, allowedCompilerDirs); err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("%w: opening %q: %w", ErrInvalidToolchain, resolved, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("%w: hashing %q: %w", ErrInvalidToolchain, resolved, err)
+	}
+
+	return &slsacommon.ProvenanceMaterial{
+		URI: toolchainURIPrefix + resolved,
+		Digest: slsacommon.DigestSet{
+			"sha256": hex.EncodeToString(h.Sum(nil)),
+		},
+	}, nil
+}
+
+func sysrootMaterial(path string) (*slsacommon.ProvenanceMaterial, error) {
+	h, err := dirhash.HashDir(path, "", dirhash.Hash1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: hashing %q: %w", ErrInvalidToolchain, path, err)
+	}
+
+	return &slsacommon.ProvenanceMaterial{
+		URI: toolchainURIPrefix + path,
+		Digest: slsacommon.DigestSet{
+			dirhashDigestAlg: strings.TrimPrefix(h, "h1:"),
+		},
+	}, nil
+}
+
+// parseToolchainMaterials decodes and validates the toolchain materials
+// shared by the build step.
+func parseToolchainMaterials(s string) ([]slsacommon.ProvenanceMaterial, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base64.StdEncoding.DecodeString: %w", ErrInvalidToolchain, err)
+	}
+
+	var materials []slsacommon.ProvenanceMaterial
+	if err := json.Unmarshal(b, &materials); err != nil {
+		return nil, fmt.Errorf("%w: json.Unmarshal: %w", ErrInvalidToolchain, err)
+	}
+
+	for _, m := range materials {
+		path, found := strings.CutPrefix(m.URI, toolchainURIPrefix)
+		if !found {
+			return nil, fmt.Errorf("%w: unexpected URI %q", ErrInvalidToolchain, m.URI)
+		}
+		if validateToolchainPath(path, allowedCompilerDirs) != nil &&
+			validateToolchainPath(path, allowedSysrootDirs) != nil {
+			return nil, fmt.Errorf("%w: unexpected path %q", ErrInvalidToolchain, path)
+		}
+		if len(m.Digest) != 1 {
+			return nil, fmt.Errorf("%w: expected a single digest for %q", ErrInvalidToolchain, m.URI)
+		}
+		if d, ok := m.Digest["sha256"]; ok {
+			if _, err := hex.DecodeString(d); err != nil || len(d) != 64 {
+				return nil, fmt.Errorf("%w: invalid sha256 digest for %q", ErrInvalidToolchain, m.URI)
+			}
+			continue
+		}
+		if d, ok := m.Digest[dirhashDigestAlg]; ok {
+			if raw, err := base64.StdEncoding.DecodeString(d); err != nil || len(raw) != sha256.Size {
+				return nil, fmt.Errorf("%w: invalid %s digest for %q", ErrInvalidToolchain, dirhashDigestAlg, m.URI)
+			}
+			continue
+		}
+		return nil, fmt.Errorf("%w: unsupported digest for %q", ErrInvalidToolchain, m.URI)
+	}
+
+	return materials, nil
+}
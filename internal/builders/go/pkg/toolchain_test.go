@@ -0,0 +1,163 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/Kong/slsa-github-generator/internal/utils"
+)
+
+func Test_parseToolchainMaterials(t *testing.T) {
+	t.Parallel()
+
+	cc := slsacommon.ProvenanceMaterial{
+		URI: "file:///usr/bin/aarch64-linux-gnu-gcc-12",
+		Digest: slsacommon.DigestSet{
+			"sha256": "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2",
+		},
+	}
+	sysroot := slsacommon.ProvenanceMaterial{
+		URI: "file:///usr/aarch64-linux-gnu",
+		Digest: slsacommon.DigestSet{
+			"dirhash1": "LZ0GC0SFu6cAQLBWFL2KW3yyyRkCKzaF7ehxGyNWRRE=",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		materials []slsacommon.ProvenanceMaterial
+		err       func(*testing.T, error)
+	}{
+		{
+			name:      "compiler and sysroot",
+			materials: []slsacommon.ProvenanceMaterial{cc, sysroot},
+		},
+		{
+			name: "compiler not allowed",
+			materials: []slsacommon.ProvenanceMaterial{
+				{
+					URI:    "file:///home/runner/gcc",
+					Digest: cc.Digest,
+				},
+			},
+			err: errInvalidToolchainFunc,
+		},
+		{
+			name: "not a file URI",
+			materials: []slsacommon.ProvenanceMaterial{
+				{
+					URI:    "https://example.com/usr/bin/gcc",
+					Digest: cc.Digest,
+				},
+			},
+			err: errInvalidToolchainFunc,
+		},
+		{
+			name: "invalid sha256",
+			materials: []slsacommon.ProvenanceMaterial{
+				{
+					URI:    cc.URI,
+					Digest: slsacommon.DigestSet{"sha256": "abcd"},
+				},
+			},
+			err: errInvalidToolchainFunc,
+		},
+		{
+			name: "invalid dirhash",
+			materials: []slsacommon.ProvenanceMaterial{
+				{
+					URI:    sysroot.URI,
+					Digest: slsacommon.DigestSet{"dirhash1": "abcd"},
+				},
+			},
+			err: errInvalidToolchainFunc,
+		},
+		{
+			name: "unsupported digest",
+			materials: []slsacommon.ProvenanceMaterial{
+				{
+					URI:    cc.URI,
+					Digest: slsacommon.DigestSet{"md5": "abcd"},
+				},
+			},
+			err: errInvalidToolchainFunc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := utils.MarshalToString(tt.materials)
+			if err != nil {
+				t.Fatalf("MarshalToString: %v", err)
+			}
+
+			got, err := parseToolchainMaterials(s)
+			if tt.err != nil {
+				tt.err(t, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(tt.materials, got); diff != "" {
+				t.Errorf("unexpected materials (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_sysrootMaterial(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "stdio.h"), []byte("int printf(const char *, ...);\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	m1, err := sysrootMaterial(dir)
+	if err != nil {
+		t.Fatalf("sysrootMaterial: %v", err)
+	}
+	if got, want := m1.URI, "file://"+dir; got != want {
+		t.Errorf("unexpected URI, want: %q, got: %q", want, got)
+	}
+
+	// Modifying the sysroot must change its digest.
+	if err := os.WriteFile(filepath.Join(dir, "stdlib.h"), []byte("void exit(int);\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	m2, err := sysrootMaterial(dir)
+	if err != nil {
+		t.Fatalf("sysrootMaterial: %v", err)
+	}
+	if cmp.Equal(m1.Digest, m2.Digest) {
+		t.Errorf("expected different digests, got: %v", m2.Digest)
+	}
+}
+
+func Test_compilerMaterial_notFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := compilerMaterial("/usr/bin/does-not-exist-gcc")
+	errInvalidToolchainFunc(t, err)
+}
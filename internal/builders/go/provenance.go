@@ -0,0 +1,207 @@
+// Copyright 2022 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/builders/go/pkg"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/sigstore"
+)
+
+// provenanceResult is the result of the 'provenance' command.
+type provenanceResult struct {
+	// SignedProvenanceName is the name of the file containing the signed provenance.
+	SignedProvenanceName string `json:"signedProvenanceName"`
+
+	// SignedProvenanceSHA256 is the sha256 digest of the signed provenance.
+	SignedProvenanceSHA256 string `json:"signedProvenanceSha256"`
+
+	// AttestationID is the ID of the attestation uploaded to GitHub, if any.
+	AttestationID int64 `json:"attestationId,omitempty"`
+}
+
+// provenanceCmd returns the 'provenance' command.
+func provenanceCmd(check func(error)) *cobra.Command {
+	var opts pkg.ProvenanceOptions
+	var rekor string
+	var redactionPolicy string
+	var output string
+	var signingMode string
+	var signingKeyPath string
+	var githubAttestation bool
+
+	c := &cobra.Command{
+		Use:   "provenance",
+		Short: "Create a signed SLSA provenance attestation for a Go binary",
+		Long: `Create a signed SLSA provenance attestation for a Go binary and upload it to
+a Rekor transparency log. The command and env are the base64-encoded values
+resolved by a dry run of the 'build' command. This command assumes that it is
+being run in the context of a Github Actions workflow.
+
+With --local, provenance for a local build is generated from the git working
+tree of --workingDir and signed with the key given by --signing-key. Local
+provenance is not uploaded to the transparency log.
+
+With --signing-mode=unsigned, the unsigned provenance statement is written
+instead. It is generated without requesting an OIDC token.
+
+With --github-attestation, the signed provenance is also uploaded as a Sigstore
+bundle to the attestations of the repository. This requires the attestations
+write permission.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, _ []string) {
+			// Note: env may be empty.
+			if opts.Name == "" || opts.Digest == "" || opts.Command == "" || opts.WorkingDir == "" {
+				check(errors.New("--binary-name, --digest, --command and --workingDir are required"))
+			}
+
+			var err error
+			opts.Mode, err = common.ParseSigningMode(signingMode)
+			check(err)
+			if githubAttestation {
+				switch {
+				case opts.Mode == common.SigningModeUnsigned:
+					check(common.ErrUnsignedAttestation)
+				case opts.Local:
+					check(errors.New("--github-attestation is only supported in Github Actions"))
+				}
+			}
+
+			opts.Redaction, err = common.LoadRedactionPolicy(redactionPolicy)
+			check(err)
+
+			res, err := runProvenanceGeneration(&opts, rekor, signingKeyPath, githubAttestation)
+			check(err)
+
+			switch output {
+			case outputGithub:
+				if opts.Local {
+					// Local builds use the file directly.
+					return
+				}
+				check(github.SetOutput("signed-provenance-name", res.SignedProvenanceName))
+				check(github.SetOutput("signed-provenance-sha256", res.SignedProvenanceSHA256))
+				if res.AttestationID != 0 {
+					check(github.SetOutput(common.AttestationIDOutput, strconv.FormatInt(res.AttestationID, 10)))
+				}
+			case outputJSON:
+				check(writeJSON(cmd.OutOrStdout(), res))
+			default:
+				check(fmt.Errorf("%w: %q", errOutputFormat, output))
+			}
+		},
+	}
+
+	c.Flags().StringVar(&opts.Name, "binary-name", "", "Untrusted binary name of the artifact built.")
+	c.Flags().StringVar(&opts.Digest, "digest", "", "sha256 digest of the untrusted binary.")
+	c.Flags().StringVar(&opts.Command, "command", "", "Command used to compile the binary.")
+	c.Flags().StringVar(&opts.Env, "env", "", "Env variables used to compile the binary.")
+	c.Flags().StringVar(&opts.EffectiveEnv, "effective-env", "", "Complete environment of the compiler, as recorded by the build in hermetic mode.")
+	c.Flags().StringVar(&opts.WorkingDir, "workingDir", "", "Working directory used to issue compilation commands.")
+	c.Flags().StringVar(&rekor, "rekor", sigstore.DefaultRekorAddr, "Rekor server to use for provenance.")
+	c.Flags().StringVar(&opts.GoSumDigest, "go-sum-digest", "", "sha256 digest of the go.sum file used in module mode.")
+	c.Flags().StringVar(&opts.Toolchains, "toolchains", "", "C toolchain materials recorded by the build for cgo.")
+	c.Flags().BoolVar(&opts.Sandbox, "sandbox", false, "The binary was compiled in a sandbox.")
+	c.Flags().StringVar(&opts.StartedOn, "started-on", "", "RFC 3339 time the build started, as measured by the build.")
+	c.Flags().StringVar(&opts.FinishedOn, "finished-on", "", "RFC 3339 time the build finished, as measured by the build.")
+	c.Flags().BoolVar(&opts.Reproducible, "reproducible", false, "The build passed the reproducibility check.")
+	c.Flags().BoolVar(&opts.AllowSelfHosted, "allow-self-hosted-runners", false,
+		"Allow generating provenance on self-hosted runners. The provenance uses a self-hosted builder ID.")
+	c.Flags().StringVar(&redactionPolicy, "redaction-policy", "",
+		"Path to a JSON redaction policy for the event payload and vars context. (default: built-in policy)")
+	c.Flags().StringVar(&output, "output", outputGithub,
+		fmt.Sprintf("Output format: %q or %q.", outputGithub, outputJSON))
+	c.Flags().BoolVar(&githubAttestation, "github-attestation", false,
+		"Upload the signed provenance to the attestations of the repository.")
+	common.AddLocalFlags(c, &opts.Local, &signingKeyPath)
+	common.AddSigningModeFlag(c, &signingMode)
+
+	return c
+}
+
+func runProvenanceGeneration(opts *pkg.ProvenanceOptions, rekor, signingKeyPath string,
+	githubAttestation bool,
+) (*provenanceResult, error) {
+	var s signing.Signer = sigstore.NewDefaultFulcio()
+	var r signing.TransparencyLog = sigstore.NewRekor(rekor)
+	if opts.Local {
+		// Local provenance is signed with the key and is not uploaded to
+		// the transparency log.
+		var err error
+		s, err = common.LoadLocalSigner(signingKeyPath, opts.Mode)
+		if err != nil {
+			return nil, err
+		}
+		r = nil
+	}
+	attBytes, logEntry, err := pkg.GenerateProvenance(opts, s, r, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := fmt.Sprintf("%s.intoto.jsonl", opts.Name)
+	f, err := utils.CreateNewFileUnderCurrentDirectory(filename, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(attBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := computeSHA256(attBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &provenanceResult{
+		SignedProvenanceName:   filename,
+		SignedProvenanceSHA256: h,
+	}
+	if githubAttestation {
+		bundle, err := sigstore.NewBundle(attBytes, logEntry)
+		if err != nil {
+			return nil, err
+		}
+		res.AttestationID, err = common.UploadGithubAttestation(context.Background(), nil, bundle)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func computeSHA256(data []byte) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, bytes.NewReader(data)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2022 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/elliptic"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+
+	"github.com/Kong/slsa-github-generator/internal/builders/common"
+	"github.com/Kong/slsa-github-generator/internal/utils"
+	"github.com/Kong/slsa-github-generator/slsa"
+)
+
+func Test_provenanceCmd_json(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	command, err := utils.MarshalToString([]string{"/usr/bin/go", "build", "-mod=vendor", "-o", "binary"})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	env, err := utils.MarshalToString([]string{"GOOS=linux", "GOARCH=amd64"})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	// The JSON output is written to stdout, along with anything else that
+	// the command prints there.
+	stdoutPath := filepath.Join(t.TempDir(), "stdout")
+	stdout, err := os.Create(stdoutPath)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer stdout.Close()
+	origStdout := os.Stdout
+	os.Stdout = stdout
+	defer func() { os.Stdout = origStdout }()
+
+	c := provenanceCmd(checkTest(t))
+	c.SetArgs([]string{
+		"--binary-name", "binary",
+		"--digest", "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2",
+		"--command", command,
+		"--env", env,
+		"--workingDir", "/home/foo",
+		"--signing-mode", "unsigned",
+		"--output", "json",
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	os.Stdout = origStdout
+
+	b, err := os.ReadFile(stdoutPath)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	var res provenanceResult
+	if err := json.Unmarshal(b, &res); err != nil {
+		t.Fatalf("unmarshaling output %q: %v", b, err)
+	}
+	if want, got := "binary.intoto.jsonl", res.SignedProvenanceName; want != got {
+		t.Errorf("unexpected provenance name, want: %q, got: %q", want, got)
+	}
+	if _, err := os.Stat(res.SignedProvenanceName); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+}
+
+func Test_provenanceCmd_local(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	command, err := utils.MarshalToString([]string{"/usr/bin/go", "build", "-mod=vendor", "-o", "binary"})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	// Change to a temporary git working tree.
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "commit.gpgsign=false",
+			"commit", "--quiet", "--allow-empty", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	t.Setenv(common.SigningKeyPasswordEnvKey, "hunter2")
+	privPEM, pubPEM, err := cryptoutils.GeneratePEMEncodedECDSAKeyPair(elliptic.P256(),
+		cryptoutils.StaticPasswordFunc([]byte("hunter2")))
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(keyPath, privPEM, 0o600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+
+	// No GitHub Actions context is needed.
+	c := provenanceCmd(checkTest(t))
+	c.SetArgs([]string{
+		"--binary-name", "binary",
+		"--digest", "2e0390eb024a52963db7b95e84a9c2b12c004054a7bad9a97ec0c7c89d4681d2",
+		"--command", command,
+		"--workingDir", dir,
+		"--local",
+		"--signing-key", keyPath,
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile("binary.intoto.jsonl")
+	if err != nil {
+		t.Fatalf("reading provenance: %v", err)
+	}
+
+	// Verify the envelope with the public key.
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(pubPEM)
+	if err != nil {
+		t.Fatalf("UnmarshalPEMToPublicKey: %v", err)
+	}
+	v, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("LoadVerifier: %v", err)
+	}
+	if err := dsse.WrapVerifier(v).VerifySignature(bytes.NewReader(b), nil); err != nil {
+		t.Fatalf("verifying attestation: %v", err)
+	}
+
+	var env struct {
+		Payload []byte `json:"payload"`
+	}
+	if err := json.Unmarshal(b, &env); err != nil {
+		t.Fatalf("unmarshaling envelope: %v", err)
+	}
+	var got struct {
+		Predicate struct {
+			BuildType string `json:"buildType"`
+			Builder   struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+			BuildConfig struct {
+				Steps []struct {
+					WorkingDir string `json:"workingDir"`
+				} `json:"steps"`
+			} `json:"buildConfig"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(env.Payload, &got); err != nil {
+		t.Fatalf("unmarshaling provenance: %v", err)
+	}
+	if want, got := slsa.LocalBuilderID, got.Predicate.Builder.ID; want != got {
+		t.Errorf("unexpected builder ID, want: %q, got: %q", want, got)
+	}
+	if want, got := slsa.LocalBuildType, got.Predicate.BuildType; want != got {
+		t.Errorf("unexpected build type, want: %q, got: %q", want, got)
+	}
+	// The Go build steps are recorded.
+	if steps := got.Predicate.BuildConfig.Steps; len(steps) == 0 || steps[len(steps)-1].WorkingDir != dir {
+		t.Errorf("unexpected build steps: %+v", steps)
+	}
+}
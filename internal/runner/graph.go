@@ -0,0 +1,200 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+)
+
+// ErrInvalidGraph indicates that the dependencies between steps are invalid.
+var ErrInvalidGraph = errors.New("invalid step graph")
+
+// stepGraph is the dependency graph of the runner's steps. Steps are
+// identified by their index.
+type stepGraph struct {
+	// deps are the indices of the steps each step depends on.
+	deps [][]int
+	// dependents are the indices of the steps that depend on each step.
+	dependents [][]int
+}
+
+// graph validates the dependencies between the runner's steps and returns
+// their graph.
+func (r *CommandRunner) graph() (*stepGraph, error) {
+	ids := make(map[string]int, len(r.Steps))
+	for i, step := range r.Steps {
+		if step.ID == "" {
+			continue
+		}
+		if _, ok := ids[step.ID]; ok {
+			return nil, fmt.Errorf("%w: duplicate step id %q", ErrInvalidGraph, step.ID)
+		}
+		ids[step.ID] = i
+	}
+
+	g := &stepGraph{
+		deps:       make([][]int, len(r.Steps)),
+		dependents: make([][]int, len(r.Steps)),
+	}
+	for i, step := range r.Steps {
+		for _, id := range step.DependsOn {
+			j, ok := ids[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: step %d depends on unknown step %q", ErrInvalidGraph, i, id)
+			}
+			g.deps[i] = append(g.deps[i], j)
+			g.dependents[j] = append(g.dependents[j], i)
+		}
+	}
+
+	if _, err := g.order(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// order returns the indices of the steps in a topological order. Among steps
+// that are ready at the same time, lower indices come first.
+func (g *stepGraph) order() ([]int, error) {
+	pending := make([]int, len(g.deps))
+	var ready []int
+	for i, deps := range g.deps {
+		pending[i] = len(deps)
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := make([]int, 0, len(g.deps))
+	for len(ready) > 0 {
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, i)
+		for _, j := range g.dependents[i] {
+			pending[j]--
+			if pending[j] == 0 {
+				ready = insertSorted(ready, j)
+			}
+		}
+	}
+
+	if len(order) != len(g.deps) {
+		return nil, fmt.Errorf("%w: dependency cycle", ErrInvalidGraph)
+	}
+	return order, nil
+}
+
+// execute runs the steps of the runner as their dependencies complete, with
+// at most Workers steps running at the same time. After the first failure, no
+// new steps are started and running steps are cancelled. It returns the
+// records of the steps that were started, in the order of the runner's Steps,
+// and the first error.
+func (r *CommandRunner) execute(ctx context.Context) ([]*StepRecord, error) {
+	g, err := r.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	workers := r.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Concurrent steps share the runner's writers.
+	rc := *r
+	rc.Stdout = &syncWriter{w: r.stdout()}
+	rc.Stderr = &syncWriter{w: r.stderr()}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	n := len(r.Steps)
+	records := make([]*StepRecord, n)
+	errs := make([]error, n)
+	pending := make([]int, n)
+	var ready []int
+	for i := range r.Steps {
+		pending[i] = len(g.deps[i])
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	done := make(chan int)
+	running := 0
+	var firstErr error
+	for {
+		for firstErr == nil && running < workers && len(ready) > 0 {
+			i := ready[0]
+			ready = ready[1:]
+			running++
+			go func(i int) {
+				records[i], errs[i] = rc.runStep(ctx, i, r.Steps[i], false)
+				done <- i
+			}(i)
+		}
+		if running == 0 {
+			break
+		}
+
+		i := <-done
+		running--
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+				cancel()
+			}
+			continue
+		}
+		for _, j := range g.dependents[i] {
+			pending[j]--
+			if pending[j] == 0 {
+				ready = insertSorted(ready, j)
+			}
+		}
+	}
+
+	var started []*StepRecord
+	for _, rec := range records {
+		if rec != nil {
+			started = append(started, rec)
+		}
+	}
+	return started, firstErr
+}
+
+// insertSorted inserts i in the sorted slice s.
+func insertSorted(s []int, i int) []int {
+	pos, _ := slices.BinarySearch(s, i)
+	return slices.Insert(s, pos, i)
+}
+
+// syncWriter serializes writes to w.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.Write.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
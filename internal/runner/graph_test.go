@@ -0,0 +1,199 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommandRunner_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	// Each step waits for the other one to start, so they only complete if
+	// they are run concurrently.
+	wait := func(self, other string) []string {
+		return []string{"bash", "-c", "touch " + self + "; while [ ! -f " + other + " ]; do sleep 0.01; done"}
+	}
+	policy := &StepPolicy{Timeout: 10 * time.Second}
+	r := CommandRunner{
+		Workers: 2,
+		Steps: []*CommandStep{
+			{ID: "a", Command: wait("a", "b"), WorkingDir: dir, Policy: policy, Outputs: []string{"a"}},
+			{ID: "b", Command: wait("b", "a"), WorkingDir: dir, Policy: policy, Outputs: []string{"b"}},
+			{
+				ID:         "c",
+				Command:    []string{"bash", "-c", "test -f a && test -f b"},
+				WorkingDir: dir,
+				DependsOn:  []string{"a", "b"},
+			},
+		},
+	}
+
+	records, err := r.RunRecords(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, rec := range records {
+		got = append(got, rec.ID+":"+rec.Status)
+	}
+	if diff := cmp.Diff([]string{"a:succeeded", "b:succeeded", "c:succeeded"}, got); diff != "" {
+		t.Errorf("unexpected records (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{"a", "b"}, records[2].DependsOn); diff != "" {
+		t.Errorf("unexpected dependencies (-want +got):\n%s", diff)
+	}
+
+	want := []*OutputRecord{
+		{
+			Path:   filepath.Join(dir, "a"),
+			Digest: sha256Digest(""),
+		},
+	}
+	if diff := cmp.Diff(want, records[0].Outputs); diff != "" {
+		t.Errorf("unexpected outputs (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommandRunner_DependencyOrder(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	step := func(id string, deps ...string) *CommandStep {
+		return &CommandStep{
+			ID:        id,
+			Command:   []string{"bash", "-c", "echo -n " + id + " >> " + log},
+			DependsOn: deps,
+		}
+	}
+
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			step("c", "b"),
+			step("b", "a"),
+			step("a"),
+			step("d"),
+		},
+	}
+
+	steps, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Steps are returned in the order of the runner's steps.
+	if want, got := "echo -n c >> "+log, steps[0].Command[2]; want != got {
+		t.Errorf("unexpected first step, want %q, got: %q", want, got)
+	}
+
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	// Among ready steps, the first in the runner's steps is run first.
+	if want, got := "abcd", string(b); want != got {
+		t.Errorf("unexpected execution order, want %q, got: %q", want, got)
+	}
+}
+
+func TestCommandRunner_StopOnFailure(t *testing.T) {
+	r := CommandRunner{
+		Workers: 2,
+		Steps: []*CommandStep{
+			{ID: "fail", Command: []string{"bash", "-c", "sleep 0.1; exit 1"}},
+			{ID: "slow", Command: []string{"bash", "-c", "sleep 30"}},
+			{ID: "next", Command: []string{"bash", "-c", "exit 0"}, DependsOn: []string{"fail"}},
+		},
+	}
+
+	start := time.Now()
+	records, err := r.RunRecords(context.Background())
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > waitDelay {
+		t.Errorf("runner took %s, slow step not cancelled", elapsed)
+	}
+
+	var got []string
+	for _, rec := range records {
+		got = append(got, rec.ID+":"+rec.Status)
+	}
+	if diff := cmp.Diff([]string{"fail:failed", "slow:canceled"}, got); diff != "" {
+		t.Errorf("unexpected records (-want +got):\n%s", diff)
+	}
+}
+
+func TestCommandRunner_MissingOutput(t *testing.T) {
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command:    []string{"bash", "-c", "exit 0"},
+				WorkingDir: t.TempDir(),
+				Outputs:    []string{"binary"},
+			},
+		},
+	}
+
+	records, err := r.RunRecords(context.Background())
+	if !errors.Is(err, ErrMissingOutput) {
+		t.Fatalf("unexpected error, want: %v, got: %v", ErrMissingOutput, err)
+	}
+	if want, got := StatusFailed, records[0].Status; want != got {
+		t.Errorf("unexpected status, want %q, got: %q", want, got)
+	}
+}
+
+func TestCommandRunner_InvalidGraph(t *testing.T) {
+	tests := map[string][]*CommandStep{
+		"duplicate id": {
+			{ID: "a", Command: []string{"true"}},
+			{ID: "a", Command: []string{"true"}},
+		},
+		"unknown dependency": {
+			{ID: "a", Command: []string{"true"}, DependsOn: []string{"b"}},
+		},
+		"cycle": {
+			{ID: "a", Command: []string{"true"}, DependsOn: []string{"c"}},
+			{ID: "b", Command: []string{"true"}, DependsOn: []string{"a"}},
+			{ID: "c", Command: []string{"true"}, DependsOn: []string{"b"}},
+		},
+	}
+
+	for name, steps := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := CommandRunner{
+				Steps:  steps,
+				Stdout: &strings.Builder{},
+			}
+
+			if _, err := r.Dry(context.Background()); !errors.Is(err, ErrInvalidGraph) {
+				t.Errorf("unexpected Dry error, want: %v, got: %v", ErrInvalidGraph, err)
+			}
+			if _, err := r.Run(context.Background()); !errors.Is(err, ErrInvalidGraph) {
+				t.Errorf("unexpected Run error, want: %v, got: %v", ErrInvalidGraph, err)
+			}
+		})
+	}
+}
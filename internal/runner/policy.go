@@ -0,0 +1,103 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// defaultBackoff is the delay before the first retry if no backoff is set.
+	defaultBackoff = time.Second
+
+	// maxBackoff is the maximum delay between retries.
+	maxBackoff = 5 * time.Minute
+)
+
+// StepPolicy is the execution policy of a step.
+type StepPolicy struct {
+	// Timeout is the maximum duration of each attempt of the step. If zero,
+	// attempts have no timeout.
+	Timeout time.Duration
+
+	// Retries is the number of times the step is retried after a failure.
+	Retries int
+
+	// Backoff is the delay before the first retry. The delay is doubled
+	// for each subsequent retry, up to five minutes. If zero, a delay of one
+	// second is used.
+	Backoff time.Duration
+}
+
+// backoff returns the delay before the retry following the given attempt,
+// starting at 1. The delay is capped at maxBackoff.
+func (p *StepPolicy) backoff(attempt int) time.Duration {
+	d := defaultBackoff
+	if p != nil && p.Backoff > 0 {
+		d = p.Backoff
+	}
+	// NOTE: The delay is doubled one attempt at a time so that it can't
+	// overflow for large attempt counts.
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// stepPolicyJSON is the JSON representation of a StepPolicy. Durations are
+// represented as strings, e.g. "1m30s".
+type stepPolicyJSON struct {
+	Timeout string `json:"timeout,omitempty"`
+	Retries int    `json:"retries,omitempty"`
+	Backoff string `json:"backoff,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON.
+func (p StepPolicy) MarshalJSON() ([]byte, error) {
+	var j stepPolicyJSON
+	if p.Timeout != 0 {
+		j.Timeout = p.Timeout.String()
+	}
+	j.Retries = p.Retries
+	if p.Backoff != 0 {
+		j.Backoff = p.Backoff.String()
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON.
+func (p *StepPolicy) UnmarshalJSON(b []byte) error {
+	var j stepPolicyJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+
+	var policy StepPolicy
+	var err error
+	if j.Timeout != "" {
+		if policy.Timeout, err = time.ParseDuration(j.Timeout); err != nil {
+			return err
+		}
+	}
+	policy.Retries = j.Retries
+	if j.Backoff != "" {
+		if policy.Backoff, err = time.ParseDuration(j.Backoff); err != nil {
+			return err
+		}
+	}
+	*p = policy
+	return nil
+}
@@ -0,0 +1,248 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCommandRunner_Timeout(t *testing.T) {
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				// The child process holds stdout open. It must be killed
+				// along with bash for the step to return.
+				Command: []string{"bash", "-c", "sleep 30 & wait"},
+				Policy: &StepPolicy{
+					Timeout: 100 * time.Millisecond,
+				},
+			},
+		},
+		Stdout: &strings.Builder{},
+	}
+
+	start := time.Now()
+	records, err := r.RunRecords(context.Background())
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("unexpected error, want: %v, got: %v", ErrTimeout, err)
+	}
+	if elapsed := time.Since(start); elapsed > waitDelay {
+		t.Errorf("step took %s, process group not killed", elapsed)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("unexpected number of records: %v", len(records))
+	}
+	if want, got := -1, records[0].ExitCode; want != got {
+		t.Errorf("unexpected exit code, want %d, got: %d", want, got)
+	}
+}
+
+func TestCommandRunner_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command: []string{"bash", "-c", "sleep 30 & wait"},
+				Policy: &StepPolicy{
+					Retries: 3,
+				},
+			},
+		},
+		Stdout: &strings.Builder{},
+		Stderr: &strings.Builder{},
+	}
+
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	records, err := r.RunRecords(ctx)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if errors.Is(err, ErrTimeout) {
+		t.Errorf("unexpected timeout error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > waitDelay {
+		t.Errorf("step took %s, process group not killed", elapsed)
+	}
+
+	// Cancelled steps are not retried.
+	if want, got := 1, records[0].Attempts; want != got {
+		t.Errorf("unexpected attempts, want %d, got: %d", want, got)
+	}
+}
+
+func TestCommandRunner_Retry(t *testing.T) {
+	counter := filepath.Join(t.TempDir(), "counter")
+	stderr := &strings.Builder{}
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				// Fails on the first two attempts.
+				Command: []string{"bash", "-c", fmt.Sprintf(
+					`echo -n x >> %q; [ "$(cat %q)" = "xxx" ]`, counter, counter)},
+				Policy: &StepPolicy{
+					Retries: 3,
+					Backoff: time.Millisecond,
+				},
+			},
+		},
+		Stderr: stderr,
+	}
+
+	records, err := r.RunRecords(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := 3, records[0].Attempts; want != got {
+		t.Errorf("unexpected attempts, want %d, got: %d", want, got)
+	}
+	if want, got := 0, records[0].ExitCode; want != got {
+		t.Errorf("unexpected exit code, want %d, got: %d", want, got)
+	}
+	if want, got := 2, strings.Count(stderr.String(), "retrying"); want != got {
+		t.Errorf("unexpected retry messages, want %d, got: %q", want, stderr.String())
+	}
+}
+
+func TestCommandRunner_RetryExhausted(t *testing.T) {
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command: []string{"bash", "-c", "exit 2"},
+				Policy: &StepPolicy{
+					Retries: 1,
+					Backoff: time.Millisecond,
+				},
+			},
+		},
+		Stderr: &strings.Builder{},
+	}
+
+	records, err := r.RunRecords(context.Background())
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if want, got := 2, records[0].Attempts; want != got {
+		t.Errorf("unexpected attempts, want %d, got: %d", want, got)
+	}
+	if want, got := 2, records[0].ExitCode; want != got {
+		t.Errorf("unexpected exit code, want %d, got: %d", want, got)
+	}
+}
+
+func TestCommandRunner_DryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command: []string{"bash", "-c", "exit 0"},
+			},
+		},
+	}
+
+	if _, err := r.Dry(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error, want: %v, got: %v", context.Canceled, err)
+	}
+}
+
+func TestStepPolicy_backoff(t *testing.T) {
+	tests := map[string]struct {
+		policy   *StepPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		"nil policy": {
+			attempt:  1,
+			expected: time.Second,
+		},
+		"default backoff": {
+			policy:   &StepPolicy{Retries: 3},
+			attempt:  2,
+			expected: 2 * time.Second,
+		},
+		"first retry": {
+			policy:   &StepPolicy{Backoff: 100 * time.Millisecond},
+			attempt:  1,
+			expected: 100 * time.Millisecond,
+		},
+		"third retry": {
+			policy:   &StepPolicy{Backoff: 100 * time.Millisecond},
+			attempt:  3,
+			expected: 400 * time.Millisecond,
+		},
+		"capped": {
+			policy:   &StepPolicy{Backoff: time.Minute},
+			attempt:  4,
+			expected: maxBackoff,
+		},
+		"large attempt": {
+			policy:   &StepPolicy{Backoff: 100 * time.Millisecond},
+			attempt:  100,
+			expected: maxBackoff,
+		},
+		"large backoff": {
+			policy:   &StepPolicy{Backoff: time.Hour},
+			attempt:  1,
+			expected: maxBackoff,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if want, got := tc.expected, tc.policy.backoff(tc.attempt); want != got {
+				t.Errorf("unexpected backoff, want %s, got: %s", want, got)
+			}
+		})
+	}
+}
+
+func TestStepPolicy_JSON(t *testing.T) {
+	policy := StepPolicy{
+		Timeout: 90 * time.Second,
+		Retries: 2,
+		Backoff: 500 * time.Millisecond,
+	}
+
+	b, err := json.Marshal(policy)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if want, got := `{"timeout":"1m30s","retries":2,"backoff":"500ms"}`, string(b); want != got {
+		t.Errorf("unexpected JSON, want %s, got: %s", want, got)
+	}
+
+	var got StepPolicy
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(policy, got); diff != "" {
+		t.Errorf("unexpected policy (-want +got):\n%s", diff)
+	}
+}
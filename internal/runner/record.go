@@ -0,0 +1,240 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+// logMediaType is the media type of captured log streams.
+const logMediaType = "text/plain"
+
+// ErrMissingOutput indicates that a step did not produce one of its outputs.
+var ErrMissingOutput = errors.New("missing step output")
+
+// Status of an executed step.
+const (
+	// StatusSucceeded indicates that the step completed successfully.
+	StatusSucceeded = "succeeded"
+
+	// StatusFailed indicates that the step failed.
+	StatusFailed = "failed"
+
+	// StatusCanceled indicates that the step was cancelled, e.g. because
+	// another step failed.
+	StatusCanceled = "canceled"
+)
+
+// StepRecord is the execution record of a step.
+type StepRecord struct {
+	// Index is the index of the step in the runner's Steps.
+	Index int `json:"index"`
+
+	// ID is the ID of the step, if any.
+	ID string `json:"id,omitempty"`
+
+	// DependsOn are the IDs of the steps the step depends on.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Status is the status of the step.
+	Status string `json:"status"`
+
+	// Step is the step configuration actually used to run the command.
+	Step *CommandStep `json:"step"`
+
+	// Policy is the execution policy of the step, if any.
+	Policy *StepPolicy `json:"policy,omitempty"`
+
+	// Attempts is the number of times the command was run.
+	Attempts int `json:"attempts"`
+
+	// StartedOn is the time the last attempt of the command was started.
+	StartedOn time.Time `json:"startedOn"`
+
+	// FinishedOn is the time the last attempt of the command exited.
+	FinishedOn time.Time `json:"finishedOn"`
+
+	// ExitCode is the exit status of the command. It is -1 if the command
+	// could not be started or was terminated by a signal.
+	ExitCode int `json:"exitCode"`
+
+	// Stdout is the record of the command's stdout in the last attempt.
+	Stdout *LogRecord `json:"stdout"`
+
+	// Stderr is the record of the command's stderr in the last attempt.
+	Stderr *LogRecord `json:"stderr"`
+
+	// Outputs are the records of the files produced by the step.
+	Outputs []*OutputRecord `json:"outputs,omitempty"`
+}
+
+// OutputRecord is the record of a file produced by a step.
+type OutputRecord struct {
+	// Path is the absolute path of the file.
+	Path string `json:"path"`
+
+	// Digest is the digest of the file.
+	Digest slsacommon.DigestSet `json:"digest"`
+}
+
+// stepStatus returns the status of a step that completed with err.
+func stepStatus(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return StatusSucceeded
+	case ctx.Err() != nil:
+		return StatusCanceled
+	default:
+		return StatusFailed
+	}
+}
+
+// outputRecords returns the records of the outputs of a step run in dir.
+func outputRecords(dir string, outputs []string) ([]*OutputRecord, error) {
+	var records []*OutputRecord
+	for _, out := range outputs {
+		path := out
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrMissingOutput, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("hashing output %q: %w", path, err)
+		}
+
+		records = append(records, &OutputRecord{
+			Path: path,
+			Digest: slsacommon.DigestSet{
+				"sha256": hex.EncodeToString(h.Sum(nil)),
+			},
+		})
+	}
+	return records, nil
+}
+
+// LogRecord is the record of a captured log stream.
+type LogRecord struct {
+	// Name is the name of the log stream, e.g. "step-0.stdout".
+	Name string `json:"name"`
+
+	// Digest is the digest of the log stream.
+	Digest slsacommon.DigestSet `json:"digest"`
+
+	// Size is the size of the log stream in bytes.
+	Size int64 `json:"size"`
+
+	// Path is the file the log stream was written to. It is only set if the
+	// runner's LogDir is set.
+	Path string `json:"path,omitempty"`
+}
+
+// Byproducts returns the captured log streams of the step as resource
+// descriptors, for use as provenance byproducts.
+func (r *StepRecord) Byproducts() []slsa1.ResourceDescriptor {
+	var byproducts []slsa1.ResourceDescriptor
+	for _, l := range []*LogRecord{r.Stdout, r.Stderr} {
+		if l == nil {
+			continue
+		}
+		byproducts = append(byproducts, slsa1.ResourceDescriptor{
+			Name:      l.Name,
+			Digest:    l.Digest,
+			MediaType: logMediaType,
+		})
+	}
+	return byproducts
+}
+
+// logCapture computes the digest and size of a log stream while it is written
+// to the underlying writers.
+type logCapture struct {
+	name string
+	w    io.Writer
+	h    hash.Hash
+	size int64
+	f    *os.File
+}
+
+// newLogCapture returns a logCapture for the stream of the step at index that
+// also writes to out. If logDir is not empty, the stream is also written to a
+// file in logDir.
+func newLogCapture(logDir string, index int, stream string, out io.Writer) (*logCapture, error) {
+	c := &logCapture{
+		name: fmt.Sprintf("step-%d.%s", index, stream),
+		h:    sha256.New(),
+	}
+
+	writers := []io.Writer{out, c.h}
+	if logDir != "" {
+		f, err := os.Create(filepath.Join(logDir, c.name))
+		if err != nil {
+			return nil, fmt.Errorf("creating log file: %w", err)
+		}
+		c.f = f
+		writers = append(writers, f)
+	}
+	c.w = io.MultiWriter(writers...)
+
+	return c, nil
+}
+
+// Write implements io.Writer.Write.
+func (c *logCapture) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file, if any.
+func (c *logCapture) Close() error {
+	if c.f == nil {
+		return nil
+	}
+	return c.f.Close()
+}
+
+// record returns the LogRecord of the stream written so far.
+func (c *logCapture) record() *LogRecord {
+	l := &LogRecord{
+		Name: c.name,
+		Digest: slsacommon.DigestSet{
+			"sha256": hex.EncodeToString(c.h.Sum(nil)),
+		},
+		Size: c.size,
+	}
+	if c.f != nil {
+		l.Path = c.f.Name()
+	}
+	return l
+}
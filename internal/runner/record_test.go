@@ -0,0 +1,171 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+func sha256Digest(s string) slsacommon.DigestSet {
+	h := sha256.Sum256([]byte(s))
+	return slsacommon.DigestSet{"sha256": hex.EncodeToString(h[:])}
+}
+
+func TestCommandRunner_RunRecords(t *testing.T) {
+	logDir := t.TempDir()
+	stdout := &strings.Builder{}
+	stderr := &strings.Builder{}
+	r := CommandRunner{
+		LogDir: logDir,
+		Steps: []*CommandStep{
+			{
+				Command: []string{"bash", "-c", "echo -n hoge; echo -n fuga >&2"},
+			},
+			{
+				Command: []string{"bash", "-c", "echo -n bar; exit 3"},
+			},
+			{
+				Command: []string{"bash", "-c", "echo -n unreachable"},
+			},
+		},
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	before := time.Now()
+	records, err := r.RunRecords(context.Background())
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// The failed step is recorded, but later steps are not run.
+	if len(records) != 2 {
+		t.Fatalf("unexpected number of records: %v", len(records))
+	}
+
+	if want, got := "hogebar", stdout.String(); want != got {
+		t.Errorf("unexpected stdout, want %q, got: %q", want, got)
+	}
+	if want, got := "fuga", stderr.String(); want != got {
+		t.Errorf("unexpected stderr, want %q, got: %q", want, got)
+	}
+
+	for i, rec := range records {
+		if rec.Index != i {
+			t.Errorf("unexpected index, want %d, got: %d", i, rec.Index)
+		}
+		if rec.StartedOn.Before(before) || rec.FinishedOn.Before(rec.StartedOn) {
+			t.Errorf("unexpected timestamps: %v, %v", rec.StartedOn, rec.FinishedOn)
+		}
+	}
+
+	if want, got := 0, records[0].ExitCode; want != got {
+		t.Errorf("unexpected exit code, want %d, got: %d", want, got)
+	}
+	if want, got := 3, records[1].ExitCode; want != got {
+		t.Errorf("unexpected exit code, want %d, got: %d", want, got)
+	}
+
+	want := &LogRecord{
+		Name:   "step-0.stdout",
+		Digest: sha256Digest("hoge"),
+		Size:   4,
+		Path:   filepath.Join(logDir, "step-0.stdout"),
+	}
+	if diff := cmp.Diff(want, records[0].Stdout); diff != "" {
+		t.Errorf("unexpected stdout record (-want +got):\n%s", diff)
+	}
+
+	// The logs are written to files in the log dir.
+	for name, content := range map[string]string{
+		"step-0.stdout": "hoge",
+		"step-0.stderr": "fuga",
+		"step-1.stdout": "bar",
+		"step-1.stderr": "",
+	} {
+		b, err := os.ReadFile(filepath.Join(logDir, name))
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if got := string(b); got != content {
+			t.Errorf("unexpected content of %s, want %q, got: %q", name, content, got)
+		}
+	}
+}
+
+func TestCommandRunner_RunRecordsNoLogDir(t *testing.T) {
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command: []string{"bash", "-c", "echo -n hoge"},
+			},
+		},
+		Stdout: &strings.Builder{},
+	}
+
+	records, err := r.RunRecords(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("unexpected number of records: %v", len(records))
+	}
+
+	if got := records[0].Stdout.Path; got != "" {
+		t.Errorf("unexpected log path: %q", got)
+	}
+}
+
+func TestStepRecord_Byproducts(t *testing.T) {
+	rec := StepRecord{
+		Stdout: &LogRecord{
+			Name:   "step-1.stdout",
+			Digest: sha256Digest("hoge"),
+			Size:   4,
+		},
+		Stderr: &LogRecord{
+			Name:   "step-1.stderr",
+			Digest: sha256Digest(""),
+		},
+	}
+
+	want := []slsa1.ResourceDescriptor{
+		{
+			Name:      "step-1.stdout",
+			Digest:    sha256Digest("hoge"),
+			MediaType: "text/plain",
+		},
+		{
+			Name:      "step-1.stderr",
+			Digest:    sha256Digest(""),
+			MediaType: "text/plain",
+		},
+	}
+	if diff := cmp.Diff(want, rec.Byproducts()); diff != "" {
+		t.Errorf("unexpected byproducts (-want +got):\n%s", diff)
+	}
+}
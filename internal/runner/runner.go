@@ -17,13 +17,22 @@ package runner
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
+// ErrTimeout indicates that a step did not complete within its timeout.
+var ErrTimeout = errors.New("step timed out")
+
+// waitDelay is how long to wait for a command's I/O to complete after the
+// command exits or is killed.
+const waitDelay = 5 * time.Second
+
 // CommandRunner runs commands and returns the build steps that were run.
 type CommandRunner struct {
 	// Stdout is the Writer used for Stdout. If nil then os.Stdout is used.
@@ -35,6 +44,25 @@ type CommandRunner struct {
 	// Env is global environment variables passed to all commands.
 	Env []string
 
+	// Hermetic disables inheriting the environment of the current process.
+	// Commands are run with only the variables named in AllowEnv, taken from
+	// the current process, followed by Env and the step's Env.
+	Hermetic bool
+
+	// AllowEnv is the list of environment variable names inherited from the
+	// current process in hermetic mode. It is ignored otherwise.
+	AllowEnv []string
+
+	// LogDir is a directory where the stdout and stderr of each step are
+	// also written, to files named step-<index>.stdout and
+	// step-<index>.stderr. If empty, logs are not written to files.
+	LogDir string
+
+	// Workers is the maximum number of steps run at the same time. Steps are
+	// run as soon as the steps they depend on have completed. If less than
+	// 1, steps are run one at a time.
+	Workers int
+
 	// Steps are the steps to execute.
 	Steps []*CommandStep
 }
@@ -49,74 +77,107 @@ type CommandStep struct {
 
 	// Env are the environment variables passed to the command.
 	Env []string `json:"env"`
+
+	// EffectiveEnv is the complete environment the command was executed
+	// with, including inherited variables. Values of variables that look
+	// like secrets are redacted. It is only set in hermetic mode.
+	EffectiveEnv []string `json:"effectiveEnv,omitempty"`
+
+	// Sandbox is the isolation of the step. If nil, the step is not
+	// sandboxed.
+	Sandbox *Sandbox `json:"sandbox,omitempty"`
+
+	// Policy is the execution policy of the step. It is not included in the
+	// returned steps, but is recorded in the step's StepRecord.
+	Policy *StepPolicy `json:"-"`
+
+	// ID identifies the step in the DependsOn of other steps. It is optional
+	// and must be unique.
+	ID string `json:"-"`
+
+	// DependsOn are the IDs of the steps that must complete successfully
+	// before the step is run.
+	DependsOn []string `json:"-"`
+
+	// Outputs are the files produced by the step, relative to WorkingDir.
+	// The step fails if an output does not exist after the command
+	// completes. Their digests are recorded in the step's StepRecord.
+	Outputs []string `json:"-"`
 }
 
 // Dry returns the command steps as they would be executed by the runner
 // without actually executing the commands. This allows builders to get an
 // accurate set of steps in a trusted environment as executing commands will
-// execute untrusted code.
-func (r *CommandRunner) Dry() (steps []*CommandStep, err error) {
-	ctx := context.Background()
-	for _, step := range r.Steps {
-		var runStep *CommandStep
-		runStep, err = r.runStep(ctx, step, true)
+// execute untrusted code. The dependencies between steps are validated.
+func (r *CommandRunner) Dry(ctx context.Context) (steps []*CommandStep, err error) {
+	if _, err = r.graph(); err != nil {
+		return // steps, err
+	}
+	for i, step := range r.Steps {
+		if err = ctx.Err(); err != nil {
+			return // steps, err
+		}
+		var rec *StepRecord
+		rec, err = r.runStep(ctx, i, step, true)
 		if err != nil {
 			return // steps, err
 		}
-		steps = append(steps, runStep)
+		steps = append(steps, rec.Step)
 	}
 	return // steps, err
 }
 
 // Run executes a series of commands and returns the steps that were executed
-// successfully. Commands are run in sequence and are expected to return a zero
-// exit status.
+// successfully. Commands are expected to return a zero exit status. Commands
+// are run in sequence, unless Workers is set, in which case steps are run
+// concurrently as their dependencies complete. After the first failure, no
+// new steps are started and running steps are cancelled.
 //
 // Global environment variables are merged with steps environment variables in
 // the returned steps. In the case of duplicates the last occurrence has precidence.
-// Environment variables are *not* inherited from the current process.
+// Environment variables inherited from the current process are *not* included
+// in the returned steps' Env. In hermetic mode, only the variables listed in
+// AllowEnv are inherited and the full environment is returned in EffectiveEnv.
 //
 // The returned CommandSteps should be included in the buildConfig provenance.
 // These are *not* the same as the runner commands. Env vars are sanitized, pwd
 // is changed to the absolute path, and only commands that executed
-// successfully are returned.
+// successfully are returned, in the order of the runner's Steps.
 func (r *CommandRunner) Run(ctx context.Context) (steps []*CommandStep, err error) {
-	for _, step := range r.Steps {
-		var runStep *CommandStep
-		runStep, err = r.runStep(ctx, step, false)
-		if err != nil {
-			return // steps, err
+	records, err := r.execute(ctx)
+	for _, rec := range records {
+		if rec.Status == StatusSucceeded {
+			steps = append(steps, rec.Step)
 		}
-		steps = append(steps, runStep)
 	}
-	return // steps, err
+	return steps, err
 }
 
-// runStep runs the build step and returns the CommandStep configuration
-// actually used to run the command. If dry is true then the CommandStep is
-// returned without executing the command.
-func (r *CommandRunner) runStep(ctx context.Context, step *CommandStep, dry bool) (*CommandStep, error) {
+// RunRecords executes the commands like Run and returns the execution record
+// of each step that was started, including the steps that failed or were
+// cancelled, in the order of the runner's Steps.
+//
+// Records contain timestamps and log digests, so they are not reproducible
+// and should not be included in the buildConfig provenance. They can be
+// recorded as byproducts instead.
+func (r *CommandRunner) RunRecords(ctx context.Context) ([]*StepRecord, error) {
+	return r.execute(ctx)
+}
+
+// runStep runs the build step at index and returns its execution record,
+// including the CommandStep configuration actually used to run the command.
+// If dry is true then the record only contains the CommandStep and the command
+// is not executed. If the command fails, the record is returned along with the
+// error.
+func (r *CommandRunner) runStep(ctx context.Context, index int, step *CommandStep, dry bool) (*StepRecord, error) {
 	if len(step.Command) == 0 {
 		return nil, errors.New("command is empty")
 	}
 
-	name := step.Command[0]
-	args := step.Command[1:]
-
-	cmd := exec.CommandContext(ctx, name, args...)
 	pwd, err := filepath.Abs(step.WorkingDir)
 	if err != nil {
 		return nil, err
 	}
-	cmd.Dir = pwd
-	cmd.Stdout = os.Stdout
-	if r.Stdout != nil {
-		cmd.Stdout = r.Stdout
-	}
-	cmd.Stderr = os.Stderr
-	if r.Stderr != nil {
-		cmd.Stderr = r.Stderr
-	}
 
 	// We will copy over environment variables from the builder when executing
 	// the command, However, we won't include the builder's environment
@@ -129,24 +190,198 @@ func (r *CommandRunner) runStep(ctx context.Context, step *CommandStep, dry bool
 	userEnv = append(userEnv, step.Env...)
 	userEnv = dedupEnv(userEnv)
 
-	cmdEnv := os.Environ()
+	var cmdEnv []string
+	if r.Hermetic {
+		cmdEnv = allowedEnv(r.AllowEnv)
+	} else {
+		cmdEnv = os.Environ()
+	}
 	cmdEnv = append(cmdEnv, userEnv...)
 
+	rec := &StepRecord{
+		Index:     index,
+		ID:        step.ID,
+		DependsOn: step.DependsOn,
+		Step: &CommandStep{
+			Command:    append([]string{}, step.Command...),
+			Env:        userEnv,
+			WorkingDir: pwd,
+		},
+		Policy: step.Policy,
+	}
+	if r.Hermetic {
+		rec.Step.EffectiveEnv = redactEnv(dedupEnv(cmdEnv))
+	}
+	if step.Sandbox != nil {
+		if err := step.Sandbox.validate(); err != nil {
+			return nil, err
+		}
+		rec.Step.Sandbox = step.Sandbox.Record()
+	}
+
+	if dry {
+		return rec, nil
+	}
+
+	var retries int
+	if step.Policy != nil {
+		retries = step.Policy.Retries
+	}
+	for attempt := 1; ; attempt++ {
+		rec.Attempts = attempt
+		err = r.runAttempt(ctx, rec, cmdEnv)
+		if err == nil {
+			rec.Outputs, err = outputRecords(pwd, step.Outputs)
+		}
+		if err == nil || attempt > retries || ctx.Err() != nil {
+			rec.Status = stepStatus(ctx, err)
+			return rec, err
+		}
+
+		backoff := step.Policy.backoff(attempt)
+		fmt.Fprintf(r.stderr(), "step %d failed (attempt %d of %d): %v; retrying in %s\n",
+			index, attempt, retries+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			rec.Status = StatusCanceled
+			return rec, ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// runAttempt executes the command of the step once and updates the record
+// with the results of the attempt.
+func (r *CommandRunner) runAttempt(ctx context.Context, rec *StepRecord, env []string) error {
+	var timeout time.Duration
+	if rec.Policy != nil {
+		timeout = rec.Policy.Timeout
+	}
+	attemptCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(attemptCtx, rec.Step.Command[0], rec.Step.Command[1:]...)
+	cmd.Dir = rec.Step.WorkingDir
+
 	// Set the environment for the command. Duplicates that appear later in the
 	// list override earlier entries. This is enforced by the stdlib exec package.
-	cmd.Env = cmdEnv
+	cmd.Env = env
 
-	if !dry {
-		if err := cmd.Run(); err != nil {
-			return nil, err
+	// Kill the whole process group on cancellation so that child processes
+	// don't linger, and don't wait forever on pipes they hold open.
+	setProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+
+	if rec.Step.Sandbox != nil {
+		if err := sandboxCommand(cmd, rec.Step.Sandbox); err != nil {
+			return err
 		}
 	}
 
-	return &CommandStep{
-		Command:    append([]string{name}, args...),
-		Env:        userEnv,
-		WorkingDir: pwd,
-	}, nil
+	stdoutLog, err := newLogCapture(r.LogDir, rec.Index, "stdout", r.stdout())
+	if err != nil {
+		return err
+	}
+	defer stdoutLog.Close()
+	cmd.Stdout = stdoutLog
+
+	stderrLog, err := newLogCapture(r.LogDir, rec.Index, "stderr", r.stderr())
+	if err != nil {
+		return err
+	}
+	defer stderrLog.Close()
+	cmd.Stderr = stderrLog
+
+	rec.StartedOn = time.Now().UTC()
+	err = cmd.Run()
+	rec.FinishedOn = time.Now().UTC()
+
+	rec.ExitCode = -1
+	if cmd.ProcessState != nil {
+		rec.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	rec.Stdout = stdoutLog.record()
+	rec.Stderr = stderrLog.record()
+
+	if err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s: %w", ErrTimeout, timeout, err)
+	}
+	return err
+}
+
+func (r *CommandRunner) stdout() io.Writer {
+	if r.Stdout != nil {
+		return r.Stdout
+	}
+	return os.Stdout
+}
+
+func (r *CommandRunner) stderr() io.Writer {
+	if r.Stderr != nil {
+		return r.Stderr
+	}
+	return os.Stderr
+}
+
+// allowedEnv returns the variables of the current process environment whose
+// names are in allow, in the order they are listed in allow.
+func allowedEnv(allow []string) []string {
+	env := []string{}
+	for _, k := range allow {
+		if v, ok := os.LookupEnv(k); ok {
+			env = append(env, k+"="+v)
+		}
+	}
+	return env
+}
+
+// redactedValue replaces the value of variables that look like secrets.
+const redactedValue = "***"
+
+// secretNameParts are substrings of variable names that indicate the value is
+// a secret.
+var secretNameParts = []string{
+	"TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL", "PRIVATE_KEY",
+	"API_KEY", "ACCESS_KEY", "AUTH",
+}
+
+// secretValuePrefixes are prefixes of values that indicate the value is a
+// secret regardless of the variable name.
+var secretValuePrefixes = []string{
+	"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_", "-----BEGIN",
+}
+
+// redactEnv returns a copy of env with the values of secret-looking variables
+// redacted.
+func redactEnv(env []string) []string {
+	redacted := make([]string, 0, len(env))
+	for _, e := range env {
+		k, v, found := strings.Cut(e, "=")
+		if found && isSecretEnv(k, v) {
+			e = k + "=" + redactedValue
+		}
+		redacted = append(redacted, e)
+	}
+	return redacted
+}
+
+func isSecretEnv(k, v string) bool {
+	name := strings.ToUpper(k)
+	for _, p := range secretNameParts {
+		if strings.Contains(name, p) {
+			return true
+		}
+	}
+	for _, p := range secretValuePrefixes {
+		if strings.HasPrefix(v, p) {
+			return true
+		}
+	}
+	return false
 }
 
 func dedupEnv(env []string) []string {
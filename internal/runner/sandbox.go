@@ -0,0 +1,103 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrSandboxUnsupported indicates that sandboxing is not supported on
+	// the current platform.
+	ErrSandboxUnsupported = errors.New("sandbox not supported")
+
+	// ErrInvalidSandbox indicates an invalid sandbox configuration.
+	ErrInvalidSandbox = errors.New("invalid sandbox")
+)
+
+// sandboxNamespaces are the Linux namespaces a sandboxed step is run in.
+var sandboxNamespaces = []string{"user", "mount", "network"}
+
+// Sandbox is the isolation of a step. Sandboxed steps are run in new user,
+// mount and network namespaces: they have no network access, ReadOnlyPaths
+// are mounted read-only and WritablePaths remain writable, even if they are
+// under a read-only path. Other paths are not affected.
+type Sandbox struct {
+	// ReadOnlyPaths are the absolute paths mounted read-only, e.g. the
+	// source directory.
+	ReadOnlyPaths []string `json:"readOnlyPaths,omitempty"`
+
+	// WritablePaths are the absolute paths that remain writable, e.g. the
+	// output directory.
+	WritablePaths []string `json:"writablePaths,omitempty"`
+
+	// Namespaces are the namespaces the step was run in. It is set by the
+	// runner in the returned steps.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// validate checks that the paths of the sandbox are clean absolute paths.
+func (s *Sandbox) validate() error {
+	for _, paths := range [][]string{s.ReadOnlyPaths, s.WritablePaths} {
+		for _, p := range paths {
+			if !filepath.IsAbs(p) || filepath.Clean(p) != p {
+				return fmt.Errorf("%w: %q is not a clean absolute path", ErrInvalidSandbox, p)
+			}
+		}
+	}
+	return nil
+}
+
+// Record returns the sandbox configuration recorded in the steps run in the
+// sandbox, including the namespaces.
+func (s *Sandbox) Record() *Sandbox {
+	return &Sandbox{
+		ReadOnlyPaths: append([]string(nil), s.ReadOnlyPaths...),
+		WritablePaths: append([]string(nil), s.WritablePaths...),
+		Namespaces:    append([]string(nil), sandboxNamespaces...),
+	}
+}
+
+// mountScript returns the shell script that sets up the mounts of the sandbox
+// and then executes its arguments. It is run as root in the new user and
+// mount namespaces, so mounts are not visible outside of the sandbox.
+func (s *Sandbox) mountScript(mount string) string {
+	lines := []string{
+		"set -e",
+		// Don't propagate mounts out of the sandbox.
+		fmt.Sprintf("%s --make-rprivate /", shellQuote(mount)),
+	}
+	// Writable paths are bind mounted first so that they are carried over
+	// by the recursive bind mounts of read-only paths that contain them.
+	for _, p := range s.WritablePaths {
+		lines = append(lines, fmt.Sprintf("%s --bind %s %s", shellQuote(mount), shellQuote(p), shellQuote(p)))
+	}
+	for _, p := range s.ReadOnlyPaths {
+		lines = append(lines,
+			fmt.Sprintf("%s --rbind %s %s", shellQuote(mount), shellQuote(p), shellQuote(p)),
+			fmt.Sprintf("%s -o remount,bind,ro %s", shellQuote(mount), shellQuote(p)),
+		)
+	}
+	lines = append(lines, `exec "$@"`)
+	return strings.Join(lines, "\n")
+}
+
+// shellQuote quotes s for use as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,52 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sandboxShell is the shell used to set up the sandbox's mounts.
+const sandboxShell = "/bin/sh"
+
+// sandboxCommand wraps cmd so that it is run in the sandbox. The command is
+// started in new user, mount and network namespaces, where the current user
+// is mapped to root so that the mounts can be set up before the original
+// command is executed.
+func sandboxCommand(cmd *exec.Cmd, s *Sandbox) error {
+	mount, err := exec.LookPath("mount")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrSandboxUnsupported, err)
+	}
+
+	args := []string{"sh", "-c", s.mountScript(mount), "sh", cmd.Path}
+	cmd.Args = append(args, cmd.Args[1:]...)
+	cmd.Path = sandboxShell
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWNET
+	cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: os.Getuid(), Size: 1},
+	}
+	cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: os.Getgid(), Size: 1},
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-// Copyright 2022 SLSA Authors
+// Copyright 2023 SLSA Authors
 //
 // Licensed under the Apache License, Version 2.0 (the "License");
 // you may not use this file except in compliance with the License.
@@ -12,12 +12,13 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package utils
+//go:build !linux
 
-import "os"
+package runner
 
-// IsPresubmitTests returns true if running in pre-submit tests.
-func IsPresubmitTests() bool {
-	return (os.Getenv("GITHUB_EVENT_NAME") == "pull_request" &&
-		os.Getenv("GITHUB_REPOSITORY") == "Kong/slsa-github-generator")
+import "os/exec"
+
+// sandboxCommand returns an error as namespaces are only supported on Linux.
+func sandboxCommand(*exec.Cmd, *Sandbox) error {
+	return ErrSandboxUnsupported
 }
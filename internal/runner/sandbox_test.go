@@ -0,0 +1,180 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// buildSandboxHelper builds the sandbox helper binary and skips the test if
+// sandboxes are not supported on the host, e.g. if unprivileged user
+// namespaces are disabled.
+func buildSandboxHelper(t *testing.T) string {
+	t.Helper()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is only supported on Linux")
+	}
+
+	helper := filepath.Join(t.TempDir(), "sandboxhelper")
+	cmd := exec.Command("go", "build", "-o", helper, "./testdata/sandboxhelper")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building sandbox helper: %v\n%s", err, out)
+	}
+
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command: []string{"true"},
+				Sandbox: &Sandbox{},
+			},
+		},
+	}
+	if _, err := r.Run(context.Background()); err != nil {
+		t.Skipf("sandbox not supported: %v", err)
+	}
+
+	return helper
+}
+
+func TestCommandRunner_Sandbox(t *testing.T) {
+	helper := buildSandboxHelper(t)
+
+	src := t.TempDir()
+	out := filepath.Join(src, "out")
+	if err := os.Mkdir(out, 0o700); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	// The listener is in the host's network namespace.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer l.Close()
+
+	sandbox := &Sandbox{
+		ReadOnlyPaths: []string{src},
+		WritablePaths: []string{out},
+	}
+
+	tests := []struct {
+		name    string
+		command []string
+		ok      bool
+	}{
+		{
+			name:    "write to writable path",
+			command: []string{helper, "write", filepath.Join(out, "binary")},
+			ok:      true,
+		},
+		{
+			name:    "write to read-only path",
+			command: []string{helper, "write", filepath.Join(src, "main.go")},
+		},
+		{
+			name:    "write outside of the sandbox paths",
+			command: []string{helper, "write", filepath.Join(t.TempDir(), "tmp")},
+			ok:      true,
+		},
+		{
+			name:    "network",
+			command: []string{helper, "dial", l.Addr().String()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stderr := &strings.Builder{}
+			r := CommandRunner{
+				Steps: []*CommandStep{
+					{
+						Command: tt.command,
+						Sandbox: sandbox,
+					},
+				},
+				Stderr: stderr,
+			}
+
+			records, err := r.RunRecords(context.Background())
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, stderr)
+			}
+			if !tt.ok && err == nil {
+				t.Fatalf("expected error")
+			}
+
+			want := &Sandbox{
+				ReadOnlyPaths: []string{src},
+				WritablePaths: []string{out},
+				Namespaces:    []string{"user", "mount", "network"},
+			}
+			if diff := cmp.Diff(want, records[0].Step.Sandbox); diff != "" {
+				t.Errorf("unexpected sandbox (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// The sandbox's mounts are not visible on the host.
+	if err := os.WriteFile(filepath.Join(src, "main.go"), nil, 0o600); err != nil {
+		t.Errorf("source is not writable after the sandbox: %v", err)
+	}
+}
+
+func TestCommandRunner_SandboxInvalid(t *testing.T) {
+	r := CommandRunner{
+		Steps: []*CommandStep{
+			{
+				Command: []string{"true"},
+				Sandbox: &Sandbox{
+					ReadOnlyPaths: []string{"relative/path"},
+				},
+			},
+		},
+	}
+
+	if _, err := r.Dry(context.Background()); !errors.Is(err, ErrInvalidSandbox) {
+		t.Fatalf("unexpected error, want: %v, got: %v", ErrInvalidSandbox, err)
+	}
+}
+
+func TestSandbox_mountScript(t *testing.T) {
+	s := Sandbox{
+		ReadOnlyPaths: []string{"/src"},
+		WritablePaths: []string{"/src/it's out"},
+	}
+
+	want := strings.Join([]string{
+		"set -e",
+		"'/usr/bin/mount' --make-rprivate /",
+		`'/usr/bin/mount' --bind '/src/it'\''s out' '/src/it'\''s out'`,
+		"'/usr/bin/mount' --rbind '/src' '/src'",
+		"'/usr/bin/mount' -o remount,bind,ro '/src'",
+		`exec "$@"`,
+	}, "\n")
+	if diff := cmp.Diff(want, s.mountScript("/usr/bin/mount")); diff != "" {
+		t.Errorf("unexpected script (-want +got):\n%s", diff)
+	}
+}
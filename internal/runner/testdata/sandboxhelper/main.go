@@ -0,0 +1,55 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sandboxhelper is run in the runner's sandbox tests. It performs a single
+// operation and exits with a zero status if it succeeded.
+//
+// Usage:
+//
+//	sandboxhelper write PATH
+//	sandboxhelper dial ADDRESS
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: sandboxhelper write PATH | dial ADDRESS")
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "write":
+		err = os.WriteFile(os.Args[2], []byte("sandbox"), 0o600)
+	case "dial":
+		var conn net.Conn
+		conn, err = net.DialTimeout("tcp", os.Args[2], time.Second)
+		if err == nil {
+			conn.Close()
+		}
+	default:
+		err = fmt.Errorf("unknown operation %q", os.Args[1])
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/envelope"
+)
+
+// CertSigner is a Signer implementation that signs DSSE envelopes with a
+// self-signed certificate, in the same format as Fulcio.
+type CertSigner struct {
+	signer signature.Signer
+	// CertPEM is the PEM encoded signing certificate.
+	CertPEM []byte
+}
+
+// NewCertSigner returns a CertSigner with a new key and certificate.
+func NewCertSigner(t *testing.T) *CertSigner {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &CertSigner{signer: s, CertPEM: certPEM}
+}
+
+// Sign implements Signer.Sign.
+func (s *CertSigner) Sign(ctx context.Context, p *intoto.Statement) (signing.Attestation, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return s.SignPayload(ctx, b)
+}
+
+// SignPayload implements Signer.SignPayload.
+func (s *CertSigner) SignPayload(_ context.Context, b []byte) (signing.Attestation, error) {
+	signed, err := dsse.WrapSigner(s.signer, intoto.PayloadType).SignMessage(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	att, err := envelope.AddCertToEnvelope(signed, s.CertPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &TestAttestation{CertVal: s.CertPEM, BytesVal: att}, nil
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testutil
+
+import (
+	"context"
+
+	githubapi "github.com/google/go-github/v57/github"
+
+	"github.com/Kong/slsa-github-generator/github"
+)
+
+// GithubClientProvider is a ClientProvider implementation that provides the
+// contained GitHub API client and no OIDC client.
+type GithubClientProvider struct {
+	Client *githubapi.Client
+}
+
+// OIDCClient implements ClientProvider.OIDCClient.
+func (p *GithubClientProvider) OIDCClient() (*github.OIDCClient, error) {
+	return nil, nil
+}
+
+// GithubClient implements ClientProvider.GithubClient.
+func (p *GithubClientProvider) GithubClient(context.Context) (*githubapi.Client, error) {
+	return p.Client, nil
+}
@@ -48,6 +48,11 @@ func (s TestSigner) Sign(context.Context, *intoto.Statement) (signing.Attestatio
 	return &s.Att, nil
 }
 
+// SignPayload implements Signer.SignPayload.
+func (s TestSigner) SignPayload(context.Context, []byte) (signing.Attestation, error) {
+	return &s.Att, nil
+}
+
 // TestLogEntry is a basic LogEntry implementation.
 type TestLogEntry struct {
 	IDVal       string
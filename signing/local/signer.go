@@ -0,0 +1,101 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package local signs provenance statements with a local private key.
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+
+	"github.com/Kong/slsa-github-generator/signing"
+)
+
+// KeySigner signs provenance statements with a local private key. The
+// attestations have no certificate and are verified with the public key.
+type KeySigner struct {
+	signer signature.Signer
+}
+
+// attestation is a signed attestation.
+type attestation struct {
+	att []byte
+}
+
+// Bytes returns the signed attestation as an encoded DSSE JSON envelope.
+func (a *attestation) Bytes() []byte {
+	return a.att
+}
+
+// Cert returns nil as attestations signed with a local key have no
+// certificate.
+func (a *attestation) Cert() []byte {
+	return nil
+}
+
+// NewKeySigner returns a KeySigner that signs with the given signer.
+func NewKeySigner(s signature.Signer) *KeySigner {
+	return &KeySigner{signer: s}
+}
+
+// LoadKeySigner returns a KeySigner for the PEM encoded private key in the
+// file at path. Keys encrypted by cosign are decrypted with the password.
+func LoadKeySigner(path string, password []byte) (*KeySigner, error) {
+	s, err := signature.LoadSignerFromPEMFile(path, crypto.SHA256, func(bool) ([]byte, error) {
+		return password, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading signing key: %w", err)
+	}
+	return NewKeySigner(s), nil
+}
+
+// PublicKeyPEM returns the PEM encoded public key of the signer.
+func (s *KeySigner) PublicKeyPEM() ([]byte, error) {
+	pub, err := s.signer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return cryptoutils.MarshalPublicKeyToPEM(pub)
+}
+
+// Sign signs the given provenance statement and returns the signed
+// attestation.
+func (s *KeySigner) Sign(ctx context.Context, p *intoto.Statement) (signing.Attestation, error) {
+	attBytes, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling json: %w", err)
+	}
+	return s.SignPayload(ctx, attBytes)
+}
+
+// SignPayload signs the given JSON encoded statement and returns the signed
+// attestation.
+func (s *KeySigner) SignPayload(_ context.Context, attBytes []byte) (signing.Attestation, error) {
+	signer := dsse.WrapSigner(s.signer, intoto.PayloadType)
+	signedAtt, err := signer.SignMessage(bytes.NewReader(attBytes))
+	if err != nil {
+		return nil, fmt.Errorf("signing message: %w", err)
+	}
+
+	return &attestation{att: signedAtt}, nil
+}
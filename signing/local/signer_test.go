@@ -0,0 +1,116 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package local
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/elliptic"
+	"os"
+	"path/filepath"
+	"testing"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+)
+
+func TestKeySigner(t *testing.T) {
+	password := []byte("hunter2")
+
+	testCases := []struct {
+		name     string
+		keyPass  []byte
+		password []byte
+		wantErr  bool
+	}{
+		{
+			name: "unencrypted",
+		},
+		{
+			name:     "encrypted",
+			keyPass:  password,
+			password: password,
+		},
+		{
+			name:     "wrong password",
+			keyPass:  password,
+			password: []byte("hunter3"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var pf cryptoutils.PassFunc
+			if tc.keyPass != nil {
+				pf = cryptoutils.StaticPasswordFunc(tc.keyPass)
+			}
+			privPEM, pubPEM, err := cryptoutils.GeneratePEMEncodedECDSAKeyPair(elliptic.P256(), pf)
+			if err != nil {
+				t.Fatalf("generating key: %v", err)
+			}
+			path := filepath.Join(t.TempDir(), "key.pem")
+			if err := os.WriteFile(path, privPEM, 0o600); err != nil {
+				t.Fatalf("writing key: %v", err)
+			}
+
+			s, err := LoadKeySigner(path, tc.password)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+
+			gotPub, err := s.PublicKeyPEM()
+			if err != nil {
+				t.Fatalf("PublicKeyPEM: %v", err)
+			}
+			if !bytes.Equal(pubPEM, gotPub) {
+				t.Errorf("unexpected public key, want: %s, got: %s", pubPEM, gotPub)
+			}
+
+			att, err := s.Sign(context.Background(), &intoto.Statement{
+				StatementHeader: intoto.StatementHeader{
+					Type:    intoto.StatementInTotoV01,
+					Subject: []intoto.Subject{{Name: "artifact", Digest: slsacommon.DigestSet{"sha256": "1234"}}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if att.Cert() != nil {
+				t.Errorf("unexpected certificate: %s", att.Cert())
+			}
+
+			// Verify the envelope with the public key.
+			pub, err := cryptoutils.UnmarshalPEMToPublicKey(pubPEM)
+			if err != nil {
+				t.Fatalf("UnmarshalPEMToPublicKey: %v", err)
+			}
+			v, err := signature.LoadVerifier(pub, crypto.SHA256)
+			if err != nil {
+				t.Fatalf("LoadVerifier: %v", err)
+			}
+			if err := dsse.WrapVerifier(v).VerifySignature(bytes.NewReader(att.Bytes()), nil); err != nil {
+				t.Errorf("verifying attestation: %v", err)
+			}
+		})
+	}
+}
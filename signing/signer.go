@@ -34,6 +34,10 @@ type Signer interface {
 	// Sign signs the given provenance statement and returns the signed
 	// attestation.
 	Sign(context.Context, *intoto.Statement) (Attestation, error)
+
+	// SignPayload signs the given JSON encoded in-toto statement, such as a
+	// statement with annotated subjects, and returns the signed attestation.
+	SignPayload(context.Context, []byte) (Attestation, error)
 }
 
 // LogEntry represents a transparency log entry.
@@ -0,0 +1,93 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	protodsse "github.com/sigstore/protobuf-specs/gen/pb-go/dsse"
+	"github.com/sigstore/rekor/pkg/generated/models"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/Kong/slsa-github-generator/signing"
+	"github.com/Kong/slsa-github-generator/signing/envelope"
+)
+
+// ErrInvalidEnvelope indicates an attestation that can't be bundled.
+var ErrInvalidEnvelope = errors.New("invalid envelope")
+
+// NewBundle returns the Sigstore bundle of a signed attestation in JSON
+// format. The attestation must be a DSSE envelope with a single signature and
+// its certificate, as returned by Fulcio.Sign. The log entry is included in
+// the bundle if it is a Rekor log entry.
+func NewBundle(att []byte, entry signing.LogEntry) ([]byte, error) {
+	env := &envelope.Envelope{}
+	if err := json.Unmarshal(att, env); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEnvelope, err)
+	}
+	if len(env.Signatures) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one signature", ErrInvalidEnvelope)
+	}
+
+	certs, err := cryptoutils.UnmarshalCertificatesFromPEM([]byte(env.Signatures[0].Cert))
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("%w: expected a PEM encoded certificate", ErrInvalidEnvelope)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %w", ErrInvalidEnvelope, err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %w", ErrInvalidEnvelope, err)
+	}
+
+	var logEntry *models.LogEntryAnon
+	if e, ok := entry.(*rekorEntryAnon); ok {
+		logEntry = e.entry
+	}
+	b, err := cbundle.MakeProtobufBundle("", certs[0].Raw, logEntry, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating bundle: %w", err)
+	}
+	b.Content = &protobundle.Bundle_DsseEnvelope{
+		DsseEnvelope: &protodsse.Envelope{
+			Payload:     payload,
+			PayloadType: env.PayloadType,
+			Signatures: []*protodsse.Signature{
+				{Sig: sig},
+			},
+		},
+	}
+
+	return protojson.Marshal(b)
+}
+
+// RekorBundle returns the cosign bundle of a Rekor log entry, as attached to
+// attestations in OCI registries. It returns nil if the log entry is not a
+// Rekor log entry.
+func RekorBundle(entry signing.LogEntry) *cbundle.RekorBundle {
+	e, ok := entry.(*rekorEntryAnon)
+	if !ok {
+		return nil
+	}
+	return cbundle.EntryToBundle(e.entry)
+}
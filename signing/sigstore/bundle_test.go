@@ -0,0 +1,144 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sigstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/swag"
+	"github.com/google/go-cmp/cmp"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	cbundle "github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	protobundle "github.com/sigstore/protobuf-specs/gen/pb-go/bundle/v1"
+	"github.com/sigstore/rekor/pkg/generated/models"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/Kong/slsa-github-generator/internal/testutil"
+	"github.com/Kong/slsa-github-generator/signing/envelope"
+)
+
+func TestNewBundle(t *testing.T) {
+	s := testutil.NewCertSigner(t)
+	att, err := s.Sign(context.Background(), &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{Type: intoto.StatementInTotoV01},
+	})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	b, err := NewBundle(att.Bytes(), &testutil.TestLogEntry{})
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	var bundle protobundle.Bundle
+	if err := protojson.Unmarshal(b, &bundle); err != nil {
+		t.Fatalf("unmarshaling bundle: %v", err)
+	}
+	if want, got := "application/vnd.dev.sigstore.bundle.v0.3+json", bundle.GetMediaType(); want != got {
+		t.Errorf("unexpected media type, want: %q, got: %q", want, got)
+	}
+
+	certs, err := cryptoutils.UnmarshalCertificatesFromPEM(s.CertPEM)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if got := bundle.GetVerificationMaterial().GetCertificate().GetRawBytes(); string(got) != string(certs[0].Raw) {
+		t.Errorf("unexpected certificate")
+	}
+	// A log entry that isn't from Rekor is not bundled.
+	if got := bundle.GetVerificationMaterial().GetTlogEntries(); len(got) != 0 {
+		t.Errorf("unexpected log entries: %v", got)
+	}
+
+	var env envelope.Envelope
+	if err := json.Unmarshal(att.Bytes(), &env); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	dsse := bundle.GetDsseEnvelope()
+	if want, got := env.Payload, base64.StdEncoding.EncodeToString(dsse.GetPayload()); want != got {
+		t.Errorf("unexpected payload, want: %q, got: %q", want, got)
+	}
+	if want, got := intoto.PayloadType, dsse.GetPayloadType(); want != got {
+		t.Errorf("unexpected payload type, want: %q, got: %q", want, got)
+	}
+	if want, got := env.Signatures[0].Sig, base64.StdEncoding.EncodeToString(dsse.GetSignatures()[0].GetSig()); want != got {
+		t.Errorf("unexpected signature, want: %q, got: %q", want, got)
+	}
+}
+
+func TestNewBundle_invalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		att  string
+	}{
+		{
+			name: "not json",
+			att:  "envelope",
+		},
+		{
+			name: "no signatures",
+			att:  `{"payloadType": "application/vnd.in-toto+json", "payload": "e30=", "signatures": []}`,
+		},
+		{
+			name: "no certificate",
+			att:  `{"payloadType": "application/vnd.in-toto+json", "payload": "e30=", "signatures": [{"sig": "c2ln"}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewBundle([]byte(tc.att), nil); !errors.Is(err, ErrInvalidEnvelope) {
+				t.Errorf("unexpected error, want: %v, got: %v", ErrInvalidEnvelope, err)
+			}
+		})
+	}
+}
+
+func TestRekorBundle(t *testing.T) {
+	if b := RekorBundle(&testutil.TestLogEntry{}); b != nil {
+		t.Errorf("unexpected bundle for a non-Rekor log entry: %v", b)
+	}
+
+	entry := &rekorEntryAnon{
+		entry: &models.LogEntryAnon{
+			Body:           "body",
+			IntegratedTime: swag.Int64(1),
+			LogIndex:       swag.Int64(2),
+			LogID:          swag.String("log-id"),
+			Verification: &models.LogEntryAnonVerification{
+				SignedEntryTimestamp: strfmt.Base64("set"),
+			},
+		},
+	}
+	want := &cbundle.RekorBundle{
+		SignedEntryTimestamp: strfmt.Base64("set"),
+		Payload: cbundle.RekorPayload{
+			Body:           "body",
+			IntegratedTime: 1,
+			LogIndex:       2,
+			LogID:          "log-id",
+		},
+	}
+	if diff := cmp.Diff(want, RekorBundle(entry)); diff != "" {
+		t.Errorf("unexpected bundle (-want +got):\n%s", diff)
+	}
+}
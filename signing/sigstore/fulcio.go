@@ -93,15 +93,20 @@ func (s *Fulcio) newSigner(ctx context.Context) (*fulcio.Signer, error) {
 // Sign signs the given provenance statement and returns the signed
 // attestation.
 func (s *Fulcio) Sign(ctx context.Context, p *intoto.Statement) (signing.Attestation, error) {
-	// Get Fulcio signer
-	if !providers.Enabled(ctx) {
-		return nil, fmt.Errorf("no auth provider is enabled. Are you running outside of Github Actions?")
-	}
-
 	attBytes, err := json.Marshal(p)
 	if err != nil {
 		return nil, fmt.Errorf("marshalling json: %w", err)
 	}
+	return s.SignPayload(ctx, attBytes)
+}
+
+// SignPayload signs the given JSON encoded statement and returns the signed
+// attestation.
+func (s *Fulcio) SignPayload(ctx context.Context, attBytes []byte) (signing.Attestation, error) {
+	// Get Fulcio signer
+	if !providers.Enabled(ctx) {
+		return nil, fmt.Errorf("no auth provider is enabled. Are you running outside of Github Actions?")
+	}
 
 	k, err := s.newSigner(ctx)
 	if err != nil {
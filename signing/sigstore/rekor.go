@@ -17,6 +17,7 @@ package sigstore
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/sigstore/cosign/v2/pkg/cosign"
 	"github.com/sigstore/rekor/pkg/client"
@@ -106,8 +107,8 @@ func (r *Rekor) Upload(ctx context.Context, att signing.Attestation) (signing.Lo
 		logEntry = &entry
 	}
 
-	fmt.Printf("Uploaded signed attestation to rekor with UUID %s.\n", uuid)
-	fmt.Printf("You could use rekor-cli to view the log entry details:\n\n"+
+	fmt.Fprintf(os.Stderr, "Uploaded signed attestation to rekor with UUID %s.\n", uuid)
+	fmt.Fprintf(os.Stderr, "You could use rekor-cli to view the log entry details:\n\n"+
 		"  $ rekor-cli get --uuid %[1]s\n\n"+
 		"In addition to that, you could also use the Rekor Search UI:\n\n"+
 		"  https://search.sigstore.dev/?uuid=%[1]s", uuid)
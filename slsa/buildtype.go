@@ -18,13 +18,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
+	githubapi "github.com/google/go-github/v57/github"
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	slsa "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
 	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 
 	"github.com/Kong/slsa-github-generator/github"
 )
@@ -61,6 +65,17 @@ type GithubActionsBuild struct {
 	Clients ClientProvider
 	// Subjects are the build subjects.
 	Subjects []intoto.Subject
+
+	// startedOn and finishedOn are the measured build timestamps, if any.
+	startedOn  *time.Time
+	finishedOn *time.Time
+
+	// run is the cached workflow run retrieved via the GitHub API.
+	run *githubapi.WorkflowRun
+
+	// redaction is the policy applied to the event payload and the `vars`
+	// context, if any.
+	redaction *RedactionPolicy
 }
 
 // WorkflowParameters contains parameters given to the workflow invocation.
@@ -116,21 +131,14 @@ func (b *GithubActionsBuild) getEntryPoint(ctx context.Context) (string, error)
 		return b.Context.Workflow, nil
 	}
 
-	runID, err := strconv.ParseInt(b.Context.RunID, 10, 64)
+	owner, repoName, err := b.ownerAndRepo()
 	if err != nil {
-		return "", fmt.Errorf("parsing run ID %q: %w", b.Context.RunID, err)
+		return "", err
 	}
 
-	repo := strings.SplitN(b.Context.Repository, "/", 2)
-	if len(repo) < 2 {
-		return "", fmt.Errorf("unexpected repository: %q", b.Context.Repository)
-	}
-	owner := repo[0]
-	repoName := repo[1]
-
-	wr, _, err := ghClient.Actions.GetWorkflowRunByID(ctx, owner, repoName, runID)
+	wr, err := b.workflowRun(ctx, ghClient)
 	if err != nil {
-		return "", fmt.Errorf("getting workflow run: %w", err)
+		return "", err
 	}
 
 	wf, _, err := ghClient.Actions.GetWorkflowByID(ctx, owner, repoName, wr.GetWorkflowID())
@@ -144,6 +152,41 @@ func (b *GithubActionsBuild) getEntryPoint(ctx context.Context) (string, error)
 	return *wf.Path, nil
 }
 
+// ownerAndRepo returns the owner and name of the repository that triggered the
+// workflow run.
+func (b *GithubActionsBuild) ownerAndRepo() (string, string, error) {
+	repo := strings.SplitN(b.Context.Repository, "/", 2)
+	if len(repo) < 2 {
+		return "", "", fmt.Errorf("unexpected repository: %q", b.Context.Repository)
+	}
+	return repo[0], repo[1], nil
+}
+
+// workflowRun retrieves the workflow run via the GitHub API. The run is cached
+// so that it is only retrieved once per build.
+func (b *GithubActionsBuild) workflowRun(ctx context.Context, ghClient *githubapi.Client) (*githubapi.WorkflowRun, error) {
+	if b.run != nil {
+		return b.run, nil
+	}
+
+	runID, err := strconv.ParseInt(b.Context.RunID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parsing run ID %q: %w", b.Context.RunID, err)
+	}
+
+	owner, repoName, err := b.ownerAndRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	wr, _, err := ghClient.Actions.GetWorkflowRunByID(ctx, owner, repoName, runID)
+	if err != nil {
+		return nil, fmt.Errorf("getting workflow run: %w", err)
+	}
+	b.run = wr
+	return wr, nil
+}
+
 // Invocation implements BuildType.Invocation. An invocation is returned that
 // describes the workflow run.
 // TODO: Document the basic invocation format.
@@ -163,9 +206,25 @@ func (b *GithubActionsBuild) Invocation(ctx context.Context) (slsa.ProvenanceInv
 	// workflow run.
 	addEnvKeyString(env, "github_event_name", b.Context.EventName)
 
-	// github_event_payload is the full event payload.
-	if b.Context.Event != nil {
-		env["github_event_payload"] = b.Context.Event
+	event, err := b.event()
+	if err != nil {
+		return i, err
+	}
+
+	// github_event_payload is the event payload, filtered by the redaction
+	// policy if any.
+	if event != nil {
+		env["github_event_payload"] = map[string]any(event)
+	}
+
+	// redaction_policy identifies the redaction policy applied to the event
+	// payload and the `vars` context.
+	if b.redaction != nil {
+		ref, err := b.redaction.Ref()
+		if err != nil {
+			return i, fmt.Errorf("redaction policy: %w", err)
+		}
+		env["redaction_policy"] = ref
 	}
 
 	// github_ref_type is type of ref that triggered the
@@ -205,6 +264,12 @@ func (b *GithubActionsBuild) Invocation(ctx context.Context) (slsa.ProvenanceInv
 			return i, err
 		}
 
+		// The github context is not signed, so check it against the
+		// signed claims of the token.
+		if err := t.CheckWorkflowContext(&b.Context); err != nil {
+			return i, err
+		}
+
 		// github_repository_id is the unique ID of the repository.
 		addEnvKeyString(env, "github_repository_id", t.RepositoryID)
 
@@ -235,11 +300,19 @@ func (b *GithubActionsBuild) Invocation(ctx context.Context) (slsa.ProvenanceInv
 
 	// Parameters coming from the trigger event.
 	params := WorkflowParameters{}
-	if b.Vars != nil {
-		params.VarsContext = b.Vars
+	vars, err := b.vars()
+	if err != nil {
+		return i, err
 	}
-	if b.Context.Event != nil {
-		params.EventInputs = b.Context.Event["inputs"]
+	if vars != nil {
+		params.VarsContext = vars
+	}
+	inputs, err := event.Inputs()
+	if err != nil {
+		return i, err
+	}
+	if inputs != nil {
+		params.EventInputs = inputs
 	}
 	if params.VarsContext != nil || params.EventInputs != nil {
 		i.Parameters = params
@@ -264,8 +337,11 @@ func (b *GithubActionsBuild) Materials(context.Context) ([]slsacommon.Provenance
 }
 
 // Metadata implements BuildType.Metadata. It specifies that parameters
-// are complete.
-func (b *GithubActionsBuild) Metadata(context.Context) (*slsa.ProvenanceMetadata, error) {
+// are complete and records the build timestamps. Timestamps measured by the
+// builder are used if set. Otherwise, the start time is derived from the
+// workflow run via the GitHub API, if available. The finish time is only
+// recorded if it was measured.
+func (b *GithubActionsBuild) Metadata(ctx context.Context) (*slsa.ProvenanceMetadata, error) {
 	metadata := slsa.ProvenanceMetadata{}
 
 	metadata.BuildInvocationID = b.Context.RunID
@@ -274,17 +350,119 @@ func (b *GithubActionsBuild) Metadata(context.Context) (*slsa.ProvenanceMetadata
 		metadata.BuildInvocationID = fmt.Sprintf("%s-%s", b.Context.RunID, b.Context.RunAttempt)
 	}
 
-	// NOTE: We don't check the vars context for completeness as they may not
-	// affect the build for builders.
 	if b.Context.Event != nil {
-		// Parameters come from the trigger event.
-		// If we have the event then mark parameters as complete.
-		metadata.Completeness.Parameters = true
+		// Parameters come from the trigger event and the vars context.
+		// If we have the event then mark parameters as complete, unless
+		// the redaction policy removed some of the inputs or vars.
+		event, err := b.event()
+		if err != nil {
+			return nil, err
+		}
+		vars, err := b.vars()
+		if err != nil {
+			return nil, err
+		}
+		inputs, err := b.Context.Event.Inputs()
+		if err != nil {
+			return nil, err
+		}
+		redacted, err := event.Inputs()
+		if err != nil {
+			return nil, err
+		}
+		metadata.Completeness.Parameters = reflect.DeepEqual(inputs, redacted) &&
+			reflect.DeepEqual(b.Vars, vars)
 	}
 
+	startedOn, finishedOn := b.buildTimes(ctx)
+	metadata.BuildStartedOn = startedOn
+	metadata.BuildFinishedOn = finishedOn
+
 	return &metadata, nil
 }
 
+// event returns the event payload filtered by the redaction policy.
+func (b *GithubActionsBuild) event() (github.EventPayload, error) {
+	if b.redaction == nil {
+		return b.Context.Event, nil
+	}
+	event, err := b.redaction.RedactEvent(b.Context.Event)
+	if err != nil {
+		return nil, fmt.Errorf("redacting event payload: %w", err)
+	}
+	return event, nil
+}
+
+// vars returns the `vars` context filtered by the redaction policy.
+func (b *GithubActionsBuild) vars() (github.VarsContext, error) {
+	if b.redaction == nil {
+		return b.Vars, nil
+	}
+	vars, err := b.redaction.RedactVars(b.Vars)
+	if err != nil {
+		return nil, fmt.Errorf("redacting vars context: %w", err)
+	}
+	return vars, nil
+}
+
+// BuildMetadata returns the metadata about the build in SLSA v1.0 format, for
+// use in the provenance's runDetails.
+func (b *GithubActionsBuild) BuildMetadata(ctx context.Context) (*slsa1.BuildMetadata, error) {
+	m, err := b.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &slsa1.BuildMetadata{
+		InvocationID: m.BuildInvocationID,
+		StartedOn:    m.BuildStartedOn,
+		FinishedOn:   m.BuildFinishedOn,
+	}, nil
+}
+
+// buildTimes returns the start and finish times of the build. The finish time
+// is only set if it was measured by the builder. If the start time was not
+// measured, it is derived from the workflow run on a best-effort basis, and is
+// nil if the workflow run is not available.
+func (b *GithubActionsBuild) buildTimes(ctx context.Context) (*time.Time, *time.Time) {
+	if b.startedOn != nil {
+		return b.startedOn, b.finishedOn
+	}
+
+	ghClient, err := b.Clients.GithubClient(ctx)
+	if err != nil || ghClient == nil {
+		return nil, b.finishedOn
+	}
+	wr, err := b.workflowRun(ctx, ghClient)
+	if err != nil || wr.RunStartedAt == nil {
+		return nil, b.finishedOn
+	}
+	t := wr.GetRunStartedAt().UTC()
+	return &t, b.finishedOn
+}
+
+// WithBuildTimes sets the start and finish times of the build as measured by
+// the builder. Either may be the zero time if it was not measured.
+func (b *GithubActionsBuild) WithBuildTimes(startedOn, finishedOn time.Time) *GithubActionsBuild {
+	b.startedOn, b.finishedOn = nil, nil
+	if !startedOn.IsZero() {
+		t := startedOn.UTC()
+		b.startedOn = &t
+	}
+	if !finishedOn.IsZero() {
+		t := finishedOn.UTC()
+		b.finishedOn = &t
+	}
+	return b
+}
+
+// WithRedactionPolicy sets the policy applied to the event payload and the
+// `vars` context before they are recorded in the provenance. The policy's ID
+// and digest are recorded in the invocation environment.
+func (b *GithubActionsBuild) WithRedactionPolicy(p *RedactionPolicy) *GithubActionsBuild {
+	b.redaction = p
+	return b
+}
+
 // WithClients overrides the build type's default client provider. This is
 // useful for tests where APIs are not available.
 func (b *GithubActionsBuild) WithClients(p ClientProvider) *GithubActionsBuild {
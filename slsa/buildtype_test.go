@@ -0,0 +1,241 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	githubapi "github.com/google/go-github/v57/github"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+
+	"github.com/Kong/slsa-github-generator/github"
+)
+
+// githubClientProvider provides the given GitHub API and OIDC clients.
+type githubClientProvider struct {
+	c    *githubapi.Client
+	oidc *github.OIDCClient
+}
+
+func (p *githubClientProvider) OIDCClient() (*github.OIDCClient, error) {
+	return p.oidc, nil
+}
+
+func (p *githubClientProvider) GithubClient(context.Context) (*githubapi.Client, error) {
+	return p.c, nil
+}
+
+// newFakeGithubClient returns a GitHub API client for a fake API that serves
+// the workflow run with the given ID and start time.
+func newFakeGithubClient(t *testing.T, runID int64, startedAt time.Time) *githubapi.Client {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/repos/owner/repo/actions/runs/%d", runID), func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"id":%d,"workflow_id":1,"run_started_at":%q}`, runID, startedAt.Format(time.RFC3339))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	c := githubapi.NewClient(srv.Client())
+	u, err := url.Parse(srv.URL + "/")
+	if err != nil {
+		t.Fatalf("url.Parse: %v", err)
+	}
+	c.BaseURL = u
+	return c
+}
+
+func TestGithubActionsBuild_Metadata(t *testing.T) {
+	startedOn := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+	finishedOn := time.Date(2023, 4, 14, 12, 5, 0, 0, time.UTC)
+
+	ctx := &github.WorkflowContext{
+		RunID:      "12345",
+		RunAttempt: "1",
+		Repository: "owner/repo",
+	}
+
+	testCases := []struct {
+		name     string
+		b        *GithubActionsBuild
+		expected *slsa02.ProvenanceMetadata
+	}{
+		{
+			name: "no timestamps",
+			b:    NewGithubActionsBuild(nil, ctx, nil).WithClients(&NilClientProvider{}),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "12345-1",
+			},
+		},
+		{
+			name: "measured timestamps",
+			b: NewGithubActionsBuild(nil, ctx, nil).
+				WithClients(&NilClientProvider{}).
+				WithBuildTimes(startedOn.In(time.FixedZone("CEST", 2*60*60)), finishedOn),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "12345-1",
+				BuildStartedOn:    &startedOn,
+				BuildFinishedOn:   &finishedOn,
+			},
+		},
+		{
+			name: "measured finish only",
+			b: NewGithubActionsBuild(nil, ctx, nil).
+				WithClients(&NilClientProvider{}).
+				WithBuildTimes(time.Time{}, finishedOn),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "12345-1",
+				BuildFinishedOn:   &finishedOn,
+			},
+		},
+		{
+			name: "start from workflow run",
+			b: NewGithubActionsBuild(nil, ctx, nil).
+				WithClients(&githubClientProvider{c: newFakeGithubClient(t, 12345, startedOn)}),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "12345-1",
+				BuildStartedOn:    &startedOn,
+			},
+		},
+		{
+			name: "measured finish with start from workflow run",
+			b: NewGithubActionsBuild(nil, ctx, nil).
+				WithClients(&githubClientProvider{c: newFakeGithubClient(t, 12345, startedOn)}).
+				WithBuildTimes(time.Time{}, finishedOn),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "12345-1",
+				BuildStartedOn:    &startedOn,
+				BuildFinishedOn:   &finishedOn,
+			},
+		},
+		{
+			name: "workflow run not found",
+			b: NewGithubActionsBuild(nil, ctx, nil).
+				WithClients(&githubClientProvider{c: newFakeGithubClient(t, 67890, startedOn)}).
+				WithBuildTimes(time.Time{}, finishedOn),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "12345-1",
+				BuildFinishedOn:   &finishedOn,
+			},
+		},
+		{
+			name: "invalid run ID",
+			b: NewGithubActionsBuild(nil, &github.WorkflowContext{
+				RunID:      "not-a-number",
+				RunAttempt: "1",
+				Repository: "owner/repo",
+			}, nil).WithClients(&githubClientProvider{c: newFakeGithubClient(t, 12345, startedOn)}),
+			expected: &slsa02.ProvenanceMetadata{
+				BuildInvocationID: "not-a-number-1",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := tc.b.Metadata(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, m); diff != "" {
+				t.Errorf("unexpected metadata (-want +got):\n%s", diff)
+			}
+
+			m1, err := tc.b.BuildMetadata(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected1 := &slsa1.BuildMetadata{
+				InvocationID: tc.expected.BuildInvocationID,
+				StartedOn:    tc.expected.BuildStartedOn,
+				FinishedOn:   tc.expected.BuildFinishedOn,
+			}
+			if diff := cmp.Diff(expected1, m1); diff != "" {
+				t.Errorf("unexpected v1 metadata (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGithubActionsBuild_Invocation_tokenClaims(t *testing.T) {
+	now := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		sha            string
+		jobWorkflowSHA string
+		err            error
+	}{
+		{
+			name:           "match",
+			sha:            "abcde",
+			jobWorkflowSHA: "fedcb",
+		},
+		{
+			name:           "sha mismatch",
+			sha:            "fghij",
+			jobWorkflowSHA: "fedcb",
+			err:            github.ErrContextMismatch,
+		},
+		{
+			name:           "job workflow sha mismatch",
+			sha:            "abcde",
+			jobWorkflowSHA: "01234",
+			err:            github.ErrContextMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, c := github.NewTestOIDCServer(t, now, &github.OIDCToken{
+				Audience:          []string{"owner/repo"},
+				Expiry:            now.Add(1 * time.Hour),
+				JobWorkflowRef:    "owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0",
+				JobWorkflowSHA:    "fedcb",
+				RepositoryID:      "1234",
+				RepositoryOwnerID: "4321",
+				ActorID:           "4567",
+				Repository:        "owner/repo",
+				SHA:               "abcde",
+				WorkflowRef:       "owner/repo/.github/workflows/release.yml@refs/heads/main",
+				RunID:             "12345",
+			})
+			defer s.Close()
+
+			b := NewGithubActionsBuild(nil, &github.WorkflowContext{
+				Repository:     "owner/repo",
+				SHA:            tc.sha,
+				WorkflowRef:    "owner/repo/.github/workflows/release.yml@refs/heads/main",
+				JobWorkflowSHA: tc.jobWorkflowSHA,
+				RunID:          "12345",
+			}, nil).WithClients(&githubClientProvider{oidc: c})
+
+			_, err := b.Invocation(context.Background())
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
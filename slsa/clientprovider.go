@@ -16,10 +16,12 @@ package slsa
 
 import (
 	"context"
+	"sync"
 
 	githubapi "github.com/google/go-github/v57/github"
 
 	"github.com/Kong/slsa-github-generator/github"
+	"github.com/Kong/slsa-github-generator/gitlab"
 )
 
 // ClientProvider creates Github API clients.
@@ -35,14 +37,28 @@ type DefaultClientProvider struct {
 	ghClient   *githubapi.Client
 }
 
-// OIDCClient returns a default OIDC client.
+// defaultOIDCClient is the OIDC client shared by all DefaultClientProviders so
+// that tokens are cached across build types and generators.
+var defaultOIDCClient struct {
+	sync.Mutex
+	c *github.OIDCClient
+}
+
+// OIDCClient returns a default OIDC client. The client is shared by all
+// DefaultClientProviders.
 func (p *DefaultClientProvider) OIDCClient() (*github.OIDCClient, error) {
 	if p.oidcClient == nil {
-		c, err := github.NewOIDCClient()
-		if err != nil {
-			return nil, err
+		defaultOIDCClient.Lock()
+		defer defaultOIDCClient.Unlock()
+
+		if defaultOIDCClient.c == nil {
+			c, err := github.NewOIDCClient()
+			if err != nil {
+				return nil, err
+			}
+			defaultOIDCClient.c = c
 		}
-		p.oidcClient = c
+		p.oidcClient = defaultOIDCClient.c
 	}
 	return p.oidcClient, nil
 }
@@ -60,6 +76,29 @@ func (p *DefaultClientProvider) GithubClient(ctx context.Context) (*githubapi.Cl
 	return p.ghClient, nil
 }
 
+// GitlabClientProvider creates GitLab clients.
+type GitlabClientProvider interface {
+	IDTokenClient() (*gitlab.IDTokenClient, error)
+}
+
+// DefaultGitlabClientProvider provides a default set of clients based on the
+// GitLab CI/CD environment.
+type DefaultGitlabClientProvider struct {
+	idTokenClient *gitlab.IDTokenClient
+}
+
+// IDTokenClient returns a client for the ID token of the current job.
+func (p *DefaultGitlabClientProvider) IDTokenClient() (*gitlab.IDTokenClient, error) {
+	if p.idTokenClient == nil {
+		c, err := gitlab.NewIDTokenClient()
+		if err != nil {
+			return nil, err
+		}
+		p.idTokenClient = c
+	}
+	return p.idTokenClient, nil
+}
+
 // NilClientProvider does not provide clients. It is useful for testing where
 // APIs are not available.
 type NilClientProvider struct{}
@@ -73,3 +112,8 @@ func (p *NilClientProvider) OIDCClient() (*github.OIDCClient, error) {
 func (p *NilClientProvider) GithubClient(context.Context) (*githubapi.Client, error) {
 	return nil, nil
 }
+
+// IDTokenClient returns nil for the client.
+func (p *NilClientProvider) IDTokenClient() (*gitlab.IDTokenClient, error) {
+	return nil, nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+
+	"github.com/Kong/slsa-github-generator/gitlab"
+)
+
+const (
+	// GitlabCIBuildType is the build type URI of builds run in GitLab CI/CD.
+	GitlabCIBuildType = "https://github.com/Kong/slsa-github-generator/gitlab-ci@v1"
+
+	// GitlabCIBuilderID is the builder ID used for GitLab CI/CD builds when
+	// the pipeline definition is not known from an ID token.
+	GitlabCIBuilderID = "https://gitlab.com/Attestations/GitLabCI@v1"
+
+	// GitlabIDTokenAudience is the audience the job's ID token must be issued
+	// for.
+	GitlabIDTokenAudience = "https://github.com/Kong/slsa-github-generator"
+)
+
+// GitlabCIBuild is a basic build type for builders running in GitLab CI/CD.
+type GitlabCIBuild struct {
+	// Context is the build's predefined CI/CD variables.
+	Context gitlab.CIContext
+	// Clients provides the GitLab ID token client.
+	Clients GitlabClientProvider
+	// Subjects are the build subjects.
+	Subjects []intoto.Subject
+
+	// now returns the current time. It is used as the build's finish time.
+	now func() time.Time
+}
+
+// NewGitlabCIBuild returns a new GitlabCIBuild that uses the CI context to
+// generate information.
+func NewGitlabCIBuild(s []intoto.Subject, c *gitlab.CIContext) *GitlabCIBuild {
+	return &GitlabCIBuild{
+		Subjects: s,
+		Context:  *c,
+		Clients:  &DefaultGitlabClientProvider{},
+		now:      time.Now,
+	}
+}
+
+// URI implements BuildType.URI.
+func (b *GitlabCIBuild) URI() string {
+	return GitlabCIBuildType
+}
+
+// Subject implements BuildType.Subject.
+func (b *GitlabCIBuild) Subject(context.Context) ([]intoto.Subject, error) {
+	return b.Subjects, nil
+}
+
+// BuildConfig implements BuildType.BuildConfig.
+func (b *GitlabCIBuild) BuildConfig(context.Context) (any, error) {
+	// The default build config is nil.
+	return nil, nil
+}
+
+// Invocation implements BuildType.Invocation. An invocation is returned that
+// describes the pipeline job.
+func (b *GitlabCIBuild) Invocation(ctx context.Context) (slsa02.ProvenanceInvocation, error) {
+	i := slsa02.ProvenanceInvocation{}
+
+	env := map[string]any{}
+	addEnvKeyString(env, "gitlab_pipeline_id", b.Context.PipelineID)
+	addEnvKeyString(env, "gitlab_pipeline_source", b.Context.PipelineSource)
+	addEnvKeyString(env, "gitlab_job_id", b.Context.JobID)
+	addEnvKeyString(env, "gitlab_job_name", b.Context.JobName)
+	addEnvKeyString(env, "gitlab_ref", b.Context.Ref())
+	addEnvKeyString(env, "gitlab_sha1", b.Context.CommitSHA)
+	addEnvKeyString(env, "gitlab_user_login", b.Context.UserLogin)
+	addEnvKeyString(env, "gitlab_project_namespace", b.Context.ProjectNamespace)
+
+	idTokenClient, err := b.Clients.IDTokenClient()
+	if err != nil {
+		return i, fmt.Errorf("id token client: %w", err)
+	}
+
+	if idTokenClient != nil {
+		t, err := idTokenClient.Token(ctx, []string{GitlabIDTokenAudience})
+		if err != nil {
+			return i, err
+		}
+
+		// The CI/CD variables are not signed, so check them against the
+		// signed claims of the token.
+		if err := t.CheckCIContext(&b.Context); err != nil {
+			return i, err
+		}
+
+		addEnvKeyString(env, "gitlab_project_id", t.ProjectID)
+		addEnvKeyString(env, "gitlab_namespace_id", t.NamespaceID)
+		addEnvKeyString(env, "gitlab_user_id", t.UserID)
+	}
+
+	i.Environment = env
+
+	i.ConfigSource.EntryPoint = b.Context.ConfigPath
+	i.ConfigSource.URI = b.Context.RepositoryURI()
+	if b.Context.CommitSHA != "" {
+		i.ConfigSource.Digest = slsacommon.DigestSet{
+			"sha1": b.Context.CommitSHA,
+		}
+	}
+
+	return i, nil
+}
+
+// Materials implements BuildType.Materials. It returns a list of materials
+// that includes the project the pipeline ran for.
+func (b *GitlabCIBuild) Materials(context.Context) ([]slsacommon.ProvenanceMaterial, error) {
+	var material []slsacommon.ProvenanceMaterial
+	if b.Context.RepositoryURI() != "" {
+		material = append(material, slsacommon.ProvenanceMaterial{
+			URI: b.Context.RepositoryURI(),
+			Digest: slsacommon.DigestSet{
+				"sha1": b.Context.CommitSHA,
+			},
+		})
+	}
+	return material, nil
+}
+
+// Metadata implements BuildType.Metadata. The build start time is the start
+// time of the job and the finish time is the current time.
+func (b *GitlabCIBuild) Metadata(context.Context) (*slsa02.ProvenanceMetadata, error) {
+	metadata := slsa02.ProvenanceMetadata{}
+
+	metadata.BuildInvocationID = b.Context.PipelineID
+	if b.Context.JobID != "" {
+		// NOTE: A pipeline runs many jobs, and retried jobs get a new ID.
+		metadata.BuildInvocationID = fmt.Sprintf("%s-%s", b.Context.PipelineID, b.Context.JobID)
+	}
+
+	if b.Context.JobStartedAt != "" {
+		startedOn, err := time.Parse(time.RFC3339, b.Context.JobStartedAt)
+		if err != nil {
+			return nil, fmt.Errorf("parsing job start time: %w", err)
+		}
+		startedOn = startedOn.UTC()
+
+		now := b.now
+		if now == nil {
+			now = time.Now
+		}
+		finishedOn := now().UTC()
+
+		metadata.BuildStartedOn = &startedOn
+		metadata.BuildFinishedOn = &finishedOn
+	}
+
+	return &metadata, nil
+}
+
+// WithClock overrides the function used to get the current time. This is
+// useful for tests that need deterministic timestamps.
+func (b *GitlabCIBuild) WithClock(now func() time.Time) *GitlabCIBuild {
+	b.now = now
+	return b
+}
+
+// WithClients overrides the build type's default client provider. This is
+// useful for tests where APIs are not available.
+func (b *GitlabCIBuild) WithClients(p GitlabClientProvider) *GitlabCIBuild {
+	b.Clients = p
+	return b
+}
+
+// GitlabCIGenerator is a SLSA provenance generator for GitLab CI/CD. The
+// builder ID is derived from the pipeline definition recorded in the job's ID
+// token.
+type GitlabCIGenerator struct {
+	buildType       BuildType
+	clients         GitlabClientProvider
+	allowSelfHosted bool
+}
+
+// NewGitlabCIGenerator returns a SLSA provenance generator for the given
+// build type.
+func NewGitlabCIGenerator(bt BuildType) *GitlabCIGenerator {
+	return &GitlabCIGenerator{
+		buildType: bt,
+		clients:   &DefaultGitlabClientProvider{},
+	}
+}
+
+// Generate generates an in-toto provenance statement in SLSA v0.2 format.
+func (g *GitlabCIGenerator) Generate(ctx context.Context) (*intoto.ProvenanceStatement, error) {
+	idTokenClient, err := g.clients.IDTokenClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// We allow a nil ID token client to support tests.
+	builderID := GitlabCIBuilderID
+	var runnerEnvironment string
+	if idTokenClient != nil {
+		t, err := idTokenClient.Token(ctx, []string{GitlabIDTokenAudience})
+		if err != nil {
+			return nil, err
+		}
+
+		runnerEnvironment = t.RunnerEnvironment
+		selfHosted := runnerEnvironment == gitlab.RunnerEnvironmentSelfHosted
+		if selfHosted && !g.allowSelfHosted {
+			return nil, fmt.Errorf("%w: pipeline %q", ErrSelfHostedRunner, t.CIConfigRefURI)
+		}
+
+		builderID = "https://" + t.CIConfigRefURI
+		if selfHosted {
+			builderID += selfHostedBuilderIDSuffix
+		}
+	}
+
+	subject, err := g.buildType.Subject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invocation, err := g.buildType.Invocation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buildConfig, err := g.buildType.BuildConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	materials, err := g.buildType.Materials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := g.buildType.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the runner the build ran on.
+	if env, ok := invocation.Environment.(map[string]any); ok && runnerEnvironment != "" {
+		env["gitlab_runner_environment"] = runnerEnvironment
+	}
+
+	return &intoto.ProvenanceStatement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: slsa02.PredicateSLSAProvenance,
+			Subject:       subject,
+		},
+		Predicate: slsa02.ProvenancePredicate{
+			BuildType: g.buildType.URI(),
+			Builder: slsacommon.ProvenanceBuilder{
+				ID: builderID,
+			},
+			Invocation:  invocation,
+			BuildConfig: buildConfig,
+			Materials:   materials,
+			Metadata:    metadata,
+		},
+	}, nil
+}
+
+// WithSelfHostedRunners allows generating provenance for jobs that ran on
+// self-hosted runners. The provenance then uses a self-hosted builder ID.
+func (g *GitlabCIGenerator) WithSelfHostedRunners() *GitlabCIGenerator {
+	g.allowSelfHosted = true
+	return g
+}
+
+// WithClients overrides the default GitlabClientProvider. Useful for tests
+// where clients are not available.
+func (g *GitlabCIGenerator) WithClients(c GitlabClientProvider) *GitlabCIGenerator {
+	g.clients = c
+	return g
+}
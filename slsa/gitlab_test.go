@@ -0,0 +1,211 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+
+	"github.com/Kong/slsa-github-generator/gitlab"
+)
+
+// idTokenClientProvider provides the given GitLab ID token client.
+type idTokenClientProvider struct {
+	c *gitlab.IDTokenClient
+}
+
+func (p *idTokenClientProvider) IDTokenClient() (*gitlab.IDTokenClient, error) {
+	return p.c, nil
+}
+
+func TestGitlabCIGenerator(t *testing.T) {
+	now := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+	startedOn := now.Add(-5 * time.Minute)
+
+	ciContext := gitlab.CIContext{
+		ServerURL:        "https://gitlab.com",
+		ProjectID:        "20",
+		ProjectPath:      "group/project",
+		ProjectNamespace: "group",
+		ConfigPath:       ".gitlab-ci.yml",
+		CommitSHA:        "abcde",
+		CommitRefName:    "main",
+		PipelineID:       "574",
+		PipelineSource:   "push",
+		JobID:            "302",
+		JobName:          "build",
+		JobStartedAt:     startedOn.Format(time.RFC3339),
+		UserLogin:        "user",
+	}
+	subjects := []intoto.Subject{{Name: "artifact", Digest: slsacommon.DigestSet{"sha256": "1234"}}}
+
+	token := func(runnerEnvironment, sha string) *gitlab.IDToken {
+		return &gitlab.IDToken{
+			NamespaceID:       "72",
+			ProjectID:         "20",
+			ProjectPath:       "group/project",
+			UserID:            "1",
+			PipelineID:        "574",
+			JobID:             "302",
+			SHA:               sha,
+			CIConfigRefURI:    "gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main",
+			RunnerEnvironment: runnerEnvironment,
+			Audience:          []string{GitlabIDTokenAudience},
+			Expiry:            now.Add(5 * time.Minute),
+		}
+	}
+
+	env := map[string]any{
+		"gitlab_pipeline_id":       "574",
+		"gitlab_pipeline_source":   "push",
+		"gitlab_job_id":            "302",
+		"gitlab_job_name":          "build",
+		"gitlab_ref":               "refs/heads/main",
+		"gitlab_sha1":              "abcde",
+		"gitlab_user_login":        "user",
+		"gitlab_project_namespace": "group",
+	}
+	tokenEnv := func(runnerEnvironment string) map[string]any {
+		m := map[string]any{
+			"gitlab_project_id":         "20",
+			"gitlab_namespace_id":       "72",
+			"gitlab_user_id":            "1",
+			"gitlab_runner_environment": runnerEnvironment,
+		}
+		for k, v := range env {
+			m[k] = v
+		}
+		return m
+	}
+
+	testCases := []struct {
+		name            string
+		token           *gitlab.IDToken
+		allowSelfHosted bool
+		builderID       string
+		env             map[string]any
+		err             error
+	}{
+		{
+			name:      "no token",
+			builderID: GitlabCIBuilderID,
+			env:       env,
+		},
+		{
+			name:      "gitlab hosted",
+			token:     token(gitlab.RunnerEnvironmentGitlabHosted, "abcde"),
+			builderID: "https://gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main",
+			env:       tokenEnv(gitlab.RunnerEnvironmentGitlabHosted),
+		},
+		{
+			name:  "self-hosted not allowed",
+			token: token(gitlab.RunnerEnvironmentSelfHosted, "abcde"),
+			err:   ErrSelfHostedRunner,
+		},
+		{
+			name:            "self-hosted allowed",
+			token:           token(gitlab.RunnerEnvironmentSelfHosted, "abcde"),
+			allowSelfHosted: true,
+			builderID:       "https://gitlab.com/group/project//.gitlab-ci.yml@refs/heads/main?runner_environment=self-hosted",
+			env:             tokenEnv(gitlab.RunnerEnvironmentSelfHosted),
+		},
+		{
+			name:  "context mismatch",
+			token: token(gitlab.RunnerEnvironmentGitlabHosted, "fghij"),
+			err:   gitlab.ErrContextMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var provider GitlabClientProvider = &NilClientProvider{}
+			if tc.token != nil {
+				s, c := gitlab.NewTestIDTokenServer(t, now, tc.token)
+				defer s.Close()
+				provider = &idTokenClientProvider{c: c}
+			}
+
+			b := NewGitlabCIBuild(subjects, &ciContext).
+				WithClients(provider).
+				WithClock(func() time.Time { return now })
+			g := NewGitlabCIGenerator(b).WithClients(provider)
+			if tc.allowSelfHosted {
+				g.WithSelfHostedRunners()
+			}
+
+			p, err := g.Generate(context.Background())
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			expected := &intoto.ProvenanceStatement{
+				StatementHeader: intoto.StatementHeader{
+					Type:          intoto.StatementInTotoV01,
+					PredicateType: slsa02.PredicateSLSAProvenance,
+					Subject:       subjects,
+				},
+				Predicate: slsa02.ProvenancePredicate{
+					BuildType: GitlabCIBuildType,
+					Builder: slsacommon.ProvenanceBuilder{
+						ID: tc.builderID,
+					},
+					Invocation: slsa02.ProvenanceInvocation{
+						ConfigSource: slsa02.ConfigSource{
+							URI:        "git+https://gitlab.com/group/project@refs/heads/main",
+							Digest:     slsacommon.DigestSet{"sha1": "abcde"},
+							EntryPoint: ".gitlab-ci.yml",
+						},
+						Environment: tc.env,
+					},
+					Materials: []slsacommon.ProvenanceMaterial{
+						{
+							URI:    "git+https://gitlab.com/group/project@refs/heads/main",
+							Digest: slsacommon.DigestSet{"sha1": "abcde"},
+						},
+					},
+					Metadata: &slsa02.ProvenanceMetadata{
+						BuildInvocationID: "574-302",
+						BuildStartedOn:    &startedOn,
+						BuildFinishedOn:   &now,
+					},
+				},
+			}
+			if diff := cmp.Diff(expected, p); diff != "" {
+				t.Errorf("unexpected provenance (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGitlabCIBuild_Metadata_invalidStartTime(t *testing.T) {
+	b := NewGitlabCIBuild(nil, &gitlab.CIContext{
+		PipelineID:   "574",
+		JobStartedAt: "yesterday",
+	}).WithClients(&NilClientProvider{})
+
+	if _, err := b.Metadata(context.Background()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"context"
+	"time"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+)
+
+const (
+	// LocalBuildType is the build type URI of builds run on a developer's
+	// machine.
+	LocalBuildType = "https://github.com/Kong/slsa-github-generator/local@v1"
+
+	// LocalBuilderID is the builder ID of builds run on a developer's machine.
+	// The builder is not a hosted build platform and its provenance should
+	// not be trusted as such.
+	LocalBuilderID = "https://github.com/Kong/slsa-github-generator/local-builder@v1"
+)
+
+// GitState is the state of a local git working tree.
+type GitState struct {
+	// Commit is the commit SHA checked out in the working tree.
+	Commit string `json:"commit"`
+
+	// Ref is the ref checked out in the working tree, if any.
+	Ref string `json:"ref"`
+
+	// RemoteURL is the URL of the repository's origin remote, if any.
+	RemoteURL string `json:"remote_url"`
+
+	// Dirty is whether the working tree has uncommitted changes.
+	Dirty bool `json:"dirty"`
+}
+
+// RepositoryURI returns a full repository URI for the working tree's
+// repository.
+func (s *GitState) RepositoryURI() string {
+	if s.RemoteURL == "" {
+		return ""
+	}
+	uri := "git+" + s.RemoteURL
+	if s.Ref != "" {
+		uri += "@" + s.Ref
+	}
+	return uri
+}
+
+// LocalBuild is a build type for builds run outside of a hosted build
+// platform, such as on a developer's machine. The provenance is derived from
+// the state of the git working tree, which is not verified.
+type LocalBuild struct {
+	// Git is the state of the working tree the build ran in.
+	Git GitState
+	// Subjects are the build subjects.
+	Subjects []intoto.Subject
+
+	// now returns the current time. It is used as the build's finish time.
+	now func() time.Time
+}
+
+// NewLocalBuild returns a new LocalBuild for the working tree state.
+func NewLocalBuild(s []intoto.Subject, git *GitState) *LocalBuild {
+	return &LocalBuild{
+		Subjects: s,
+		Git:      *git,
+		now:      time.Now,
+	}
+}
+
+// URI implements BuildType.URI.
+func (b *LocalBuild) URI() string {
+	return LocalBuildType
+}
+
+// Subject implements BuildType.Subject.
+func (b *LocalBuild) Subject(context.Context) ([]intoto.Subject, error) {
+	return b.Subjects, nil
+}
+
+// BuildConfig implements BuildType.BuildConfig.
+func (b *LocalBuild) BuildConfig(context.Context) (any, error) {
+	// The default build config is nil.
+	return nil, nil
+}
+
+// Invocation implements BuildType.Invocation. The invocation records the
+// working tree the build ran in.
+func (b *LocalBuild) Invocation(context.Context) (slsa02.ProvenanceInvocation, error) {
+	i := slsa02.ProvenanceInvocation{}
+
+	i.Environment = map[string]any{
+		// local_git_dirty records whether the build included uncommitted
+		// changes, in which case the sources don't match the commit.
+		"local_git_dirty": b.Git.Dirty,
+	}
+
+	i.ConfigSource.URI = b.Git.RepositoryURI()
+	if b.Git.Commit != "" {
+		i.ConfigSource.Digest = slsacommon.DigestSet{
+			"sha1": b.Git.Commit,
+		}
+	}
+	return i, nil
+}
+
+// Materials implements BuildType.Materials. It returns the working tree's
+// repository and commit, if known.
+func (b *LocalBuild) Materials(context.Context) ([]slsacommon.ProvenanceMaterial, error) {
+	var material []slsacommon.ProvenanceMaterial
+	if b.Git.RepositoryURI() != "" && b.Git.Commit != "" {
+		material = append(material, slsacommon.ProvenanceMaterial{
+			URI: b.Git.RepositoryURI(),
+			Digest: slsacommon.DigestSet{
+				"sha1": b.Git.Commit,
+			},
+		})
+	}
+	return material, nil
+}
+
+// Metadata implements BuildType.Metadata. The materials are only complete if
+// the working tree is clean.
+func (b *LocalBuild) Metadata(context.Context) (*slsa02.ProvenanceMetadata, error) {
+	now := b.now
+	if now == nil {
+		now = time.Now
+	}
+	finishedOn := now().UTC()
+
+	metadata := slsa02.ProvenanceMetadata{
+		BuildFinishedOn: &finishedOn,
+	}
+	metadata.Completeness.Materials = b.Git.Commit != "" && !b.Git.Dirty
+	return &metadata, nil
+}
+
+// WithClock overrides the function used to get the current time. This is
+// useful for tests that need deterministic timestamps.
+func (b *LocalBuild) WithClock(now func() time.Time) *LocalBuild {
+	b.now = now
+	return b
+}
+
+// LocalGenerator is a SLSA provenance generator for builds run outside of a
+// hosted build platform. The provenance uses LocalBuilderID and is not
+// backed by any platform identity.
+type LocalGenerator struct {
+	buildType BuildType
+}
+
+// NewLocalGenerator returns a SLSA provenance generator for the given build
+// type.
+func NewLocalGenerator(bt BuildType) *LocalGenerator {
+	return &LocalGenerator{buildType: bt}
+}
+
+// Generate generates an in-toto provenance statement in SLSA v0.2 format.
+func (g *LocalGenerator) Generate(ctx context.Context) (*intoto.ProvenanceStatement, error) {
+	subject, err := g.buildType.Subject(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	invocation, err := g.buildType.Invocation(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buildConfig, err := g.buildType.BuildConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	materials, err := g.buildType.Materials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := g.buildType.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &intoto.ProvenanceStatement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: slsa02.PredicateSLSAProvenance,
+			Subject:       subject,
+		},
+		Predicate: slsa02.ProvenancePredicate{
+			BuildType: g.buildType.URI(),
+			Builder: slsacommon.ProvenanceBuilder{
+				ID: LocalBuilderID,
+			},
+			Invocation:  invocation,
+			BuildConfig: buildConfig,
+			Materials:   materials,
+			Metadata:    metadata,
+		},
+	}, nil
+}
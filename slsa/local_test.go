@@ -0,0 +1,103 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+)
+
+func TestLocalGenerator(t *testing.T) {
+	now := time.Date(2023, 4, 14, 12, 0, 0, 0, time.UTC)
+	subjects := []intoto.Subject{{Name: "artifact", Digest: slsacommon.DigestSet{"sha256": "1234"}}}
+
+	testCases := []struct {
+		name     string
+		git      GitState
+		expected slsa02.ProvenancePredicate
+	}{
+		{
+			name: "clean",
+			git: GitState{
+				Commit:    "abcde",
+				Ref:       "refs/heads/main",
+				RemoteURL: "https://github.com/owner/repo.git",
+			},
+			expected: slsa02.ProvenancePredicate{
+				Invocation: slsa02.ProvenanceInvocation{
+					ConfigSource: slsa02.ConfigSource{
+						URI:    "git+https://github.com/owner/repo.git@refs/heads/main",
+						Digest: slsacommon.DigestSet{"sha1": "abcde"},
+					},
+					Environment: map[string]any{"local_git_dirty": false},
+				},
+				Materials: []slsacommon.ProvenanceMaterial{
+					{
+						URI:    "git+https://github.com/owner/repo.git@refs/heads/main",
+						Digest: slsacommon.DigestSet{"sha1": "abcde"},
+					},
+				},
+				Metadata: &slsa02.ProvenanceMetadata{
+					BuildFinishedOn: &now,
+					Completeness:    slsa02.ProvenanceComplete{Materials: true},
+				},
+			},
+		},
+		{
+			name: "dirty without remote",
+			git: GitState{
+				Commit: "abcde",
+				Dirty:  true,
+			},
+			expected: slsa02.ProvenancePredicate{
+				Invocation: slsa02.ProvenanceInvocation{
+					ConfigSource: slsa02.ConfigSource{
+						Digest: slsacommon.DigestSet{"sha1": "abcde"},
+					},
+					Environment: map[string]any{"local_git_dirty": true},
+				},
+				Metadata: &slsa02.ProvenanceMetadata{
+					BuildFinishedOn: &now,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewLocalBuild(subjects, &tc.git).WithClock(func() time.Time { return now })
+			p, err := NewLocalGenerator(b).Generate(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			expected := tc.expected
+			expected.BuildType = LocalBuildType
+			expected.Builder.ID = LocalBuilderID
+			if diff := cmp.Diff(subjects, p.Subject); diff != "" {
+				t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(expected, p.Predicate); diff != "" {
+				t.Errorf("unexpected predicate (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
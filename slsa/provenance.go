@@ -16,29 +16,52 @@ package slsa
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
+
+	"github.com/Kong/slsa-github-generator/github"
 )
 
 const (
 	// GithubHostedActionsBuilderID is a default builder ID for Github hosted actions.
 	GithubHostedActionsBuilderID = "https://github.com/Attestations/GitHubHostedActions@v1"
+
+	// GithubSelfHostedActionsBuilderID is a default builder ID for Github
+	// actions run on self-hosted runners.
+	GithubSelfHostedActionsBuilderID = "https://github.com/Attestations/GitHubSelfHostedActions@v1"
+
+	// selfHostedBuilderIDSuffix is appended to the ID of reusable workflow
+	// builders run on self-hosted runners.
+	selfHostedBuilderIDSuffix = "?runner_environment=self-hosted"
 )
 
+// ErrSelfHostedRunner indicates that the build ran on a self-hosted runner
+// but self-hosted runners are not allowed.
+var ErrSelfHostedRunner = errors.New("self-hosted runners are not allowed")
+
 var githubComReplace = regexp.MustCompile(`^(https?://)?github\.com/?`)
 
+// Generator generates SLSA provenance for a build.
+type Generator interface {
+	// Generate generates an in-toto provenance statement.
+	Generate(context.Context) (*intoto.ProvenanceStatement, error)
+}
+
 // HostedActionsGenerator is a SLSA provenance generator for Github Hosted
 // Actions. Provenance is generated based on a "build type" which defines the
 // format for many of the fields in the provenance metadata. Builders for
 // different ecosystems (languages etc.) can implement a build type from
 // scratch or by extending GithubActionsBuild.
 type HostedActionsGenerator struct {
-	buildType BuildType
-	clients   ClientProvider
+	buildType       BuildType
+	clients         ClientProvider
+	allowSelfHosted bool
+	runner          github.RunnerContext
 }
 
 // NewHostedActionsGenerator returns a SLSA provenance generator for the given build type.
@@ -46,6 +69,7 @@ func NewHostedActionsGenerator(bt BuildType) *HostedActionsGenerator {
 	return &HostedActionsGenerator{
 		buildType: bt,
 		clients:   &DefaultClientProvider{},
+		runner:    github.GetRunnerContext(),
 	}
 }
 
@@ -63,14 +87,26 @@ func (g *HostedActionsGenerator) Generate(ctx context.Context) (*intoto.Provenan
 
 	// We allow nil OIDC client to support e2e tests on pull requests.
 	builderID := GithubHostedActionsBuilderID
+	var runnerEnvironment string
 	if oidcClient != nil {
 		t, err := oidcClient.Token(ctx, []string{audience})
 		if err != nil {
 			return nil, err
 		}
 
-		if t.JobWorkflowRef != "" {
+		runnerEnvironment = t.RunnerEnvironment
+		selfHosted := runnerEnvironment == github.RunnerEnvironmentSelfHosted
+		if selfHosted && !g.allowSelfHosted {
+			return nil, fmt.Errorf("%w: job %q", ErrSelfHostedRunner, t.JobWorkflowRef)
+		}
+
+		switch {
+		case t.JobWorkflowRef != "" && selfHosted:
+			builderID = fmt.Sprintf("https://github.com/%s%s", t.JobWorkflowRef, selfHostedBuilderIDSuffix)
+		case t.JobWorkflowRef != "":
 			builderID = fmt.Sprintf("https://github.com/%s", t.JobWorkflowRef)
+		case selfHosted:
+			builderID = GithubSelfHostedActionsBuilderID
 		}
 	}
 
@@ -99,6 +135,16 @@ func (g *HostedActionsGenerator) Generate(ctx context.Context) (*intoto.Provenan
 		return nil, err
 	}
 
+	// Record the runner the build ran on.
+	if env, ok := invocation.Environment.(map[string]any); ok && runnerEnvironment != "" {
+		env["github_runner_environment"] = runnerEnvironment
+		if runnerEnvironment == github.RunnerEnvironmentSelfHosted {
+			env["github_runner_name"] = g.runner.Name
+			env["github_runner_os"] = g.runner.OS
+			env["github_runner_arch"] = g.runner.Arch
+		}
+	}
+
 	return &intoto.ProvenanceStatement{
 		StatementHeader: intoto.StatementHeader{
 			Type:          intoto.StatementInTotoV01,
@@ -118,6 +164,15 @@ func (g *HostedActionsGenerator) Generate(ctx context.Context) (*intoto.Provenan
 	}, nil
 }
 
+// WithSelfHostedRunners allows generating provenance for builds that ran on
+// self-hosted runners. The provenance then uses a self-hosted builder ID and
+// records the runner.
+func (g *HostedActionsGenerator) WithSelfHostedRunners(runner github.RunnerContext) *HostedActionsGenerator {
+	g.allowSelfHosted = true
+	g.runner = runner
+	return g
+}
+
 // WithClients overrides the default ClientProvider. Useful for tests where
 // clients are not available.
 func (g *HostedActionsGenerator) WithClients(c ClientProvider) *HostedActionsGenerator {
@@ -16,10 +16,12 @@ package slsa
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	githubapi "github.com/google/go-github/v57/github"
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
 	slsa02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
@@ -31,6 +33,19 @@ var (
 	testBuildConfig = "test build config"
 )
 
+// oidcClientProvider provides the given OIDC client and no GitHub API client.
+type oidcClientProvider struct {
+	c *github.OIDCClient
+}
+
+func (p *oidcClientProvider) OIDCClient() (*github.OIDCClient, error) {
+	return p.c, nil
+}
+
+func (p *oidcClientProvider) GithubClient(context.Context) (*githubapi.Client, error) {
+	return nil, nil
+}
+
 type TestBuild struct {
 	*GithubActionsBuild
 }
@@ -399,3 +414,95 @@ func TestHostedActionsProvenance(t *testing.T) {
 		})
 	}
 }
+
+func TestHostedActionsGenerator_runnerEnvironment(t *testing.T) {
+	now := time.Date(2022, 4, 14, 12, 24, 0, 0, time.UTC)
+	runner := github.RunnerContext{Name: "runner-1", OS: "Linux", Arch: "X64"}
+
+	testCases := []struct {
+		name              string
+		runnerEnvironment string
+		jobWorkflowRef    string
+		allowSelfHosted   bool
+		builderID         string
+		env               map[string]any
+		err               error
+	}{
+		{
+			name:              "github-hosted",
+			runnerEnvironment: github.RunnerEnvironmentGithubHosted,
+			jobWorkflowRef:    "owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0",
+			builderID:         "https://github.com/owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0",
+			env: map[string]any{
+				"github_runner_environment": github.RunnerEnvironmentGithubHosted,
+			},
+		},
+		{
+			name:              "self-hosted not allowed",
+			runnerEnvironment: github.RunnerEnvironmentSelfHosted,
+			jobWorkflowRef:    "owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0",
+			err:               ErrSelfHostedRunner,
+		},
+		{
+			name:              "self-hosted allowed",
+			runnerEnvironment: github.RunnerEnvironmentSelfHosted,
+			jobWorkflowRef:    "owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0",
+			allowSelfHosted:   true,
+			builderID:         "https://github.com/owner/builder/.github/workflows/builder.yml@refs/tags/v1.0.0?runner_environment=self-hosted",
+			env: map[string]any{
+				"github_runner_environment": github.RunnerEnvironmentSelfHosted,
+				"github_runner_name":        "runner-1",
+				"github_runner_os":          "Linux",
+				"github_runner_arch":        "X64",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, c := github.NewTestOIDCServer(t, now, &github.OIDCToken{
+				Audience:          []string{testBuildType},
+				Expiry:            now.Add(1 * time.Hour),
+				JobWorkflowRef:    tc.jobWorkflowRef,
+				RepositoryID:      "1234",
+				RepositoryOwnerID: "4321",
+				ActorID:           "4567",
+				RunnerEnvironment: tc.runnerEnvironment,
+			})
+			defer s.Close()
+
+			b := &TestBuild{
+				GithubActionsBuild: NewGithubActionsBuild(
+					nil, &github.WorkflowContext{}, nil).WithClients(&NilClientProvider{}),
+			}
+			g := NewHostedActionsGenerator(b).WithClients(&oidcClientProvider{c: c})
+			if tc.allowSelfHosted {
+				g.WithSelfHostedRunners(runner)
+			}
+
+			p, err := g.Generate(context.Background())
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+
+			if want, got := tc.builderID, p.Predicate.Builder.ID; want != got {
+				t.Errorf("unexpected builder ID, want: %q, got: %q", want, got)
+			}
+			env, ok := p.Predicate.Invocation.Environment.(map[string]any)
+			if !ok {
+				t.Fatalf("unexpected environment type: %T", p.Predicate.Invocation.Environment)
+			}
+			for k, want := range tc.env {
+				if got := env[k]; want != got {
+					t.Errorf("unexpected %q, want: %v, got: %v", k, want, got)
+				}
+			}
+			if _, ok := env["github_runner_name"]; ok && tc.env["github_runner_name"] == nil {
+				t.Errorf("unexpected runner name recorded for hosted runner")
+			}
+		})
+	}
+}
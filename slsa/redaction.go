@@ -0,0 +1,354 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     https://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/Kong/slsa-github-generator/github"
+)
+
+// DefaultRedactionPolicyID is the ID of DefaultRedactionPolicy.
+const DefaultRedactionPolicyID = "https://github.com/Kong/slsa-github-generator/redaction/default@v1"
+
+// wildcard matches any object key or array element in a path.
+const wildcard = "*"
+
+// ErrInvalidRedactionPolicy indicates that a redaction policy is invalid.
+var ErrInvalidRedactionPolicy = errors.New("invalid redaction policy")
+
+// DefaultRedactionPolicy removes free-form text and email addresses from
+// the event payload. They are not needed to verify the build and should not
+// be published in a transparency log.
+var DefaultRedactionPolicy = &RedactionPolicy{
+	ID: DefaultRedactionPolicyID,
+	EventPayload: PathFilter{
+		Exclude: []string{
+			"$.pusher.email",
+			"$.head_commit.author.email",
+			"$.head_commit.committer.email",
+			"$.commits[*].author.email",
+			"$.commits[*].committer.email",
+			"$.pull_request.body",
+			"$.issue.body",
+			"$.comment.body",
+			"$.review.body",
+			"$.release.body",
+		},
+	},
+}
+
+// RedactionPolicy selects the parts of the event payload and the `vars`
+// context that are recorded in the provenance.
+type RedactionPolicy struct {
+	// ID identifies the policy, e.g. a URI. It is recorded in the provenance
+	// along with the digest of the policy.
+	ID string `json:"id"`
+
+	// EventPayload is the filter applied to the event payload.
+	EventPayload PathFilter `json:"eventPayload"`
+
+	// Vars is the filter applied to the `vars` context.
+	Vars PathFilter `json:"vars"`
+}
+
+// PathFilter is an include/exclude filter on a JSON document. Paths are in
+// JSON path style, e.g. "$.pull_request.body" or "$.commits[*].author.email".
+// Object keys and array indices may be replaced with the "*" wildcard.
+type PathFilter struct {
+	// Include are the paths that are kept. If empty, the whole document is
+	// kept.
+	Include []string `json:"include,omitempty"`
+
+	// Exclude are the paths that are removed. They are applied after
+	// Include.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// RedactionPolicyRef identifies the redaction policy applied to the
+// provenance.
+type RedactionPolicyRef struct {
+	// ID is the ID of the policy.
+	ID string `json:"id"`
+
+	// Digest is the digest of the JSON encoding of the policy.
+	Digest slsacommon.DigestSet `json:"digest"`
+}
+
+// ParseRedactionPolicy parses and validates a JSON-encoded redaction policy.
+func ParseRedactionPolicy(b []byte) (*RedactionPolicy, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	var p RedactionPolicy
+	if err := d.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: json.Decode: %w", ErrInvalidRedactionPolicy, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Validate checks that the policy has an ID and that its paths are valid.
+func (p *RedactionPolicy) Validate() error {
+	if p.ID == "" {
+		return fmt.Errorf("%w: empty id", ErrInvalidRedactionPolicy)
+	}
+	for _, f := range []PathFilter{p.EventPayload, p.Vars} {
+		if _, _, err := f.compile(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ref returns the reference to the policy that is recorded in the provenance.
+func (p *RedactionPolicy) Ref() (*RedactionPolicyRef, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return &RedactionPolicyRef{
+		ID: p.ID,
+		Digest: slsacommon.DigestSet{
+			"sha256": hex.EncodeToString(sum[:]),
+		},
+	}, nil
+}
+
+// RedactEvent returns a copy of the event payload filtered by the policy.
+func (p *RedactionPolicy) RedactEvent(event map[string]any) (map[string]any, error) {
+	if event == nil {
+		return nil, nil
+	}
+	v, err := p.EventPayload.apply(event)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]any)
+	if m == nil {
+		m = map[string]any{}
+	}
+	return m, nil
+}
+
+// RedactVars returns a copy of the `vars` context filtered by the policy.
+func (p *RedactionPolicy) RedactVars(vars github.VarsContext) (github.VarsContext, error) {
+	if vars == nil {
+		return nil, nil
+	}
+	m := make(map[string]any, len(vars))
+	for k, v := range vars {
+		m[k] = v
+	}
+	v, err := p.Vars.apply(m)
+	if err != nil {
+		return nil, err
+	}
+	filtered, _ := v.(map[string]any)
+	redacted := github.VarsContext{}
+	for k, v := range filtered {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: vars: unexpected type %T for %q", ErrInvalidRedactionPolicy, v, k)
+		}
+		redacted[k] = s
+	}
+	return redacted, nil
+}
+
+// apply returns a copy of v filtered by f.
+func (f PathFilter) apply(v any) (any, error) {
+	include, exclude, err := f.compile()
+	if err != nil {
+		return nil, err
+	}
+	if len(include) > 0 {
+		var ok bool
+		if v, ok = includePaths(v, include); !ok {
+			return nil, nil
+		}
+	}
+	return excludePaths(v, exclude), nil
+}
+
+// compile parses the include and exclude paths of f.
+func (f PathFilter) compile() ([][]string, [][]string, error) {
+	include, err := parsePaths(f.Include)
+	if err != nil {
+		return nil, nil, err
+	}
+	exclude, err := parsePaths(f.Exclude)
+	if err != nil {
+		return nil, nil, err
+	}
+	return include, exclude, nil
+}
+
+func parsePaths(paths []string) ([][]string, error) {
+	var parsed [][]string
+	for _, p := range paths {
+		segments, err := parsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, segments)
+	}
+	return parsed, nil
+}
+
+// parsePath splits a path such as "$.commits[*].author" into its segments,
+// e.g. ["commits", "*", "author"].
+func parsePath(p string) ([]string, error) {
+	rest, ok := strings.CutPrefix(p, "$")
+	if !ok {
+		return nil, fmt.Errorf("%w: path %q does not start with \"$\"", ErrInvalidRedactionPolicy, p)
+	}
+
+	var segments []string
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("%w: empty key in path %q", ErrInvalidRedactionPolicy, p)
+			}
+			segments = append(segments, key)
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated index in path %q", ErrInvalidRedactionPolicy, p)
+			}
+			index := rest[1:end]
+			if index != wildcard {
+				if _, err := strconv.ParseUint(index, 10, 32); err != nil {
+					return nil, fmt.Errorf("%w: invalid index %q in path %q", ErrInvalidRedactionPolicy, index, p)
+				}
+			}
+			segments = append(segments, index)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("%w: unexpected %q in path %q", ErrInvalidRedactionPolicy, rest[0], p)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: path %q selects the whole document", ErrInvalidRedactionPolicy, p)
+	}
+	return segments, nil
+}
+
+// subPaths returns the remainder of the paths whose first segment matches key.
+// It reports whether one of the paths ends at key.
+func subPaths(paths [][]string, key string) ([][]string, bool) {
+	var sub [][]string
+	for _, p := range paths {
+		if p[0] != wildcard && p[0] != key {
+			continue
+		}
+		if len(p) == 1 {
+			return nil, true
+		}
+		sub = append(sub, p[1:])
+	}
+	return sub, false
+}
+
+// includePaths returns the parts of v selected by paths. It reports whether
+// any part of v was selected.
+func includePaths(v any, paths [][]string) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		m := map[string]any{}
+		for k, e := range v {
+			sub, all := subPaths(paths, k)
+			if all {
+				m[k] = e
+				continue
+			}
+			if len(sub) == 0 {
+				continue
+			}
+			if e, ok := includePaths(e, sub); ok {
+				m[k] = e
+			}
+		}
+		return m, len(m) > 0
+	case []any:
+		var a []any
+		for i, e := range v {
+			sub, all := subPaths(paths, strconv.Itoa(i))
+			if all {
+				a = append(a, e)
+				continue
+			}
+			if len(sub) == 0 {
+				continue
+			}
+			if e, ok := includePaths(e, sub); ok {
+				a = append(a, e)
+			}
+		}
+		return a, len(a) > 0
+	default:
+		// Paths go deeper than the document.
+		return nil, false
+	}
+}
+
+// excludePaths returns a copy of v without the parts selected by paths.
+func excludePaths(v any, paths [][]string) any {
+	if len(paths) == 0 {
+		return v
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			sub, all := subPaths(paths, k)
+			if all {
+				continue
+			}
+			m[k] = excludePaths(e, sub)
+		}
+		return m
+	case []any:
+		a := make([]any, 0, len(v))
+		for i, e := range v {
+			sub, all := subPaths(paths, strconv.Itoa(i))
+			if all {
+				continue
+			}
+			a = append(a, excludePaths(e, sub))
+		}
+		return a
+	default:
+		return v
+	}
+}
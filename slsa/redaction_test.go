@@ -0,0 +1,385 @@
+// Copyright 2023 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slsa
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/Kong/slsa-github-generator/github"
+)
+
+func testEvent() map[string]any {
+	return map[string]any{
+		"action": "opened",
+		"inputs": map[string]any{
+			"version": "v1.0.0",
+		},
+		"pull_request": map[string]any{
+			"number": 1,
+			"title":  "Fix build",
+			"body":   "Private details",
+		},
+		"commits": []any{
+			map[string]any{
+				"id":     "abcde",
+				"author": map[string]any{"name": "user", "email": "user@example.com"},
+			},
+			map[string]any{
+				"id":     "fghij",
+				"author": map[string]any{"name": "other", "email": "other@example.com"},
+			},
+		},
+	}
+}
+
+func TestParseRedactionPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		policy   string
+		expected *RedactionPolicy
+		err      error
+	}{
+		{
+			name:   "valid",
+			policy: `{"id":"policy","eventPayload":{"include":["$.commits[*].id","$.inputs"],"exclude":["$.commits[0]"]},"vars":{"exclude":["$.*"]}}`,
+			expected: &RedactionPolicy{
+				ID: "policy",
+				EventPayload: PathFilter{
+					Include: []string{"$.commits[*].id", "$.inputs"},
+					Exclude: []string{"$.commits[0]"},
+				},
+				Vars: PathFilter{
+					Exclude: []string{"$.*"},
+				},
+			},
+		},
+		{
+			name:   "missing id",
+			policy: `{"eventPayload":{"exclude":["$.sender"]}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+		{
+			name:   "unknown field",
+			policy: `{"id":"policy","events":{}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+		{
+			name:   "no root",
+			policy: `{"id":"policy","eventPayload":{"exclude":["sender"]}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+		{
+			name:   "whole document",
+			policy: `{"id":"policy","eventPayload":{"exclude":["$"]}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+		{
+			name:   "empty key",
+			policy: `{"id":"policy","eventPayload":{"exclude":["$.pull_request..body"]}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+		{
+			name:   "invalid index",
+			policy: `{"id":"policy","vars":{"include":["$.commits[-1]"]}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+		{
+			name:   "unterminated index",
+			policy: `{"id":"policy","vars":{"include":["$.commits[0"]}}`,
+			err:    ErrInvalidRedactionPolicy,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := ParseRedactionPolicy([]byte(tc.policy))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error, want: %v, got: %v", tc.err, err)
+			}
+			if diff := cmp.Diff(tc.expected, p); diff != "" {
+				t.Errorf("unexpected policy (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRedactionPolicy_RedactEvent(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		filter   PathFilter
+		expected map[string]any
+	}{
+		{
+			name:     "no filter",
+			expected: testEvent(),
+		},
+		{
+			name: "exclude",
+			filter: PathFilter{
+				Exclude: []string{"$.pull_request.body", "$.commits[*].author.email", "$.missing.key"},
+			},
+			expected: map[string]any{
+				"action": "opened",
+				"inputs": map[string]any{
+					"version": "v1.0.0",
+				},
+				"pull_request": map[string]any{
+					"number": 1,
+					"title":  "Fix build",
+				},
+				"commits": []any{
+					map[string]any{
+						"id":     "abcde",
+						"author": map[string]any{"name": "user"},
+					},
+					map[string]any{
+						"id":     "fghij",
+						"author": map[string]any{"name": "other"},
+					},
+				},
+			},
+		},
+		{
+			name: "include",
+			filter: PathFilter{
+				Include: []string{"$.inputs", "$.commits[*].id", "$.pull_request.number"},
+			},
+			expected: map[string]any{
+				"inputs": map[string]any{
+					"version": "v1.0.0",
+				},
+				"pull_request": map[string]any{
+					"number": 1,
+				},
+				"commits": []any{
+					map[string]any{"id": "abcde"},
+					map[string]any{"id": "fghij"},
+				},
+			},
+		},
+		{
+			name: "include and exclude",
+			filter: PathFilter{
+				Include: []string{"$.commits"},
+				Exclude: []string{"$.commits[1]", "$.commits[*].author"},
+			},
+			expected: map[string]any{
+				"commits": []any{
+					map[string]any{"id": "abcde"},
+				},
+			},
+		},
+		{
+			name: "include nothing",
+			filter: PathFilter{
+				Include: []string{"$.action.name"},
+			},
+			expected: map[string]any{},
+		},
+		{
+			name: "exclude wildcard",
+			filter: PathFilter{
+				Exclude: []string{"$.*"},
+			},
+			expected: map[string]any{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &RedactionPolicy{ID: "policy", EventPayload: tc.filter}
+			event := testEvent()
+			got, err := p.RedactEvent(event)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("unexpected event (-want +got):\n%s", diff)
+			}
+			// The original event must not be modified.
+			if diff := cmp.Diff(testEvent(), event); diff != "" {
+				t.Errorf("event was modified (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRedactionPolicy_RedactVars(t *testing.T) {
+	t.Parallel()
+
+	p := &RedactionPolicy{
+		ID: "policy",
+		Vars: PathFilter{
+			Include: []string{"$.PUBLIC_VAR", "$.INTERNAL_VAR"},
+			Exclude: []string{"$.INTERNAL_VAR"},
+		},
+	}
+	got, err := p.RedactVars(github.VarsContext{
+		"PUBLIC_VAR":   "public",
+		"INTERNAL_VAR": "internal",
+		"OTHER_VAR":    "other",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(github.VarsContext{"PUBLIC_VAR": "public"}, got); diff != "" {
+		t.Errorf("unexpected vars (-want +got):\n%s", diff)
+	}
+}
+
+func TestGithubActionsBuild_WithRedactionPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := &RedactionPolicy{
+		ID: "policy",
+		EventPayload: PathFilter{
+			Exclude: []string{"$.pull_request.body", "$.inputs.version"},
+		},
+		Vars: PathFilter{
+			Exclude: []string{"$.INTERNAL_VAR"},
+		},
+	}
+	ref, err := p.Ref()
+	if err != nil {
+		t.Fatalf("Ref: %v", err)
+	}
+
+	b := NewGithubActionsBuild(nil, &github.WorkflowContext{
+		RunID: "12345",
+		Event: testEvent(),
+	}, github.VarsContext{
+		"PUBLIC_VAR":   "public",
+		"INTERNAL_VAR": "internal",
+	}).WithClients(&NilClientProvider{}).WithRedactionPolicy(p)
+
+	i, err := b.Invocation(context.Background())
+	if err != nil {
+		t.Fatalf("Invocation: %v", err)
+	}
+	env, ok := i.Environment.(map[string]any)
+	if !ok {
+		t.Fatalf("unexpected environment type: %T", i.Environment)
+	}
+	if diff := cmp.Diff(ref, env["redaction_policy"]); diff != "" {
+		t.Errorf("unexpected policy ref (-want +got):\n%s", diff)
+	}
+	event, ok := env["github_event_payload"].(map[string]any)
+	if !ok {
+		t.Fatalf("unexpected event payload type: %T", env["github_event_payload"])
+	}
+	if diff := cmp.Diff(map[string]any{"number": 1, "title": "Fix build"}, event["pull_request"]); diff != "" {
+		t.Errorf("unexpected pull request (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(WorkflowParameters{
+		EventInputs: map[string]any{},
+		VarsContext: github.VarsContext{"PUBLIC_VAR": "public"},
+	}, i.Parameters); diff != "" {
+		t.Errorf("unexpected parameters (-want +got):\n%s", diff)
+	}
+
+	// The inputs were redacted so the parameters are not complete.
+	m, err := b.Metadata(context.Background())
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if m.Completeness.Parameters {
+		t.Errorf("expected incomplete parameters")
+	}
+}
+
+func TestGithubActionsBuild_Metadata_redaction(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		policy   *RedactionPolicy
+		complete bool
+	}{
+		{
+			name:     "no policy",
+			complete: true,
+		},
+		{
+			name: "nothing redacted",
+			policy: &RedactionPolicy{
+				ID: "policy",
+				Vars: PathFilter{
+					Exclude: []string{"$.MISSING_VAR"},
+				},
+			},
+			complete: true,
+		},
+		{
+			name: "inputs redacted",
+			policy: &RedactionPolicy{
+				ID: "policy",
+				EventPayload: PathFilter{
+					Exclude: []string{"$.inputs.version"},
+				},
+			},
+		},
+		{
+			name: "vars redacted",
+			policy: &RedactionPolicy{
+				ID: "policy",
+				Vars: PathFilter{
+					Exclude: []string{"$.INTERNAL_VAR"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := NewGithubActionsBuild(nil, &github.WorkflowContext{
+				RunID: "12345",
+				Event: testEvent(),
+			}, github.VarsContext{
+				"PUBLIC_VAR":   "public",
+				"INTERNAL_VAR": "internal",
+			}).WithClients(&NilClientProvider{}).WithRedactionPolicy(tc.policy)
+
+			m, err := b.Metadata(context.Background())
+			if err != nil {
+				t.Fatalf("Metadata: %v", err)
+			}
+			if want, got := tc.complete, m.Completeness.Parameters; want != got {
+				t.Errorf("unexpected parameters completeness, want: %t, got: %t", want, got)
+			}
+		})
+	}
+}
+
+func TestDefaultRedactionPolicy(t *testing.T) {
+	t.Parallel()
+
+	if err := DefaultRedactionPolicy.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}